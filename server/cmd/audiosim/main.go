@@ -0,0 +1,144 @@
+// audiosim runs the avatar bot's audio pipeline without the Agora SDK and
+// prints the speech segments it would send to Anam. It feeds a synthetic
+// scenario, or the input capture of a session (PALABRA_AUDIO_CAPTURE), to try
+// voice detection settings offline:
+//
+//	audiosim
+//	audiosim -threshold 300 -hangover 300ms 20250101T120000Z-abc-0-input.wav
+//	audiosim -loudness -target -20 -v
+//	audiosim -adaptive 20250101T120000Z-abc-0-input.wav
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/audio"
+)
+
+const inputRate = 16000 // Rate the SDK delivers the target's audio at
+
+var (
+	threshold = flag.Int64("threshold", audio.DefaultEnergyThreshold, "Energy gate threshold (mean square)")
+	hangover  = flag.Duration("hangover", audio.DefaultHangoverFrames*audio.FrameDuration, "Silence forwarded before a segment ends")
+	preroll   = flag.Duration("preroll", audio.DefaultPrerollFrames*audio.FrameDuration, "Audio forwarded from before a speech onset")
+	adaptive  = flag.Bool("adaptive", false, "Use the adaptive gate instead of the energy gate (ignores -threshold, -hangover and -preroll)")
+	outRate   = flag.Int("rate", 24000, "Rate of the audio sent to Anam")
+	chunk     = flag.Duration("chunk", audio.DefaultChunkDuration, "Audio per message sent to Anam")
+	loudness  = flag.Bool("loudness", false, "Normalize the loudness before voice detection")
+	target    = flag.Float64("target", audio.DefaultLoudnessTarget, "Loudness target (dBFS RMS)")
+	maxGain   = flag.Float64("max-gain", audio.DefaultLoudnessMaxGain, "Most the AGC amplifies (dB)")
+	ceiling   = flag.Float64("ceiling", audio.DefaultLoudnessCeiling, "Limiter ceiling (dBFS)")
+	verbose   = flag.Bool("v", false, "Print every packet that reaches the sink")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [input.wav]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var gate interface {
+		audio.Detector
+		Settings() string
+	}
+	var adaptiveGate *audio.AdaptiveGate
+	if *adaptive {
+		adaptiveGate = audio.NewAdaptiveGate()
+		gate = adaptiveGate
+	} else {
+		energyGate := audio.NewEnergyGate()
+		if err := energyGate.Update(*threshold, *hangover, *preroll); err != nil {
+			log.Fatalf("audiosim: %v", err)
+		}
+		gate = energyGate
+	}
+	if err := audio.ValidChunkDuration(*chunk); err != nil {
+		log.Fatalf("audiosim: %v", err)
+	}
+	resampler, err := audio.NewResampler(inputRate, *outRate, 1)
+	if err != nil {
+		log.Fatalf("audiosim: %v", err)
+	}
+	stages := []audio.Stage{audio.Convert()}
+	if *loudness {
+		config := audio.LoudnessConfig{TargetDBFS: *target, MaxGainDB: *maxGain, CeilingDBFS: *ceiling}
+		if err := config.Validate(); err != nil {
+			log.Fatalf("audiosim: %v", err)
+		}
+		fmt.Printf("loudness: %s\n", config)
+		stages = append(stages, audio.Loudness(config))
+	}
+	stages = append(stages, audio.Detect(gate), audio.Resample(resampler), audio.Chunk(*chunk))
+	h := audio.NewHarness(inputRate, stages...)
+
+	if flag.NArg() == 1 {
+		if err := replay(h, flag.Arg(0)); err != nil {
+			log.Fatalf("audiosim: %v", err)
+		}
+	} else {
+		scenario(h)
+	}
+	fmt.Printf("%s, %s of input\n", gate.Settings(), h.Elapsed())
+
+	if *verbose {
+		for _, out := range h.Outputs() {
+			fmt.Printf("%8s %-13s %5d bytes @ %d Hz, level %d\n", out.At, out.Packet.Kind, len(out.Packet.PCM), out.Packet.SampleRate, out.Packet.Level)
+		}
+	}
+	for i, segment := range h.Segments() {
+		end := "open"
+		if segment.End > 0 {
+			end = segment.End.String()
+		}
+		fmt.Printf("segment %d: %s - %s, %s of audio in %d packets\n", i+1, segment.Start, end, segment.Audio, segment.Packets)
+	}
+	if adaptiveGate != nil {
+		d := adaptiveGate.Diagnostics()
+		fmt.Printf("gate: estimated gap between words %s\n", d.Gap.Round(time.Millisecond))
+		for _, end := range d.Ends {
+			fmt.Printf("voice_end at %s: %s\n", end.At, end)
+		}
+	}
+}
+
+// scenario pushes speech-like bursts: words with short gaps, a pause, noise
+// below the threshold and a word cut by a pause of the bot
+func scenario(h *audio.Harness) {
+	h.Silence(500 * time.Millisecond)
+	h.Tone(400*time.Millisecond, 220, 3000)
+	h.Silence(150 * time.Millisecond)
+	h.Tone(600*time.Millisecond, 180, 2000)
+	h.Silence(time.Second)
+	h.Noise(time.Second, 15)
+	h.Tone(300*time.Millisecond, 250, 2500)
+	h.EndSegment()
+	h.Silence(500 * time.Millisecond)
+}
+
+// replay pushes the audio of a mono WAV file recorded at the SDK's rate
+func replay(h *audio.Harness, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	pcm, rate, channels, err := audio.ReadWAV(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if rate != inputRate || channels != 1 {
+		return fmt.Errorf("%s: %d Hz with %d channels, expected %d Hz mono", path, rate, channels, inputRate)
+	}
+	h.PCM(pcm)
+	return nil
+}
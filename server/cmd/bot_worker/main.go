@@ -1,13 +1,28 @@
 // bot_worker is a child process that runs Agora SDK operations in isolation.
 // If this process crashes (e.g., Agora SDK segfault), the parent HTTP server
 // stays up and can handle the error gracefully.
+//
+// With -socket it listens for the server on a Unix socket instead of using
+// stdin/stdout, and keeps its sessions running while the server restarts.
+//
+// With -daemon it instead runs as a long-lived node on another host: it dials
+// the server, registers its capacity and receives sessions over the network.
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/samyak-jain/agora_backend/services"
 	"github.com/samyak-jain/agora_backend/services/ipc"
@@ -16,14 +31,47 @@ import (
 
 var (
 	logger       *log.Logger
-	stdoutWriter *ipc.MessageWriter
-	stdoutLock   sync.Mutex
+	parentWriter *ipc.MessageWriter // stdout or the server connection, nil while detached
+	parentLock   sync.Mutex
+
+	// Listener of -socket mode, closed (removing the socket) before exiting
+	socketListener net.Listener
 
 	// Original stdout for IPC (before redirect)
 	originalStdout *os.File
+
+	// Sessions hosted by this process (taskID -> worker)
+	sessions   = make(map[string]*services.BotWorker)
+	sessionsMu sync.Mutex
+	sessionsWg sync.WaitGroup
+
+	maxSessions = flag.Int("max-sessions", 1, "Maximum number of sessions hosted by this process")
+	socketPath  = flag.String("socket", "", "Listen for the server on this Unix socket; sessions survive a server restart")
+	daemonMode  = flag.Bool("daemon", false, "Run as a remote node that registers with the server")
+	serverAddr  = flag.String("server", os.Getenv("PALABRA_BOT_SERVER_ADDR"), "Server node address in daemon mode (tcp://host:port, tls://host:port or unix:///path)")
+	nodeID      = flag.String("node-id", "", "Node ID advertised in daemon mode (defaults to hostname)")
+
+	// Session log messages below this level are not sent to the parent
+	minLogLevel = botipc.LogLevelINFO
 )
 
+// Maximum delay between reconnect attempts in daemon mode
+const maxReconnectBackoff = 30 * time.Second
+
+// How long STOP_SESSION waits for a session to tear down before acknowledging
+const stopTimeout = 4 * time.Second
+
+// Default time sessions keep running in -socket mode while no server is attached
+const defaultReattachTimeout = 60 * time.Second
+
+// errIncompatibleServer ends the command loop of a server speaking another
+// protocol version. Its sessions are left to the caller, as when the server
+// goes away.
+var errIncompatibleServer = errors.New("incompatible server")
+
 func main() {
+	flag.Parse()
+
 	// Save original stdout for IPC communication BEFORE redirecting
 	originalStdout = os.Stdout
 
@@ -36,37 +84,272 @@ func main() {
 
 	// Setup logging to stderr
 	logger = log.New(os.Stderr, "[bot_worker] ", log.LstdFlags|log.Lshortfile)
-	logger.Println("Bot worker process started")
+	logger.Printf("Bot worker process started (max %d sessions)", *maxSessions)
+
+	if name := os.Getenv("PALABRA_BOT_LOG_LEVEL"); name != "" {
+		if level, ok := botipc.EnumValuesLogLevel[strings.ToUpper(name)]; ok {
+			minLogLevel = level
+		} else {
+			logger.Printf("Ignoring unknown PALABRA_BOT_LOG_LEVEL %q", name)
+		}
+	}
+
+	if *daemonMode {
+		runDaemon()
+		return
+	}
+
+	if *socketPath != "" {
+		runSocket()
+		waitForSessions(5 * time.Second)
+		logger.Println("Bot worker process exiting")
+		return
+	}
 
 	// Setup IPC writer using original stdout
-	stdoutWriter = ipc.NewMessageWriter(originalStdout)
+	setParentWriter(ipc.NewMessageWriter(originalStdout))
+
+	// Announce our protocol version before the parent sends anything
+	if err := sendHello(); err != nil {
+		logger.Fatalf("Failed to send HELLO: %v", err)
+	}
 
 	// Setup IPC reader from stdin
 	stdinReader := ipc.NewMessageReader(os.Stdin)
 
 	// Main command loop
 	runCommandLoop(stdinReader)
+	stopAllSessions()
+
+	// Give running sessions a chance to release the SDK cleanly
+	waitForSessions(5 * time.Second)
 
 	logger.Println("Bot worker process exiting")
 }
 
-func runCommandLoop(reader *ipc.MessageReader) {
-	var worker *services.BotWorker
+// runSocket serves the server over a Unix socket. Sessions outlive the
+// connection: when the server goes away (restart, deploy) they keep running
+// until a server reattaches, and are stopped if none does within the reattach
+// timeout. A server attaching while another is connected takes over. The
+// server retires the worker with SIGTERM.
+func runSocket() {
+	listener, err := ipc.Listen(ipc.SchemeUnix+"://"+*socketPath, nil)
+	if err != nil {
+		logger.Fatalf("Failed to listen on %s: %v", *socketPath, err)
+	}
+	socketListener = listener
+	defer listener.Close()
+
+	// stderr is a pipe to the server that spawned us; once it is gone, writes
+	// must fail instead of killing the process with SIGPIPE
+	signal.Ignore(syscall.SIGPIPE)
+
+	terminate := make(chan os.Signal, 1)
+	signal.Notify(terminate, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-terminate
+		logger.Printf("Received %v, stopping sessions", sig)
+		stopAllSessions()
+		waitForSessions(5 * time.Second)
+		exit(0)
+	}()
+
+	timeout := defaultReattachTimeout
+	if env := os.Getenv("PALABRA_BOT_REATTACH_TIMEOUT_SECONDS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			timeout = time.Duration(parsed) * time.Second
+		}
+	}
+
+	conns := make(chan net.Conn)
+	go acceptServers(listener, conns)
 
+	var current net.Conn
+	served := make(chan error, 1)
+	deadline := time.After(timeout) // The spawning server must attach in time too
+
+	for {
+		select {
+		case conn := <-conns:
+			if current != nil {
+				logger.Println("Another server attached, dropping the current connection")
+				current.Close()
+				<-served
+			}
+			current = conn
+			deadline = nil
+			go func() { served <- serveServer(conn) }()
+
+		case err := <-served:
+			current = nil
+			if err == nil {
+				// Stopped by a command (single-session STOP_SESSION)
+				return
+			}
+			active := sessionCount()
+			if active == 0 {
+				logger.Printf("Server disconnected (%v) and no sessions are left, exiting", err)
+				return
+			}
+			logger.Printf("Server disconnected (%v), keeping %d sessions running for up to %v until a server reattaches",
+				err, active, timeout)
+			deadline = time.After(timeout)
+
+		case <-deadline:
+			logger.Printf("No server attached within %v, stopping sessions", timeout)
+			stopAllSessions()
+			return
+		}
+	}
+}
+
+// acceptServers hands accepted connections to runSocket until the listener is closed
+func acceptServers(listener net.Listener, conns chan<- net.Conn) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Printf("Error accepting server connection: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		conns <- conn
+	}
+}
+
+// serveServer handles the commands of one server connection, returning nil
+// when a command ended the worker and the read error when the server went away
+func serveServer(conn net.Conn) error {
+	defer conn.Close()
+
+	writer := ipc.NewMessageWriter(conn)
+	setParentWriter(writer)
+	defer clearParentWriter(writer)
+
+	if err := sendHello(); err != nil {
+		return fmt.Errorf("failed to send HELLO: %w", err)
+	}
+	return runCommandLoop(ipc.NewMessageReader(conn))
+}
+
+// exit terminates the process, removing the -socket listener first so a
+// restarting server does not try to reattach
+func exit(code int) {
+	if socketListener != nil {
+		socketListener.Close()
+	}
+	os.Exit(code)
+}
+
+// singleSession reports whether this process hosts a single session and
+// exits with it (the default one-session-per-process placement)
+func singleSession() bool {
+	return !*daemonMode && *maxSessions <= 1
+}
+
+// runDaemon keeps a registered connection to the server, reconnecting with
+// backoff. Sessions do not survive a lost connection - the server fails them -
+// so they are stopped before reconnecting.
+func runDaemon() {
+	if *serverAddr == "" {
+		logger.Fatal("-server (or PALABRA_BOT_SERVER_ADDR) is required in daemon mode")
+	}
+
+	// Trust a private CA for tls:// servers
+	tlsConfig, err := ipc.ClientTLSConfig(os.Getenv("PALABRA_BOT_NODE_TLS_CA"))
+	if err != nil {
+		logger.Fatalf("Invalid PALABRA_BOT_NODE_TLS_CA: %v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	id := *nodeID
+	if id == "" {
+		id = hostname
+	}
+
+	backoff := time.Second
+	for {
+		conn, err := ipc.Dial(*serverAddr, tlsConfig)
+		if err != nil {
+			logger.Printf("Failed to connect to %s: %v (retrying in %v)", *serverAddr, err, backoff)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxReconnectBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		logger.Printf("Connected to %s as node %s", *serverAddr, id)
+		err = serveConnection(conn, id, hostname)
+		waitForSessions(5 * time.Second)
+
+		// An incompatible server is waited out like an unreachable one
+		if errors.Is(err, errIncompatibleServer) {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxReconnectBackoff)
+		}
+	}
+}
+
+// serveConnection registers with the server and handles its commands until
+// the connection drops, returning why it did
+func serveConnection(conn net.Conn, id, hostname string) error {
+	defer conn.Close()
+
+	setParentWriter(ipc.NewMessageWriter(conn))
+
+	if err := sendHello(); err != nil {
+		logger.Printf("Failed to send HELLO: %v", err)
+		return err
+	}
+
+	registerMsg := ipc.BuildNodeRegisterMessage(id, hostname, uint32(*maxSessions), os.Getenv("PALABRA_BOT_NODE_TOKEN"))
+	if err := writeToParent(registerMsg); err != nil {
+		logger.Printf("Failed to register node: %v", err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go sendHeartbeats(id, done)
+
+	err := runCommandLoop(ipc.NewMessageReader(conn))
+	stopAllSessions()
+	return err
+}
+
+// sendHeartbeats reports the number of hosted sessions until done is closed
+func sendHeartbeats(id string, done <-chan struct{}) {
+	ticker := time.NewTicker(ipc.NodeHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := writeToParent(ipc.BuildNodeHeartbeatMessage(id, uint32(sessionCount()))); err != nil {
+				logger.Printf("Failed to send heartbeat: %v", err)
+			}
+		}
+	}
+}
+
+// runCommandLoop handles commands until the stream breaks, returning the read
+// error, or until a command ends the loop, returning nil. Sessions are left
+// running when the stream breaks; the caller decides whether to stop them.
+func runCommandLoop(reader *ipc.MessageReader) error {
 	for {
 		// Read next command from parent
 		msgBytes, err := reader.ReadMessage()
 		if err != nil {
 			if err == io.EOF {
-				logger.Println("Parent closed stdin, shutting down")
+				logger.Println("Parent closed the IPC stream")
 			} else {
-				logger.Printf("Error reading from stdin: %v", err)
-			}
-			// Cleanup and exit
-			if worker != nil {
-				worker.Stop()
+				logger.Printf("Error reading from parent: %v", err)
 			}
-			return
+			return err
 		}
 
 		// Parse the IPC message
@@ -75,54 +358,92 @@ func runCommandLoop(reader *ipc.MessageReader) {
 			logger.Printf("Error parsing IPC message: %v", err)
 			continue
 		}
+		requestID := ipc.ParseRequestID(msgBytes)
 
 		switch msgType {
-		case botipc.MessageTypeSTART_SESSION:
-			if worker != nil {
-				logger.Println("Session already running, ignoring START_SESSION")
-				continue
+		case botipc.MessageTypeHELLO:
+			hello := ipc.ParseHelloPayload(payloadBytes)
+			if err := hello.CheckCompatible(); err != nil {
+				logger.Printf("Incompatible server, disconnecting: %v", err)
+				return fmt.Errorf("%w: %v", errIncompatibleServer, err)
 			}
+			logger.Printf("Server build %s speaks IPC protocol version %d", hello.BuildID, hello.ProtocolVersion)
 
+		case botipc.MessageTypeSTART_SESSION:
 			payload := ipc.ParseStartSessionPayload(payloadBytes)
 			taskID := string(payload.TaskId())
 
 			logger.Printf("Received START_SESSION for task %s", taskID)
 
-			// Send INITIALIZING status
-			sendStatus(taskID, botipc.SessionStatusINITIALIZING, "Starting session", 0)
+			sessionsMu.Lock()
+			if _, exists := sessions[taskID]; exists {
+				sessionsMu.Unlock()
+				logger.Printf("Session %s already running, ignoring START_SESSION", taskID)
+				sendNack(requestID, taskID, msgType, ipc.NackSessionExists, "session is already running")
+				continue
+			}
+			if len(sessions) >= *maxSessions {
+				sessionsMu.Unlock()
+				logger.Printf("At capacity (%d sessions), rejecting task %s", *maxSessions, taskID)
+				message := fmt.Sprintf("bot_worker already hosts %d sessions", *maxSessions)
+				sendError(taskID, ipc.NackAtCapacity, message, true)
+				sendNack(requestID, taskID, msgType, ipc.NackAtCapacity, message)
+				continue
+			}
+
+			// START_SESSION is answered once the session connects or fails
+			reply := &startReply{requestID: requestID, taskID: taskID}
 
-			// Create and start the worker
+			// Create the worker
 			config := services.BotWorkerConfig{
-				TaskID:        taskID,
-				AppID:         string(payload.AppId()),
-				Channel:       string(payload.Channel()),
-				BotUID:        payload.BotUid(),
-				BotToken:      string(payload.BotToken()),
-				PalabraUID:    payload.PalabraUid(),
-				AnamAPIKey:    string(payload.AnamApiKey()),
-				AnamBaseURL:   string(payload.AnamBaseUrl()),
-				AnamAvatarID:  string(payload.AnamAvatarId()),
-				AnamUID:       payload.AnamUid(),
-				AnamToken:     string(payload.AnamToken()),
+				TaskID:         taskID,
+				AppID:          string(payload.AppId()),
+				Channel:        string(payload.Channel()),
+				BotUID:         payload.BotUid(),
+				BotToken:       string(payload.BotToken()),
+				PalabraUID:     payload.PalabraUid(),
+				AnamAPIKey:     string(payload.AnamApiKey()),
+				AnamBaseURL:    string(payload.AnamBaseUrl()),
+				AnamAvatarID:   string(payload.AnamAvatarId()),
+				AnamUID:        payload.AnamUid(),
+				AnamToken:      string(payload.AnamToken()),
 				TargetLanguage: string(payload.TargetLanguage()),
-				StatusCallback: sendStatus,
-				LogCallback:    sendLog,
-				ErrorCallback:  sendError,
+				VADMode:        string(payload.VadMode()),
+				AudioCapture:   ipc.ParseAudioCapture(payload),
+				ChunkDuration:  time.Duration(payload.ChunkMs()) * time.Millisecond,
+				Loudness:       ipc.ParseLoudness(payload),
+				Placeholder:    ipc.ParsePlaceholderVideo(payload),
+				FanoutGroup:    string(payload.FanoutGroup()),
+				Captions:       payload.Captions(),
+				TaskState:      string(payload.TaskState()),
+				StatusCallback: func(taskID string, status botipc.SessionStatus, message string, anamUID uint32) {
+					sendStatus(taskID, status, message, anamUID)
+					if status == botipc.SessionStatusCONNECTED {
+						reply.ack()
+					}
+				},
+				LogCallback: sendLog,
+				ErrorCallback: func(taskID, errorCode, message string, fatal bool) {
+					sendError(taskID, errorCode, message, fatal)
+					if fatal {
+						reply.nack(errorCode, message)
+					}
+				},
+				MetricsCallback:     sendMetrics,
+				AvatarVideoCallback: sendAvatarVideo,
+				CaptionCallback:     sendCaption,
 			}
 
-			worker = services.NewBotWorker(config)
+			worker := services.NewBotWorker(config)
+			sessions[taskID] = worker
+			sessionsWg.Add(1)
+			sessionsMu.Unlock()
+
+			// Send INITIALIZING status
+			sendStatus(taskID, botipc.SessionStatusINITIALIZING, "Starting session", 0)
 
 			// Start the worker in a goroutine
-			go func() {
-				err := worker.Run()
-				if err != nil {
-					logger.Printf("Worker failed: %v", err)
-					sendError(taskID, "WORKER_FAILED", err.Error(), true)
-				}
-				// Worker finished, we should exit
-				logger.Println("Worker finished, exiting")
-				os.Exit(0)
-			}()
+			go runSession(taskID, worker, reply)
 
 		case botipc.MessageTypeSTOP_SESSION:
 			payload := ipc.ParseStopSessionPayload(payloadBytes)
@@ -131,51 +452,309 @@ func runCommandLoop(reader *ipc.MessageReader) {
 
 			logger.Printf("Received STOP_SESSION for task %s: %s", taskID, reason)
 
-			if worker != nil {
-				sendStatus(taskID, botipc.SessionStatusDISCONNECTING, "Stopping session", 0)
-				worker.Stop()
-				worker = nil
-				sendStatus(taskID, botipc.SessionStatusDISCONNECTED, "Session stopped", 0)
+			sessionsMu.Lock()
+			worker, ok := sessions[taskID]
+			delete(sessions, taskID)
+			sessionsMu.Unlock()
+
+			switch {
+			case !ok:
+				sendNack(requestID, taskID, msgType, ipc.NackUnknownSession, "no such session")
+			case singleSession():
+				stopSession(requestID, taskID, worker)
+			default:
+				// Other sessions keep receiving commands while this one tears down
+				go stopSession(requestID, taskID, worker)
 			}
 
-			// Exit after stop
-			return
+			// Single-session workers exit after stop
+			if singleSession() {
+				return nil
+			}
+
+		case botipc.MessageTypeUPDATE_CONFIG:
+			payload := ipc.ParseUpdateConfigPayload(payloadBytes)
+			taskID := string(payload.TaskId())
+
+			logger.Printf("Received UPDATE_CONFIG for task %s", taskID)
+			controlSession(requestID, taskID, msgType, func(worker *services.BotWorker) error {
+				return worker.UpdateVAD(
+					int64(payload.VadThreshold()),
+					time.Duration(payload.HangoverMs())*time.Millisecond,
+					time.Duration(payload.PrerollMs())*time.Millisecond,
+				)
+			})
+
+		case botipc.MessageTypePAUSE_FORWARDING, botipc.MessageTypeRESUME_FORWARDING:
+			payload := ipc.ParseSessionCommandPayload(payloadBytes)
+			taskID := string(payload.TaskId())
+
+			logger.Printf("Received %s for task %s", botipc.EnumNamesMessageType[msgType], taskID)
+			controlSession(requestID, taskID, msgType, func(worker *services.BotWorker) error {
+				return worker.SetForwarding(msgType == botipc.MessageTypeRESUME_FORWARDING)
+			})
+
+		case botipc.MessageTypeSWITCH_TARGET:
+			payload := ipc.ParseSwitchTargetPayload(payloadBytes)
+			taskID := string(payload.TaskId())
+
+			logger.Printf("Received SWITCH_TARGET for task %s: UID %d", taskID, payload.PalabraUid())
+			controlSession(requestID, taskID, msgType, func(worker *services.BotWorker) error {
+				return worker.SwitchTarget(payload.PalabraUid())
+			})
+
+		case botipc.MessageTypeLIST_SESSIONS:
+			logger.Println("Received LIST_SESSIONS")
+			sendSessionList(requestID)
 
 		default:
 			logger.Printf("Unknown message type: %d", msgType)
+			sendNack(requestID, "", msgType, ipc.NackUnsupported, fmt.Sprintf("unknown message type %d", msgType))
 		}
 	}
 }
 
+// startReply answers a START_SESSION exactly once: ACK when the session
+// connects, NACK when it fails or ends first
+type startReply struct {
+	once      sync.Once
+	requestID uint64
+	taskID    string
+}
+
+func (r *startReply) ack() {
+	r.once.Do(func() {
+		sendAck(r.requestID, r.taskID, botipc.MessageTypeSTART_SESSION)
+	})
+}
+
+func (r *startReply) nack(errorCode, message string) {
+	r.once.Do(func() {
+		sendNack(r.requestID, r.taskID, botipc.MessageTypeSTART_SESSION, errorCode, message)
+	})
+}
+
+// stopSession stops a session removed from the sessions map and acknowledges
+// the STOP_SESSION once it has torn down
+func stopSession(requestID uint64, taskID string, worker *services.BotWorker) {
+	sendStatus(taskID, botipc.SessionStatusDISCONNECTING, "Stopping session", 0)
+	worker.Stop()
+
+	select {
+	case <-worker.Done():
+	case <-time.After(stopTimeout):
+		logger.Printf("Session %s did not stop within %v", taskID, stopTimeout)
+	}
+
+	sendStatus(taskID, botipc.SessionStatusDISCONNECTED, "Session stopped", 0)
+	sendAck(requestID, taskID, botipc.MessageTypeSTOP_SESSION)
+}
+
+// controlSession applies a runtime control command to a hosted session and
+// answers it with ACK or NACK
+func controlSession(requestID uint64, taskID string, command botipc.MessageType, apply func(worker *services.BotWorker) error) {
+	sessionsMu.Lock()
+	worker, ok := sessions[taskID]
+	sessionsMu.Unlock()
+
+	if !ok {
+		sendNack(requestID, taskID, command, ipc.NackUnknownSession, "no such session")
+		return
+	}
+	if err := apply(worker); err != nil {
+		sendNack(requestID, taskID, command, ipc.NackCommandFailed, err.Error())
+		return
+	}
+	sendAck(requestID, taskID, command)
+}
+
+// runSession runs a worker until it stops and reports sessions that ended on their own
+func runSession(taskID string, worker *services.BotWorker, reply *startReply) {
+	defer sessionsWg.Done()
+
+	err := worker.Run()
+	if err != nil {
+		logger.Printf("Worker for task %s failed: %v", taskID, err)
+		sendError(taskID, "WORKER_FAILED", err.Error(), true)
+		reply.nack("WORKER_FAILED", err.Error())
+	}
+	reply.nack(ipc.NackSessionEnded, "session ended before connecting")
+
+	// If the session is still registered, it ended without a STOP_SESSION
+	// (idle timeout, target left, startup failure)
+	sessionsMu.Lock()
+	active := sessions[taskID] == worker
+	if active {
+		delete(sessions, taskID)
+	}
+	sessionsMu.Unlock()
+
+	if active {
+		sendStatus(taskID, botipc.SessionStatusDISCONNECTED, "Session ended", 0)
+	}
+
+	if singleSession() && active {
+		// Worker finished on its own, we should exit. After a STOP_SESSION the
+		// command loop returns instead, once the stop is acknowledged.
+		logger.Println("Worker finished, exiting")
+		exit(0)
+	}
+	logger.Printf("Session %s finished", taskID)
+}
+
+// sessionCount returns the number of hosted sessions
+func sessionCount() int {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return len(sessions)
+}
+
+// stopAllSessions stops every hosted session
+func stopAllSessions() {
+	sessionsMu.Lock()
+	workers := make([]*services.BotWorker, 0, len(sessions))
+	for taskID, worker := range sessions {
+		workers = append(workers, worker)
+		delete(sessions, taskID)
+	}
+	sessionsMu.Unlock()
+
+	for _, worker := range workers {
+		worker.Stop()
+	}
+}
+
+// waitForSessions waits for session goroutines to finish cleanup, up to timeout
+func waitForSessions(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		sessionsWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		logger.Printf("Sessions did not finish within %v", timeout)
+	}
+}
+
+// setParentWriter swaps the stream messages to the parent are written to
+func setParentWriter(w *ipc.MessageWriter) {
+	parentLock.Lock()
+	defer parentLock.Unlock()
+	parentWriter = w
+}
+
+// clearParentWriter detaches the stream, unless another server took over already
+func clearParentWriter(w *ipc.MessageWriter) {
+	parentLock.Lock()
+	defer parentLock.Unlock()
+	if parentWriter == w {
+		parentWriter = nil
+	}
+}
+
+// writeToParent writes a message to the current parent stream. Messages sent
+// while no server is attached are dropped; a reattaching server asks for the
+// current state with LIST_SESSIONS.
+func writeToParent(msg []byte) error {
+	parentLock.Lock()
+	defer parentLock.Unlock()
+	if parentWriter == nil {
+		return nil
+	}
+	return parentWriter.WriteMessage(msg)
+}
+
+// sendHello announces this build's protocol version and capabilities
+func sendHello() error {
+	return writeToParent(ipc.BuildHelloMessage(ipc.LocalHello()))
+}
+
+// sendSessionList answers LIST_SESSIONS with the hosted sessions
+func sendSessionList(requestID uint64) {
+	list := ipc.SessionList{Pid: os.Getpid(), MaxSessions: *maxSessions}
+
+	sessionsMu.Lock()
+	for _, worker := range sessions {
+		list.Sessions = append(list.Sessions, worker.Info())
+	}
+	sessionsMu.Unlock()
+
+	if err := writeToParent(ipc.BuildSessionListMessage(requestID, list)); err != nil {
+		logger.Printf("Failed to send session list: %v", err)
+	}
+}
+
 // sendStatus sends a status update to the parent process
 func sendStatus(taskID string, status botipc.SessionStatus, message string, anamUID uint32) {
-	stdoutLock.Lock()
-	defer stdoutLock.Unlock()
-
 	msg := ipc.BuildStatusMessage(taskID, status, message, anamUID)
-	if err := stdoutWriter.WriteMessage(msg); err != nil {
+	if err := writeToParent(msg); err != nil {
 		logger.Printf("Failed to send status: %v", err)
 	}
 }
 
 // sendLog sends a log message to the parent process
 func sendLog(taskID string, level botipc.LogLevel, message string) {
-	stdoutLock.Lock()
-	defer stdoutLock.Unlock()
-
+	if level < minLogLevel {
+		return
+	}
 	msg := ipc.BuildLogMessage(taskID, level, message)
-	if err := stdoutWriter.WriteMessage(msg); err != nil {
+	if err := writeToParent(msg); err != nil {
 		logger.Printf("Failed to send log: %v", err)
 	}
 }
 
+// sendMetrics sends session counters to the parent process
+func sendMetrics(taskID string, metrics ipc.SessionMetrics) {
+	msg := ipc.BuildMetricsMessage(taskID, metrics)
+	if err := writeToParent(msg); err != nil {
+		logger.Printf("Failed to send metrics: %v", err)
+	}
+}
+
+// sendAvatarVideo reports a placeholder video event to the parent process
+func sendAvatarVideo(taskID string, event ipc.AvatarVideoEvent) {
+	msg := ipc.BuildAvatarVideoMessage(taskID, event)
+	if err := writeToParent(msg); err != nil {
+		logger.Printf("Failed to send avatar video event: %v", err)
+	}
+}
+
+// sendCaption relays a caption to the parent process
+func sendCaption(taskID string, caption ipc.Caption) {
+	msg := ipc.BuildCaptionMessage(taskID, caption)
+	if err := writeToParent(msg); err != nil {
+		logger.Printf("Failed to send caption: %v", err)
+	}
+}
+
 // sendError sends an error to the parent process
 func sendError(taskID, errorCode, message string, fatal bool) {
-	stdoutLock.Lock()
-	defer stdoutLock.Unlock()
-
 	msg := ipc.BuildErrorMessage(taskID, errorCode, message, fatal)
-	if err := stdoutWriter.WriteMessage(msg); err != nil {
+	if err := writeToParent(msg); err != nil {
 		logger.Printf("Failed to send error: %v", err)
 	}
 }
+
+// sendAck reports that a command succeeded. Commands without a request ID
+// are not answered.
+func sendAck(requestID uint64, taskID string, command botipc.MessageType) {
+	if requestID == 0 {
+		return
+	}
+	if err := writeToParent(ipc.BuildAckMessage(requestID, taskID, command)); err != nil {
+		logger.Printf("Failed to send ACK: %v", err)
+	}
+}
+
+// sendNack reports that a command failed, with its error details
+func sendNack(requestID uint64, taskID string, command botipc.MessageType, errorCode, message string) {
+	if requestID == 0 {
+		return
+	}
+	if err := writeToParent(ipc.BuildNackMessage(requestID, taskID, command, errorCode, message)); err != nil {
+		logger.Printf("Failed to send NACK: %v", err)
+	}
+}
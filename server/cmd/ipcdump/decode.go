@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Replaces tokens and API keys unless -secrets is given
+const redactedValue = "[REDACTED]"
+
+// dumpRecord is one message as printed by ipcdump
+type dumpRecord struct {
+	Time      string      `json:"time"`
+	OffsetMs  int64       `json:"offset_ms"` // Since the first message
+	Direction string      `json:"direction"`
+	Type      string      `json:"type"`
+	RequestID uint64      `json:"request_id,omitempty"`
+	Payload   interface{} `json:"payload,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// decoder turns framed IPC messages into dump records
+type decoder struct {
+	showSecrets bool
+}
+
+// secret redacts a credential unless secrets are shown
+func (d decoder) secret(value []byte) string {
+	if len(value) == 0 || d.showSecrets {
+		return string(value)
+	}
+	return redactedValue
+}
+
+// decode parses a message and its payload. Corrupt messages are reported in
+// the record's error instead of aborting the dump.
+func (d decoder) decode(direction ipc.Direction, msg []byte) (record dumpRecord) {
+	record.Direction = direction.String()
+	defer func() {
+		if r := recover(); r != nil {
+			record.Error = fmt.Sprintf("corrupt message: %v", r)
+		}
+	}()
+
+	msgType, payloadBytes, err := ipc.ParseIPCMessage(msg)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	record.Type = msgType.String()
+	record.RequestID = ipc.ParseRequestID(msg)
+	record.Payload = d.payload(msgType, payloadBytes)
+	return record
+}
+
+// payload converts a payload into a JSON-friendly map using the botipc accessors
+func (d decoder) payload(msgType botipc.MessageType, data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+
+	switch msgType {
+	case botipc.MessageTypeSTART_SESSION:
+		p := botipc.GetRootAsStartSessionPayload(data, 0)
+		var capture interface{}
+		if c := ipc.ParseAudioCapture(p); c != nil {
+			capture = map[string]interface{}{
+				"dir":         c.Dir,
+				"max_bytes":   c.MaxBytes,
+				"max_seconds": int64(c.MaxDuration / time.Second),
+			}
+		}
+		var loudness interface{}
+		if l := ipc.ParseLoudness(p); l != nil {
+			loudness = map[string]interface{}{
+				"engine":       l.Engine,
+				"target_dbfs":  l.TargetDBFS,
+				"max_gain_db":  l.MaxGainDB,
+				"ceiling_dbfs": l.CeilingDBFS,
+			}
+		}
+		var placeholder interface{}
+		if v := ipc.ParsePlaceholderVideo(p); v != nil {
+			placeholder = map[string]interface{}{
+				"uid":        v.UID,
+				"token":      d.secret([]byte(v.Token)),
+				"source":     v.Source,
+				"timeout_ms": int64(v.Timeout / time.Millisecond),
+			}
+		}
+		return map[string]interface{}{
+			"task_id":           string(p.TaskId()),
+			"app_id":            string(p.AppId()),
+			"channel":           string(p.Channel()),
+			"bot_uid":           p.BotUid(),
+			"bot_token":         d.secret(p.BotToken()),
+			"palabra_uid":       p.PalabraUid(),
+			"anam_api_key":      d.secret(p.AnamApiKey()),
+			"anam_base_url":     string(p.AnamBaseUrl()),
+			"anam_avatar_id":    string(p.AnamAvatarId()),
+			"anam_uid":          p.AnamUid(),
+			"anam_token":        d.secret(p.AnamToken()),
+			"target_language":   string(p.TargetLanguage()),
+			"vad_mode":          string(p.VadMode()),
+			"audio_capture":     capture,
+			"chunk_ms":          p.ChunkMs(),
+			"loudness":          loudness,
+			"placeholder_video": placeholder,
+			"fanout_group":      string(p.FanoutGroup()),
+			"captions":          p.Captions(),
+		}
+
+	case botipc.MessageTypeSTOP_SESSION:
+		p := botipc.GetRootAsStopSessionPayload(data, 0)
+		return map[string]interface{}{
+			"task_id": string(p.TaskId()),
+			"reason":  string(p.Reason()),
+		}
+
+	case botipc.MessageTypeUPDATE_CONFIG:
+		p := botipc.GetRootAsUpdateConfigPayload(data, 0)
+		return map[string]interface{}{
+			"task_id":       string(p.TaskId()),
+			"vad_threshold": p.VadThreshold(),
+			"hangover_ms":   p.HangoverMs(),
+			"preroll_ms":    p.PrerollMs(),
+		}
+
+	case botipc.MessageTypePAUSE_FORWARDING, botipc.MessageTypeRESUME_FORWARDING, botipc.MessageTypeLIST_SESSIONS:
+		p := botipc.GetRootAsSessionCommandPayload(data, 0)
+		return map[string]interface{}{
+			"task_id": string(p.TaskId()),
+		}
+
+	case botipc.MessageTypeSWITCH_TARGET:
+		p := botipc.GetRootAsSwitchTargetPayload(data, 0)
+		return map[string]interface{}{
+			"task_id":     string(p.TaskId()),
+			"palabra_uid": p.PalabraUid(),
+		}
+
+	case botipc.MessageTypeSTATUS_UPDATE:
+		p := botipc.GetRootAsStatusPayload(data, 0)
+		return map[string]interface{}{
+			"task_id":  string(p.TaskId()),
+			"status":   p.Status().String(),
+			"message":  string(p.Message()),
+			"anam_uid": p.AnamUid(),
+		}
+
+	case botipc.MessageTypeLOG_MESSAGE:
+		p := botipc.GetRootAsLogPayload(data, 0)
+		return map[string]interface{}{
+			"task_id": string(p.TaskId()),
+			"level":   p.Level().String(),
+			"message": string(p.Message()),
+		}
+
+	case botipc.MessageTypeERROR_RESPONSE:
+		p := botipc.GetRootAsErrorPayload(data, 0)
+		return map[string]interface{}{
+			"task_id":    string(p.TaskId()),
+			"error_code": string(p.ErrorCode()),
+			"message":    string(p.Message()),
+			"fatal":      p.Fatal(),
+		}
+
+	case botipc.MessageTypeAVATAR_VIDEO:
+		taskID, event := ipc.ParseAvatarVideoPayload(data)
+		return map[string]interface{}{
+			"task_id":   taskID,
+			"event":     event.Event,
+			"waited_ms": int64(event.Waited / time.Millisecond),
+			"message":   event.Message,
+		}
+
+	case botipc.MessageTypeCAPTION:
+		taskID, caption := ipc.ParseCaptionPayload(data)
+		return map[string]interface{}{
+			"task_id":        taskID,
+			"uid":            caption.UID,
+			"language":       caption.Language,
+			"segment_id":     caption.SegmentID,
+			"text":           caption.Text,
+			"final":          caption.Final,
+			"start_ms":       caption.Start.Milliseconds(),
+			"end_ms":         caption.End.Milliseconds(),
+			"received_at_ms": caption.ReceivedAt.UnixMilli(),
+		}
+
+	case botipc.MessageTypeMETRICS:
+		p := botipc.GetRootAsMetricsPayload(data, 0)
+		timings := make([]map[string]interface{}, p.AnamHttpLength())
+		var timing botipc.HttpTiming
+		for i := range timings {
+			p.AnamHttp(&timing, i)
+			timings[i] = map[string]interface{}{
+				"endpoint":    string(timing.Endpoint()),
+				"status_code": timing.StatusCode(),
+				"duration_ms": timing.DurationMs(),
+			}
+		}
+		var gate interface{}
+		if g := p.Gate(nil); g != nil {
+			ends := make([]map[string]interface{}, g.EndsLength())
+			var end botipc.GateEnd
+			for i := range ends {
+				g.Ends(&end, i)
+				ends[i] = map[string]interface{}{
+					"at_ms":            end.AtMs(),
+					"reason":           string(end.Reason()),
+					"segment_ms":       end.SegmentMs(),
+					"silence_ms":       end.SilenceMs(),
+					"hangover_ms":      end.HangoverMs(),
+					"level_dbfs":       end.LevelDbfs(),
+					"threshold_dbfs":   end.ThresholdDbfs(),
+					"noise_floor_dbfs": end.NoiseFloorDbfs(),
+				}
+			}
+			gate = map[string]interface{}{
+				"noise_floor_dbfs":  g.NoiseFloorDbfs(),
+				"speech_level_dbfs": g.SpeechLevelDbfs(),
+				"threshold_dbfs":    g.ThresholdDbfs(),
+				"hangover_ms":       g.HangoverMs(),
+				"preroll_ms":        g.PrerollMs(),
+				"gap_ms":            g.GapMs(),
+				"ends":              ends,
+			}
+		}
+		return map[string]interface{}{
+			"task_id":             string(p.TaskId()),
+			"frames_received":     p.FramesReceived(),
+			"frames_forwarded":    p.FramesForwarded(),
+			"voice_segments":      p.VoiceSegments(),
+			"voice_end_count":     p.VoiceEndCount(),
+			"anam_http":           timings,
+			"rms_avg":             p.RmsAvg(),
+			"rms_peak":            p.RmsPeak(),
+			"anam_send_errors":    p.AnamSendErrors(),
+			"ws_rtt_ms":           p.WsRttMs(),
+			"ms_since_audio":      p.MsSinceAudio(),
+			"anam_dropped_frames": p.AnamDroppedFrames(),
+			"anam_queue_depth":    p.AnamQueueDepth(),
+			"anam_queue_peak":     p.AnamQueuePeak(),
+			"gate":                gate,
+		}
+
+	case botipc.MessageTypeSESSION_LIST:
+		p := botipc.GetRootAsSessionListPayload(data, 0)
+		sessions := make([]map[string]interface{}, p.SessionsLength())
+		var info botipc.SessionInfo
+		for i := range sessions {
+			p.Sessions(&info, i)
+			sessions[i] = map[string]interface{}{
+				"task_id":         string(info.TaskId()),
+				"channel":         string(info.Channel()),
+				"target_language": string(info.TargetLanguage()),
+				"status":          info.Status().String(),
+				"bot_uid":         info.BotUid(),
+				"palabra_uid":     info.PalabraUid(),
+				"anam_uid":        info.AnamUid(),
+				"started_at_ms":   info.StartedAtMs(),
+			}
+		}
+		return map[string]interface{}{
+			"pid":          p.Pid(),
+			"max_sessions": p.MaxSessions(),
+			"sessions":     sessions,
+		}
+
+	case botipc.MessageTypeACK, botipc.MessageTypeNACK:
+		p := botipc.GetRootAsAckPayload(data, 0)
+		return map[string]interface{}{
+			"task_id":    string(p.TaskId()),
+			"command":    p.Command().String(),
+			"error_code": string(p.ErrorCode()),
+			"message":    string(p.Message()),
+		}
+
+	case botipc.MessageTypeREGISTER_NODE:
+		p := botipc.GetRootAsNodeRegisterPayload(data, 0)
+		return map[string]interface{}{
+			"node_id":  string(p.NodeId()),
+			"hostname": string(p.Hostname()),
+			"capacity": p.Capacity(),
+			"token":    d.secret(p.Token()),
+		}
+
+	case botipc.MessageTypeNODE_HEARTBEAT:
+		p := botipc.GetRootAsNodeHeartbeatPayload(data, 0)
+		return map[string]interface{}{
+			"node_id":         string(p.NodeId()),
+			"active_sessions": p.ActiveSessions(),
+		}
+
+	case botipc.MessageTypeHELLO:
+		p := botipc.GetRootAsHelloPayload(data, 0)
+		types := make([]string, p.MessageTypesLength())
+		for i := range types {
+			types[i] = p.MessageTypes(i).String()
+		}
+		capabilities := make([]string, p.CapabilitiesLength())
+		for i := range capabilities {
+			capabilities[i] = string(p.Capabilities(i))
+		}
+		return map[string]interface{}{
+			"protocol_version": p.ProtocolVersion(),
+			"build_id":         string(p.BuildId()),
+			"message_types":    types,
+			"capabilities":     capabilities,
+		}
+
+	default:
+		return map[string]interface{}{
+			"raw_bytes": len(data),
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// ipcdump prints IPC captures written by the server (PALABRA_IPC_CAPTURE_DIR)
+// as JSON lines, one per message.
+//
+// With -replay it instead plays the server side of a capture against a
+// bot_worker binary, with the original timing, and prints what is sent and
+// what the worker answers. This reproduces a live meeting's session without
+// the server:
+//
+//	ipcdump capture.ipccap
+//	ipcdump -replay ./bot_worker -speed 2 capture.ipccap
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+var (
+	showSecrets = flag.Bool("secrets", false, "Print tokens and API keys instead of redacting them")
+	replayPath  = flag.String("replay", "", "Replay the server side of the capture against this bot_worker binary")
+	speed       = flag.Float64("speed", 1, "Replay speed factor (0 sends without delays)")
+	linger      = flag.Duration("linger", 10*time.Second, "How long to keep the worker running after the last replayed message")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <capture%s>\n", os.Args[0], ipc.CaptureExtension)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	records, err := readCapture(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("ipcdump: %v", err)
+	}
+
+	out := &printer{enc: json.NewEncoder(os.Stdout), decoder: decoder{showSecrets: *showSecrets}}
+	if *replayPath != "" {
+		if err := replay(records, out); err != nil {
+			log.Fatalf("ipcdump: %v", err)
+		}
+		return
+	}
+
+	for _, record := range records {
+		out.print(record.Time, records[0].Time, record.Direction, record.Message)
+	}
+}
+
+// readCapture reads every record of a capture. A record cut short by a crash
+// ends the capture with a warning.
+func readCapture(path string) ([]ipc.CaptureRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := ipc.NewCaptureReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var records []ipc.CaptureRecord
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("ipcdump: %s: stopping at record %d: %v", path, len(records)+1, err)
+			break
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: no messages captured", path)
+	}
+	return records, nil
+}
+
+// printer writes dump records to stdout; replay prints from two goroutines
+type printer struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	decoder decoder
+}
+
+func (p *printer) print(at, start time.Time, direction ipc.Direction, msg []byte) {
+	record := p.decoder.decode(direction, msg)
+	record.Time = at.UTC().Format(time.RFC3339Nano)
+	record.OffsetMs = at.Sub(start).Milliseconds()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enc.Encode(record)
+}
+
+// replay starts the bot_worker over stdin/stdout and sends it the captured
+// server messages, keeping their request IDs so the answers can be matched
+func replay(records []ipc.CaptureRecord, out *printer) error {
+	// Host as many sessions as the capture starts
+	maxSessions := 0
+	for _, record := range records {
+		if record.Direction != ipc.ToWorker {
+			continue
+		}
+		if msgType, _, err := ipc.ParseIPCMessage(record.Message); err == nil && msgType == botipc.MessageTypeSTART_SESSION {
+			maxSessions++
+		}
+	}
+
+	cmd := exec.Command(*replayPath, "-max-sessions", strconv.Itoa(max(maxSessions, 1)))
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", *replayPath, err)
+	}
+	log.Printf("ipcdump: replaying %d messages against %s (PID %d)", len(records), *replayPath, cmd.Process.Pid)
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader := ipc.NewMessageReader(stdout)
+		for {
+			msg, err := reader.ReadMessage()
+			if err != nil {
+				return
+			}
+			out.print(time.Now(), start, ipc.FromWorker, msg)
+		}
+	}()
+
+	writer := ipc.NewMessageWriter(stdin)
+	for _, record := range records {
+		if record.Direction != ipc.ToWorker {
+			continue
+		}
+		if *speed > 0 {
+			due := start.Add(time.Duration(float64(record.Time.Sub(records[0].Time)) / *speed))
+			time.Sleep(time.Until(due))
+		}
+		if err := writer.WriteMessage(record.Message); err != nil {
+			log.Printf("ipcdump: worker stopped reading: %v", err)
+			break
+		}
+		out.print(time.Now(), start, ipc.ToWorker, record.Message)
+	}
+
+	// Closing stdin makes the worker stop its sessions and exit
+	select {
+	case <-done:
+	case <-time.After(*linger):
+	}
+	stdin.Close()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		log.Printf("ipcdump: worker did not exit, killing it")
+		cmd.Process.Kill()
+	}
+
+	err = cmd.Wait()
+	log.Printf("ipcdump: worker exited: %v", err)
+	return nil
+}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/gorilla/mux"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog/hlog"
 
@@ -125,6 +126,24 @@ func main() {
 	router.HandleFunc("/v1/palabra/start", http.HandlerFunc(requestHandler.PalabraStart))
 	router.HandleFunc("/v1/palabra/stop", http.HandlerFunc(requestHandler.PalabraStop))
 	router.HandleFunc("/v1/palabra/tasks", http.HandlerFunc(requestHandler.PalabraTasks))
+	router.HandleFunc("/v1/palabra/tasks/{id}/status", http.HandlerFunc(requestHandler.PalabraTaskStatus)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/tasks/{id}/history", http.HandlerFunc(requestHandler.PalabraTaskHistory)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/tasks/{id}/logs", http.HandlerFunc(requestHandler.PalabraTaskLogs)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/tasks/{id}/control", http.HandlerFunc(requestHandler.PalabraTaskControl)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/palabra/channels/{channel}/events", http.HandlerFunc(requestHandler.PalabraStreamEvents)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/crashes", http.HandlerFunc(requestHandler.PalabraCrashes)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/crashes/{name}", http.HandlerFunc(requestHandler.PalabraCrashDownload)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/captures", http.HandlerFunc(requestHandler.PalabraAudioCaptures)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/palabra/captures/{name}", http.HandlerFunc(requestHandler.PalabraAudioCaptureDownload)).Methods(http.MethodGet)
+	router.HandleFunc("/v1/channels/{channel}/transcripts", http.HandlerFunc(requestHandler.ChannelTranscripts)).Methods(http.MethodGet)
+	router.Handle("/metrics", promhttp.Handler())
+
+	// Create the bot manager up front, so it reattaches to the bot_workers of
+	// the previous run and remote nodes can register before the first session
+	// is requested
+	if viper.GetBool("ENABLE_ANAM") {
+		services.GetBotProcessManager(logger)
+	}
 
 	// Stub endpoints for local development
 	router.HandleFunc("/v1/user/details", http.HandlerFunc(requestHandler.UserDetails))
@@ -3,43 +3,117 @@ package services
 import (
 	"encoding/base64"
 	"fmt"
-	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	agoraservice "github.com/AgoraIO-Extensions/Agora-Golang-Server-SDK/v2/go_sdk/rtc"
+	"github.com/samyak-jain/agora_backend/services/audio"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
 )
 
+// The Agora service is a process-wide singleton. When a bot_worker hosts
+// several sessions, the first bot initializes it and the last one releases it.
+var (
+	agoraServiceMu   sync.Mutex
+	agoraServiceRefs int
+)
+
+// acquireAgoraService initializes the Agora service on first use
+func acquireAgoraService(appID string, logf LogFunc) {
+	agoraServiceMu.Lock()
+	defer agoraServiceMu.Unlock()
+
+	if agoraServiceRefs == 0 {
+		svcCfg := agoraservice.NewAgoraServiceConfig()
+		svcCfg.AppId = appID
+		svcCfg.LogPath = "./agora_rtc_log/agorasdk.log"
+		svcCfg.ConfigDir = "./agora_rtc_log"
+		svcCfg.DataDir = "./agora_rtc_log"
+		if model := apmModel(); model > 0 {
+			// Needed by the apm loudness engine; leave the remote tracks unprocessed
+			svcCfg.APMModel = model
+			svcCfg.APMConfig = passthroughAPMConfig()
+		}
+
+		agoraservice.Initialize(svcCfg)
+		logf(botipc.LogLevelINFO, "Agora service initialized")
+	}
+	agoraServiceRefs++
+}
+
+// releaseAgoraService releases the Agora service once no bot is using it
+func releaseAgoraService(logf LogFunc) {
+	agoraServiceMu.Lock()
+	defer agoraServiceMu.Unlock()
+
+	agoraServiceRefs--
+	if agoraServiceRefs == 0 {
+		agoraservice.Release()
+		logf(botipc.LogLevelINFO, "Agora service released")
+	}
+}
+
+// Rate the SDK delivers the target's audio at
+const inputSampleRate = 16000
+
 // AgoraBot subscribes to Palabra audio (UID 3000) and forwards to Anam WebSocket
 type AgoraBot struct {
-	appID         string
-	channel       string
-	botUID        string // UID 4000+ (Anam avatar)
-	token         string
-	targetUID     string // UID 3000+ (Palabra audio to subscribe to)
-	anamClient    *AnamClient
-	conn          *agoraservice.RtcConnection
-	stopChan      chan struct{}
+	appID          string
+	channel        string
+	botUID         string // UID 4000+ (Anam avatar)
+	token          string
+	targetUID      string // UID 3000+ (Palabra audio to subscribe to, guarded by targetMu)
+	targetMu       sync.RWMutex
+	anamClient     *AnamClient
+	conn           *botConnection // Own or shared with the bot's fan-out group
+	fanoutGroup    string         // Bots of the same group share their connection, "" for one of its own
+	stopChan       chan struct{}
 	targetLeftChan chan struct{} // Signals when target UID leaves channel
-	isConnected   bool
-	isSpeaking    bool   // Track if currently sending speech to Anam
-	silenceFrames int    // Count consecutive silent frames (for voice_end)
-	frameCount    int    // Total frames forwarded (for logging)
-	pcmFile       *os.File // Debug: record PCM audio for Audacity
-
-	// Voice Activity Detection (VAD) state
-	audioBuffer   [][]byte // Ring buffer for pre-roll (stores last 10 frames = ~100ms)
-	bufferIndex   int      // Current position in ring buffer
-	rmsThreshold  int64    // RMS threshold for voice detection (default: 100)
-	speechFrames  int      // Count frames above threshold before triggering speech
-	sendingAudio  bool     // Currently sending audio to Anam
+	isConnected    bool
+	isSpeaking     bool          // Track if currently sending speech to Anam
+	frameCount     int           // Frames forwarded in the current segment (for logging)
+	messageCount   int           // Messages sent in the current segment (for logging)
+	droppedCount   int           // Messages dropped by the send queue in the current segment (for logging)
+	capture        *audioCapture // Records input and Anam audio (nil unless the session asked)
+
+	// Captions of the target's data stream (see captions.go), nil unless the session asked
+	onCaption       func(caption ipc.Caption)
+	captionLanguage string // Language of the target's stream
+
+	// Voice Activity Detection (VAD), chosen per session (energy gate by default)
+	detector       VoiceDetector
+	chunkDuration  time.Duration                        // Audio per message sent to Anam
+	loudness       *audio.LoudnessConfig                // Normalization of the audio sent to Anam, nil when off
+	loudnessEngine string                               // See LoudnessEngine*
+	apm            *agoraservice.ExternalAudioProcessor // Runs the AGC ahead of the pipeline (apm engine only)
+	pipeline       *audio.Pipeline                      // Target audio -> Anam, fed from the SDK audio thread (or the APM's)
+
+	// Controlled at runtime (read by the SDK audio thread)
+	paused     atomic.Bool // Forwarding paused through PauseForwarding
+	endSegment atomic.Bool // Ask the audio thread to close the current speech segment
 
 	// Idle detection
-	lastAudioTime time.Time // Time when audio was last forwarded to Anam
+	lastAudioTime atomic.Int64 // UnixNano of when audio was last forwarded to Anam
+
+	// Counters reported to the parent (updated from the SDK audio thread)
+	framesReceived  atomic.Uint64
+	framesForwarded atomic.Uint64
+	voiceSegments   atomic.Uint64
+	voiceEnds       atomic.Uint64
+
+	// Frame RMS levels since the last DrainLevels
+	rmsSum    atomic.Uint64
+	rmsFrames atomic.Uint64
+	rmsPeak   atomic.Uint64
+
+	logFunc LogFunc // Routes log output to the session (stdout if nil)
 }
 
 // NewAgoraBot creates a new Agora bot that subscribes to audio and forwards to Anam
 func NewAgoraBot(appID, channel, botUID, token, targetUID string, anamClient *AnamClient) *AgoraBot {
-	return &AgoraBot{
+	b := &AgoraBot{
 		appID:          appID,
 		channel:        channel,
 		botUID:         botUID,
@@ -49,250 +123,142 @@ func NewAgoraBot(appID, channel, botUID, token, targetUID string, anamClient *An
 		stopChan:       make(chan struct{}),
 		targetLeftChan: make(chan struct{}),
 		isConnected:    false,
-		audioBuffer:    make([][]byte, 10), // 10 frames = ~100ms pre-roll
-		rmsThreshold:   100,                // RMS threshold for voice detection
-		sendingAudio:   false,
-		lastAudioTime:  time.Now(), // Initialize to now
+		detector:       newEnergyDetector(),
+		chunkDuration:  audio.DefaultChunkDuration,
 	}
+	b.lastAudioTime.Store(time.Now().UnixNano())
+	return b
 }
 
 // Start connects the bot to Agora and subscribes to target UID
 func (b *AgoraBot) Start() error {
-	// Initialize Agora service
-	svcCfg := agoraservice.NewAgoraServiceConfig()
-	svcCfg.AppId = b.appID
-	svcCfg.LogPath = "./agora_rtc_log/agorasdk.log"
-	svcCfg.ConfigDir = "./agora_rtc_log"
-	svcCfg.DataDir = "./agora_rtc_log"
-
-	agoraservice.Initialize(svcCfg)
-	fmt.Printf("[AgoraBot] Agora service initialized\n")
-
-	// Create RTC connection config WITHOUT auto-subscribe
-	// Bot will manually subscribe ONLY to target UID (Palabra 3000)
-	conCfg := &agoraservice.RtcConnectionConfig{
-		AutoSubscribeAudio: false, // CRITICAL: Don't auto-subscribe to all users
-		AutoSubscribeVideo: false,
-		ClientRole:         agoraservice.ClientRoleBroadcaster,
-		ChannelProfile:     agoraservice.ChannelProfileLiveBroadcasting,
-	}
-
-	// Create publish config (needed even if not publishing)
-	publishConfig := agoraservice.NewRtcConPublishConfig()
-	publishConfig.AudioPublishType = agoraservice.AudioPublishTypePcm
-	publishConfig.IsPublishAudio = false // Not publishing, only subscribing
-	publishConfig.IsPublishVideo = false
-	publishConfig.AudioScenario = agoraservice.AudioScenarioDefault
-
-	// Create connection
-	b.conn = agoraservice.NewRtcConnection(conCfg, publishConfig)
-	if b.conn == nil {
-		return fmt.Errorf("failed to create RTC connection")
-	}
-
-	fmt.Printf("[AgoraBot] RTC connection created\n")
-
-	// Open PCM file for debugging (can be imported to Audacity as Raw PCM: 24kHz, mono, 16-bit signed LE)
-	pcmFile, err := os.Create("/tmp/anam_audio_24khz.pcm")
+	// Resample the target's audio to the rate the Anam session takes
+	anamRate := b.anamClient.SampleRate()
+	resampler, err := audio.NewResampler(inputSampleRate, anamRate, 1)
 	if err != nil {
-		fmt.Printf("[AgoraBot] WARNING: Could not create PCM debug file: %v\n", err)
-	} else {
-		b.pcmFile = pcmFile
-		fmt.Printf("[AgoraBot] Recording PCM to /tmp/anam_audio_24khz.pcm (import to Audacity: Raw, 24000Hz, mono, 16-bit signed LE)\n")
+		return fmt.Errorf("failed to create resampler: %w", err)
 	}
+	b.log(botipc.LogLevelINFO, "Resampling %d Hz -> %d Hz for Anam, %s per message", inputSampleRate, anamRate, b.chunkDuration)
 
-	// Create connection signal channel (to wait for connection before registering observers)
-	connSignal := make(chan struct{})
+	// Initialize Agora service (shared by all bots in this process)
+	acquireAgoraService(b.appID, b.log)
 
-	// Register connection observer
-	connObserver := &agoraservice.RtcConnectionObserver{
-		OnConnected: func(con *agoraservice.RtcConnection, info *agoraservice.RtcConnectionInfo, reason int) {
-			fmt.Printf("[AgoraBot] ✅ Bot (UID %s) connected to channel: %s\n", b.botUID, info.ChannelId)
-			connSignal <- struct{}{} // Signal that connection is ready
-		},
-		OnDisconnected: func(con *agoraservice.RtcConnection, info *agoraservice.RtcConnectionInfo, reason int) {
-			fmt.Printf("[AgoraBot] ❌ Bot (UID %s) disconnected from channel: %s\n", b.botUID, info.ChannelId)
-		},
-		OnUserJoined: func(con *agoraservice.RtcConnection, uid string) {
-			fmt.Printf("[AgoraBot] 👤 User joined channel: UID %s (Bot listening for UID %s)\n", uid, b.targetUID)
-
-			// Explicitly subscribe to Palabra audio when it joins
-			if uid == b.targetUID {
-				fmt.Printf("[AgoraBot] 🎯 Target UID %s joined! Bot will now subscribe and forward audio to Anam\n", uid)
-				fmt.Printf("[AgoraBot] Target UID %s joined! Explicitly subscribing to audio...\n", uid)
-
-				// Get local user and subscribe
-				localUser := con.GetLocalUser()
-				if localUser != nil {
-					ret := localUser.SubscribeAudio(uid)
-					if ret == 0 {
-						fmt.Printf("[AgoraBot] Successfully subscribed to audio from UID %s\n", uid)
-					} else {
-						fmt.Printf("[AgoraBot] ERROR: Failed to subscribe to audio from UID %s, ret=%d\n", uid, ret)
-					}
-				} else {
-					fmt.Printf("[AgoraBot] ERROR: localUser is nil, cannot subscribe\n")
-				}
-			}
-		},
-		OnUserLeft: func(con *agoraservice.RtcConnection, uid string, reason int) {
-			fmt.Printf("[AgoraBot] User left: %s (reason: %d)\n", uid, reason)
-			// If our target UID (Palabra bot) leaves, signal to stop
-			if uid == b.targetUID {
-				fmt.Printf("[AgoraBot] ⚠️ Target UID %s left channel - signaling shutdown\n", uid)
-				select {
-				case <-b.targetLeftChan:
-					// Already closed
-				default:
-					close(b.targetLeftChan)
-				}
-			}
-		},
+	// The SDK audio callback only feeds the pipeline; everything from the
+	// level meter to the Anam sink is a stage. Loudness normalization runs
+	// before the detector, so quiet speech is detected too.
+	stages := []audio.Stage{audio.Convert(), audio.StageFunc(b.meter)}
+	if b.loudness != nil {
+		stages = append(stages, b.loudnessStage())
 	}
+	b.pipeline = audio.NewPipeline(append(stages,
+		audio.Detect(b.detector),
+		audio.Resample(resampler),
+		audio.Chunk(b.chunkDuration),
+		audio.StageFunc(b.deliver),
+	)...)
+
+	// Join the channel on a connection of its own, or on the one of the
+	// bot's fan-out group (see agora_connection.go)
+	conn, err := attachConnection(b)
+	if err != nil {
+		b.releaseAPM()
+		releaseAgoraService(b.log)
+		return err
+	}
+	b.conn = conn
+	b.log(botipc.LogLevelINFO, "Audio frame observer registered (VAD: %s)", b.detector.Settings())
 
-	b.conn.RegisterObserver(connObserver)
+	b.isConnected = true
+	b.log(botipc.LogLevelINFO, "Bot ready - subscribed to UID %s", b.target())
 
-	// Connect to channel FIRST
-	b.conn.Connect(b.token, b.channel, b.botUID)
-	fmt.Printf("[AgoraBot] Connecting to channel %s as UID %s...\n", b.channel, b.botUID)
+	// NOTE: No test silence sender - only forward real audio from Palabra
+	b.log(botipc.LogLevelINFO, "Waiting for audio from Palabra UID %s", b.target())
 
-	// Wait for connection to complete (like the working example)
-	<-connSignal
-	fmt.Printf("[AgoraBot] Connection established! Now registering audio observer...\n")
+	return nil
+}
 
-	// Get localUser AFTER connection (critical!)
-	localUser := b.conn.GetLocalUser()
-	if localUser != nil {
-		// Set audio parameters (from working example)
-		localUser.SetPlaybackAudioFrameBeforeMixingParameters(1, 16000)
-		fmt.Printf("[AgoraBot] Audio parameters set\n")
+// observerVAD returns the VAD the audio observer must run for the detector,
+// nil if none. With the APM, the APM runs it.
+func (b *AgoraBot) observerVAD() *agoraservice.AudioVadConfigV2 {
+	if b.apm != nil {
+		return nil
 	}
+	return b.detector.ObserverVAD()
+}
 
-	// Register audio frame observer AFTER connection
-	audioObserver := &agoraservice.AudioFrameObserver{
-		OnPlaybackAudioFrameBeforeMixing: func(localUser *agoraservice.LocalUser, channelId string, userId string, frame *agoraservice.AudioFrame, vadResultState agoraservice.VadState, vadResultFrame *agoraservice.AudioFrame) bool {
-			// DEBUG: Log EVERY audio callback
-			fmt.Printf("[AgoraBot] Audio callback fired - UID: %s, BufferSize: %d, Target: %s\n", userId, len(frame.Buffer), b.targetUID)
-
-			// Only forward audio from Palabra UID
-			if userId == b.targetUID {
-				// CRITICAL: Anam expects 24kHz audio, but Agora gives us 16kHz
-				// We need to upsample from 16kHz to 24kHz (ratio 3:2)
-
-				if frame.SamplesPerSec != 16000 {
-					fmt.Printf("[AgoraBot] WARNING: Unexpected sample rate %d Hz (expected 16000 Hz)\n", frame.SamplesPerSec)
-				}
+// userJoined logs a user joining the channel and reports whether it is the
+// bot's target, which the connection then subscribes to
+func (b *AgoraBot) userJoined(uid string) bool {
+	target := b.target()
+	b.log(botipc.LogLevelINFO, "👤 User joined channel: UID %s (Bot listening for UID %s)", uid, target)
 
-				// Convert PCM bytes to int16 samples
-				inputSamples := make([]int16, len(frame.Buffer)/2)
-				for i := 0; i < len(inputSamples); i++ {
-					inputSamples[i] = int16(frame.Buffer[i*2]) | int16(frame.Buffer[i*2+1])<<8
-				}
-
-				// Calculate RMS (volume level)
-				_, rms := isFrameSilent(inputSamples)
+	// Explicitly subscribe to Palabra audio when it joins
+	if uid != target {
+		return false
+	}
+	b.log(botipc.LogLevelINFO, "🎯 Target UID %s joined! Bot will now subscribe and forward audio to Anam", uid)
+	b.log(botipc.LogLevelINFO, "Target UID %s joined! Explicitly subscribing to audio...", uid)
+	return true
+}
 
-				// Upsample to 24kHz
-				outputSamples := upsample16to24(inputSamples)
+// userLeft signals shutdown when the target leaves the channel
+func (b *AgoraBot) userLeft(uid string, reason int) {
+	b.log(botipc.LogLevelINFO, "User left: %s (reason: %d)", uid, reason)
+	// If our target UID (Palabra bot) leaves, signal to stop
+	if uid == b.target() {
+		b.log(botipc.LogLevelWARN, "⚠️ Target UID %s left channel - signaling shutdown", uid)
+		select {
+		case <-b.targetLeftChan:
+			// Already closed
+		default:
+			close(b.targetLeftChan)
+		}
+	}
+}
 
-				// Convert back to bytes
-				outputBytes := make([]byte, len(outputSamples)*2)
-				for i, sample := range outputSamples {
-					outputBytes[i*2] = byte(sample)
-					outputBytes[i*2+1] = byte(sample >> 8)
-				}
+// audioFrame takes a frame from the SDK audio thread, keeping only the
+// target's. On a shared connection it sees the other bots' targets too.
+func (b *AgoraBot) audioFrame(userId string, frame *agoraservice.AudioFrame, vadResultState agoraservice.VadState, vadResultFrame *agoraservice.AudioFrame) {
+	target := b.target()
 
-				// VOICE ACTIVITY DETECTION (VAD)
-				// Store frame in ring buffer (for pre-roll)
-				b.audioBuffer[b.bufferIndex] = outputBytes
-				b.bufferIndex = (b.bufferIndex + 1) % len(b.audioBuffer)
-
-				// Check if voice detected (RMS above threshold)
-				voiceDetected := rms > b.rmsThreshold
-
-				if voiceDetected {
-					// Voice detected!
-					if !b.sendingAudio {
-						// START sending audio to Anam
-						// First, send pre-roll buffer (last 100ms) to catch the beginning
-						fmt.Printf("[AgoraBot] 🎤 VOICE DETECTED (RMS=%d) - Starting audio stream with 100ms pre-roll\n", rms)
-
-						// Send buffered frames (last 10 frames = ~100ms)
-						sentPreroll := 0
-						for i := 0; i < len(b.audioBuffer); i++ {
-							idx := (b.bufferIndex + i) % len(b.audioBuffer)
-							if b.audioBuffer[idx] != nil {
-								prerollB64 := base64.StdEncoding.EncodeToString(b.audioBuffer[idx])
-								b.anamClient.SendAudioWithSampleRate(prerollB64, 24000)
-								sentPreroll++
-							}
-						}
-						fmt.Printf("[AgoraBot] 📤 Sent %d pre-roll frames (~%dms)\n", sentPreroll, sentPreroll*10)
-
-						b.sendingAudio = true
-						b.isSpeaking = true
-					}
-
-					// Reset silence counter
-					b.silenceFrames = 0
-
-					// Send current frame
-					audioB64 := base64.StdEncoding.EncodeToString(outputBytes)
-					err := b.anamClient.SendAudioWithSampleRate(audioB64, 24000)
-					if err != nil {
-						fmt.Printf("[AgoraBot] ❌ Error forwarding audio: %v\n", err)
-					}
-
-					// Update last audio time for idle detection
-					b.lastAudioTime = time.Now()
-
-					// Log every 100 frames (~1 second)
-					b.frameCount++
-					if b.frameCount%100 == 0 {
-						fmt.Printf("[AgoraBot] 📊 Sending voice: %d frames total, RMS=%d\n", b.frameCount, rms)
-					}
-
-				} else if b.sendingAudio {
-					// Currently sending but this frame is silent
-					b.silenceFrames++
-
-					// Continue sending for 500ms after voice stops (to avoid cutting off)
-					if b.silenceFrames < 50 {
-						// Still in tail period - keep sending
-						audioB64 := base64.StdEncoding.EncodeToString(outputBytes)
-						b.anamClient.SendAudioWithSampleRate(audioB64, 24000)
-						b.frameCount++
-					} else {
-						// 500ms of silence - STOP sending
-						fmt.Printf("[AgoraBot] 🔇 SILENCE for 500ms (RMS=%d) - Stopping audio stream (sent %d frames total)\n", rms, b.frameCount)
-						b.anamClient.SendVoiceEnd()
-						b.sendingAudio = false
-						b.isSpeaking = false
-						b.silenceFrames = 0
-						b.frameCount = 0
-					}
-				}
+	// DEBUG: Log EVERY audio callback
+	b.log(botipc.LogLevelDEBUG, "Audio callback fired - UID: %s, BufferSize: %d, Target: %s", userId, len(frame.Buffer), target)
 
-				// DEBUG: Write ALL audio to PCM file (for debugging)
-				if b.pcmFile != nil {
-					b.pcmFile.Write(outputBytes)
-				}
-			}
-			return true
-		},
+	// Only forward audio from Palabra UID
+	if userId != target {
+		// Still close the segment of a previous target. With the APM,
+		// only its thread feeds the pipeline.
+		if b.apm == nil {
+			b.closePendingSegment()
+		}
+		return
 	}
+	b.framesReceived.Add(1)
+	b.capture.Input(frame.Buffer)
 
-	// Register audio observer AFTER connection (from working example)
-	b.conn.RegisterAudioFrameObserver(audioObserver, 0, nil)
-	fmt.Printf("[AgoraBot] Audio frame observer registered\n")
-
-	b.isConnected = true
-	fmt.Printf("[AgoraBot] Bot ready - subscribed to UID %s\n", b.targetUID)
-
-	// NOTE: No test silence sender - only forward real audio from Palabra
-	fmt.Printf("[AgoraBot] Waiting for audio from Palabra UID %s\n", b.targetUID)
+	// The APM feeds its processed frames to the pipeline
+	if b.apm != nil {
+		if ret := b.apm.PushAudioPcmData(frame.Buffer, frame.SamplesPerSec, 1, 0); ret != 0 {
+			b.log(botipc.LogLevelWARN, "APM rejected a frame, ret=%d", ret)
+		}
+		return
+	}
+	b.feed(frame, vadResultState, vadResultFrame)
+}
 
-	return nil
+// streamMessage relays the captions of the target's data stream. On a
+// shared connection it sees the other bots' targets too.
+func (b *AgoraBot) streamMessage(uid string, data []byte) {
+	if b.onCaption == nil || uid != b.target() {
+		return
+	}
+	caption, ok := parseCaption(data, b.captionLanguage)
+	if !ok {
+		b.log(botipc.LogLevelDEBUG, "Ignored data stream message from UID %s (%d bytes)", uid, len(data))
+		return
+	}
+	caption.UID = uid
+	b.onCaption(caption)
 }
 
 // sendPeriodicSilence sends silence to Anam every 2 seconds to keep connection alive
@@ -307,15 +273,15 @@ func (b *AgoraBot) sendPeriodicSilence() {
 	for {
 		select {
 		case <-b.stopChan:
-			fmt.Printf("[AgoraBot] Stopping silence sender\n")
+			b.log(botipc.LogLevelINFO, "Stopping silence sender")
 			return
 		case <-ticker.C:
 			if b.anamClient != nil && b.anamClient.IsConnected() {
 				err := b.anamClient.SendAudio(silenceB64)
 				if err != nil {
-					fmt.Printf("[AgoraBot] Error sending test silence to Anam: %v\n", err)
+					b.log(botipc.LogLevelERROR, "Error sending test silence to Anam: %v", err)
 				} else {
-					fmt.Printf("[AgoraBot] Sent test silence to Anam to keep connection alive\n")
+					b.log(botipc.LogLevelDEBUG, "Sent test silence to Anam to keep connection alive")
 				}
 			}
 		}
@@ -330,90 +296,293 @@ func (b *AgoraBot) Stop() error {
 
 	close(b.stopChan)
 
-	if b.pcmFile != nil {
-		b.pcmFile.Close()
-		fmt.Printf("[AgoraBot] PCM debug file closed: /tmp/anam_audio_24khz.pcm\n")
-	}
-
 	if b.conn != nil {
-		b.conn.Disconnect()
-		b.conn.Release()
-		fmt.Printf("[AgoraBot] Disconnected from channel\n")
+		b.conn.detach(b)
 	}
 
-	agoraservice.Release()
-	fmt.Printf("[AgoraBot] Agora service released\n")
+	b.releaseAPM()
+	releaseAgoraService(b.log)
 
 	b.isConnected = false
 	return nil
 }
 
-// IsConnected returns whether the bot is connected
-func (b *AgoraBot) IsConnected() bool {
-	return b.isConnected
+// log writes a log line through logFunc, falling back to stdout
+func (b *AgoraBot) log(level botipc.LogLevel, format string, args ...interface{}) {
+	if b.logFunc != nil {
+		b.logFunc(level, format, args...)
+		return
+	}
+	fmt.Printf("[AgoraBot] "+format+"\n", args...)
 }
 
-// isFrameSilent checks if an audio frame is silent using RMS energy
-func isFrameSilent(samples []int16) (bool, int64) {
-	if len(samples) == 0 {
-		return true, 0
+// target returns the UID whose audio is forwarded
+func (b *AgoraBot) target() string {
+	b.targetMu.RLock()
+	defer b.targetMu.RUnlock()
+	return b.targetUID
+}
+
+// UpdateVAD changes the voice detection settings of a running bot. Zero
+// values leave a setting unchanged.
+func (b *AgoraBot) UpdateVAD(rmsThreshold int64, hangover, preroll time.Duration) error {
+	if rmsThreshold < 0 || hangover < 0 || preroll < 0 {
+		return fmt.Errorf("VAD settings must not be negative")
+	}
+	if err := b.detector.Update(rmsThreshold, hangover, preroll); err != nil {
+		return err
 	}
 
-	// Calculate RMS (Root Mean Square) energy
-	var sum int64
-	for _, sample := range samples {
-		sum += int64(sample) * int64(sample)
+	b.log(botipc.LogLevelINFO, "VAD updated: %s", b.detector.Settings())
+	return nil
+}
+
+// SetFanoutGroup shares the Agora connection with the other bots of group
+// in this process. It must be called before Start.
+func (b *AgoraBot) SetFanoutGroup(group string) {
+	b.fanoutGroup = group
+}
+
+// SetCaptions relays the captions of the target's data stream, in language
+// unless they name theirs, to fn. It must be called before Start.
+func (b *AgoraBot) SetCaptions(language string, fn func(caption ipc.Caption)) {
+	b.captionLanguage = language
+	b.onCaption = fn
+}
+
+// SetAudioCapture records the session's audio. It must be called before Start.
+func (b *AgoraBot) SetAudioCapture(capture *audioCapture) {
+	b.capture = capture
+}
+
+// SetChunkDuration sets how much audio each message to Anam carries, 0 for
+// the default. It must be called before Start.
+func (b *AgoraBot) SetChunkDuration(d time.Duration) {
+	if d <= 0 {
+		d = audio.DefaultChunkDuration
 	}
-	rms := sum / int64(len(samples))
+	b.chunkDuration = d
+}
 
-	// CRITICAL: Lowered threshold based on testing
-	// Palabra audio seems to have lower amplitude than typical speech
-	// Was 1000, now 100 to avoid filtering actual speech
-	const silenceThreshold int64 = 100
+// SetLoudness normalizes the loudness of the audio sent to Anam with engine
+// (see LoudnessEngine*, "" for the default). It must be called before Start.
+func (b *AgoraBot) SetLoudness(engine string, config audio.LoudnessConfig) {
+	if engine == "" {
+		engine = DefaultLoudnessEngine
+	}
+	config = config.WithDefaults()
+	b.loudness = &config
+	b.loudnessEngine = engine
+}
 
-	return rms < silenceThreshold, rms
+// SetVoiceDetector chooses how the bot detects speech. It must be called
+// before Start.
+func (b *AgoraBot) SetVoiceDetector(detector VoiceDetector) {
+	b.detector = detector
 }
 
-// upsample16to24 upsamples PCM16 audio from 16kHz to 24kHz using linear interpolation
-// Input: 160 samples @ 16kHz (10ms of audio)
-// Output: 240 samples @ 24kHz (10ms of audio)
-func upsample16to24(input []int16) []int16 {
-	inputLen := len(input)
-	outputLen := (inputLen * 3) / 2 // 3:2 ratio
+// feed pushes a frame of the target into the pipeline, from the SDK audio
+// thread, or from the APM's thread when the APM processes the audio first
+func (b *AgoraBot) feed(frame *agoraservice.AudioFrame, vadResultState agoraservice.VadState, vadResultFrame *agoraservice.AudioFrame) {
+	b.closePendingSegment()
+	if b.paused.Load() {
+		return
+	}
+	if frame.SamplesPerSec != inputSampleRate {
+		b.log(botipc.LogLevelWARN, "Unexpected sample rate %d Hz (expected %d Hz)", frame.SamplesPerSec, inputSampleRate)
+	}
+	b.pipeline.Push(audioPacket(frame, vadResultState, vadResultFrame))
+}
 
-	output := make([]int16, outputLen)
+// closePendingSegment closes the current speech segment when paused or after
+// a target switch. It must run on the thread feeding the pipeline.
+func (b *AgoraBot) closePendingSegment() {
+	if b.endSegment.Swap(false) {
+		b.pipeline.EndSegment()
+	}
+}
+
+// audioPacket converts an SDK frame and the observer's VAD result for the pipeline
+func audioPacket(frame *agoraservice.AudioFrame, vadState agoraservice.VadState, vadFrame *agoraservice.AudioFrame) audio.Packet {
+	p := audio.Packet{
+		Kind:       audio.KindAudio,
+		PCM:        frame.Buffer,
+		SampleRate: frame.SamplesPerSec,
+		Analysis: audio.FrameAnalysis{
+			FarFieldFlag: frame.FarFieldFlag,
+			Rms:          frame.Rms,
+			VoiceProb:    frame.VoiceProb,
+			MusicProb:    frame.MusicProb,
+			Pitch:        frame.Pitch,
+		},
+		VAD: audio.VADResult{State: audio.VADState(vadState)},
+	}
+	if vadFrame != nil {
+		p.VAD.PCM = vadFrame.Buffer
+	}
+	return p
+}
+
+// meter is the pipeline stage recording the level of every target frame
+func (b *AgoraBot) meter(p audio.Packet, emit func(audio.Packet)) {
+	if p.Kind == audio.KindAudio {
+		b.recordLevel(p.Level)
+	}
+	emit(p)
+}
 
-	// For every 2 input samples, create 3 output samples
-	for i := 0; i < inputLen-1; i++ {
-		outputIdx := (i * 3) / 2
+// deliver is the pipeline's sink: it sends the segments the detector passes
+// on to Anam, resampled to the Anam rate
+func (b *AgoraBot) deliver(p audio.Packet, _ func(audio.Packet)) {
+	switch p.Kind {
+	case audio.KindSegmentStart:
+		b.log(botipc.LogLevelINFO, "🎤 VOICE DETECTED (RMS=%d) - Starting audio stream", p.Level)
+		b.isSpeaking = true
+		b.voiceSegments.Add(1)
+
+	case audio.KindAudio:
+		if b.send(p.PCM, p.SampleRate) {
+			// Count 10ms frames, whatever the chunk duration
+			frames := (len(p.PCM)/2*int(time.Second/audio.FrameDuration) + p.SampleRate/2) / p.SampleRate
+			b.framesForwarded.Add(uint64(frames))
+			b.messageCount++
+
+			// Log about every second
+			if b.frameCount/100 != (b.frameCount+frames)/100 {
+				b.log(botipc.LogLevelDEBUG, "📊 Sending voice: %d frames total, RMS=%d", b.frameCount+frames, p.Level)
+			}
+			b.frameCount += frames
+		}
 
-		// First output sample = input sample
-		output[outputIdx] = input[i]
+		// Update last audio time for idle detection
+		b.lastAudioTime.Store(time.Now().UnixNano())
 
-		// If we have room for interpolated samples
-		if outputIdx+1 < outputLen {
-			// Interpolate between input[i] and input[i+1]
-			// For 3:2, we insert one sample at 2/3 position
-			output[outputIdx+1] = int16((int32(input[i])*1 + int32(input[i+1])*2) / 3)
+	case audio.KindSegmentEnd:
+		b.log(botipc.LogLevelINFO, "🔇 SILENCE (RMS=%d) - Stopping audio stream (sent %d frames total in %d messages, %d dropped)", p.Level, b.frameCount, b.messageCount, b.droppedCount)
+		if gate, ok := b.detector.(GateDiagnoser); ok {
+			if end, ok := gate.LastEnd(); ok {
+				b.log(botipc.LogLevelINFO, "Gate: %s", end)
+			}
 		}
+		b.anamClient.QueueVoiceEnd()
+		b.voiceEnds.Add(1)
+		b.isSpeaking = false
+		b.frameCount = 0
+		b.messageCount = 0
+		b.droppedCount = 0
+	}
+}
 
-		if outputIdx+2 < outputLen && i%2 == 0 {
-			// Every other pair gets a third sample
-			output[outputIdx+2] = int16((int32(input[i])*1 + int32(input[i+1])*1) / 2)
+// send queues PCM for Anam and reports whether it was queued. The
+// AnamClient's writer sends it, so the SDK audio thread never waits on the
+// network.
+func (b *AgoraBot) send(pcm []byte, sampleRate int) bool {
+	if !b.anamClient.QueueAudio(pcm, sampleRate) {
+		if b.droppedCount == 0 {
+			b.log(botipc.LogLevelWARN, "⚠️ Anam send queue full, dropping audio")
 		}
+		b.droppedCount++
+		return false
+	}
+	b.capture.Anam(pcm)
+	return true
+}
+
+// SetForwarding pauses or resumes forwarding audio to Anam. Pausing ends the
+// current speech segment so the avatar stops talking.
+func (b *AgoraBot) SetForwarding(enabled bool) {
+	if b.paused.Swap(!enabled) == !enabled {
+		return
 	}
+	if enabled {
+		b.log(botipc.LogLevelINFO, "▶️ Forwarding resumed")
+	} else {
+		b.endSegment.Store(true)
+		b.log(botipc.LogLevelINFO, "⏸️ Forwarding paused")
+	}
+}
 
-	// Last sample
-	if inputLen > 0 {
-		output[outputLen-1] = input[inputLen-1]
+// SwitchTarget subscribes to a different Palabra UID and forwards its audio
+// instead of the current target's
+func (b *AgoraBot) SwitchTarget(uid string) error {
+	if !b.isConnected || b.conn == nil {
+		return fmt.Errorf("bot is not connected")
 	}
 
-	return output
+	previous := b.target()
+	if uid == previous {
+		return nil
+	}
+
+	if err := b.conn.switchTarget(b, uid); err != nil {
+		return err
+	}
+	b.log(botipc.LogLevelINFO, "🎯 Switched target from UID %s to UID %s", previous, uid)
+	return nil
+}
+
+// setTarget forwards the audio of uid from now on, closing the current
+// speech segment
+func (b *AgoraBot) setTarget(uid string) {
+	b.targetMu.Lock()
+	b.targetUID = uid
+	b.targetMu.Unlock()
+	b.endSegment.Store(true)
+}
+
+// IsConnected returns whether the bot is connected
+func (b *AgoraBot) IsConnected() bool {
+	return b.isConnected
 }
 
 // GetIdleDuration returns how long since audio was last sent to Anam
 func (b *AgoraBot) GetIdleDuration() time.Duration {
-	return time.Since(b.lastAudioTime)
+	return time.Since(time.Unix(0, b.lastAudioTime.Load()))
+}
+
+// FramesReceived returns the audio frames received from the target UID since
+// the bot started
+func (b *AgoraBot) FramesReceived() uint64 {
+	return b.framesReceived.Load()
+}
+
+// recordLevel accumulates the RMS of a frame received from the target UID
+func (b *AgoraBot) recordLevel(rms int64) {
+	level := uint64(rms)
+	b.rmsSum.Add(level)
+	b.rmsFrames.Add(1)
+	for {
+		peak := b.rmsPeak.Load()
+		if level <= peak || b.rmsPeak.CompareAndSwap(peak, level) {
+			return
+		}
+	}
+}
+
+// DrainLevels returns the mean and peak frame RMS since the previous call
+func (b *AgoraBot) DrainLevels() (avg, peak uint32) {
+	sum, frames := b.rmsSum.Swap(0), b.rmsFrames.Swap(0)
+	peak = uint32(b.rmsPeak.Swap(0))
+	if frames > 0 {
+		avg = uint32(sum / frames)
+	}
+	return avg, peak
+}
+
+// AudioStats returns the frames forwarded to Anam, speech segments started and
+// voice_end signals sent since the bot started
+func (b *AgoraBot) AudioStats() (framesForwarded, voiceSegments, voiceEnds uint64) {
+	return b.framesForwarded.Load(), b.voiceSegments.Load(), b.voiceEnds.Load()
+}
+
+// GateDiagnostics returns the adaptive gate's view of the target's audio and
+// reports whether the session's detector has one
+func (b *AgoraBot) GateDiagnostics() (audio.GateDiagnostics, bool) {
+	gate, ok := b.detector.(GateDiagnoser)
+	if !ok {
+		return audio.GateDiagnostics{}, false
+	}
+	return gate.Diagnostics(), true
 }
 
 // TargetLeftChan returns a channel that closes when target UID leaves
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	agoraservice "github.com/AgoraIO-Extensions/Agora-Golang-Server-SDK/v2/go_sdk/rtc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Time a bot's connection has to join the channel. The bots of its fan-out
+// group wait for it as well.
+const agoraConnectTimeout = 10 * time.Second
+
+// Bots of the same fan-out group and channel in a bot_worker share one
+// connection (group + "/" + channel -> connection)
+var (
+	fanoutMu          sync.Mutex
+	fanoutConnections = make(map[string]*botConnection)
+)
+
+// botConnection is the Agora connection of one or more bots. A bot without a
+// fan-out group has one of its own. The bots of a group share one that joins
+// as the first bot's UID, subscribes to each bot's target and hands every
+// frame to every bot; each bot keeps only its target's frames, so its
+// detector, pipeline and Anam sender stay its own.
+type botConnection struct {
+	key   string // Key in fanoutConnections, "" for a bot's own connection
+	uid   string // UID the connection joined as
+	conn  *agoraservice.RtcConnection
+	ready chan struct{} // Closed once connected or failed
+	err   error         // Why connecting failed, set before ready is closed
+
+	mu          sync.RWMutex // Read-held while the audio thread hands a frame to the bots
+	bots        []*AgoraBot
+	targets     map[string]int  // Target UID -> bots forwarding it (subscribed while > 0)
+	joined      map[string]bool // Remote users in the channel
+	observerVAD bool            // The audio observer runs the SDK's VAD
+	closed      bool            // The last bot left; the connection is released
+}
+
+// attachConnection connects b to the Agora channel, sharing the connection
+// of its fan-out group if it has one
+func attachConnection(b *AgoraBot) (*botConnection, error) {
+	if b.fanoutGroup == "" {
+		c := newBotConnection("", b)
+		if err := c.connect(b); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	key := b.fanoutGroup + "/" + b.channel
+	for {
+		fanoutMu.Lock()
+		c, ok := fanoutConnections[key]
+		if !ok {
+			c = newBotConnection(key, b)
+			fanoutConnections[key] = c
+		}
+		fanoutMu.Unlock()
+
+		if !ok {
+			if err := c.connect(b); err != nil {
+				fanoutMu.Lock()
+				delete(fanoutConnections, key)
+				fanoutMu.Unlock()
+				return nil, err
+			}
+			b.log(botipc.LogLevelINFO, "Opened the connection of fan-out group %s", b.fanoutGroup)
+			return c, nil
+		}
+
+		<-c.ready
+		if c.err != nil {
+			return nil, c.err
+		}
+		if c.attach(b) {
+			return c, nil
+		}
+		// Released by its last bot meanwhile; open a new one
+	}
+}
+
+// newBotConnection creates the connection record of first, its first bot
+func newBotConnection(key string, first *AgoraBot) *botConnection {
+	return &botConnection{
+		key:     key,
+		uid:     first.botUID,
+		ready:   make(chan struct{}),
+		bots:    []*AgoraBot{first},
+		targets: map[string]int{first.target(): 1},
+		joined:  make(map[string]bool),
+	}
+}
+
+// connect joins the channel as first, already counted as the connection's bot
+func (c *botConnection) connect(first *AgoraBot) error {
+	defer close(c.ready)
+
+	// Create RTC connection config WITHOUT auto-subscribe
+	// Bot will manually subscribe ONLY to target UIDs (Palabra 3000+)
+	conCfg := &agoraservice.RtcConnectionConfig{
+		AutoSubscribeAudio: false, // CRITICAL: Don't auto-subscribe to all users
+		AutoSubscribeVideo: false,
+		ClientRole:         agoraservice.ClientRoleBroadcaster,
+		ChannelProfile:     agoraservice.ChannelProfileLiveBroadcasting,
+	}
+
+	// Create publish config (needed even if not publishing)
+	publishConfig := agoraservice.NewRtcConPublishConfig()
+	publishConfig.AudioPublishType = agoraservice.AudioPublishTypePcm
+	publishConfig.IsPublishAudio = false // Not publishing, only subscribing
+	publishConfig.IsPublishVideo = false
+	publishConfig.AudioScenario = agoraservice.AudioScenarioDefault
+
+	c.conn = agoraservice.NewRtcConnection(conCfg, publishConfig)
+	if c.conn == nil {
+		c.err = fmt.Errorf("failed to create RTC connection")
+		return c.err
+	}
+
+	first.log(botipc.LogLevelINFO, "RTC connection created")
+
+	// Signal the first connection (to wait for it before registering observers)
+	connSignal := make(chan struct{}, 1)
+
+	c.conn.RegisterObserver(&agoraservice.RtcConnectionObserver{
+		OnConnected: func(con *agoraservice.RtcConnection, info *agoraservice.RtcConnectionInfo, reason int) {
+			c.forEachBot(func(b *AgoraBot) {
+				b.log(botipc.LogLevelINFO, "✅ Bot (UID %s) connected to channel: %s", c.uid, info.ChannelId)
+			})
+			select {
+			case connSignal <- struct{}{}:
+			default:
+			}
+		},
+		OnDisconnected: func(con *agoraservice.RtcConnection, info *agoraservice.RtcConnectionInfo, reason int) {
+			c.forEachBot(func(b *AgoraBot) {
+				b.log(botipc.LogLevelWARN, "❌ Bot (UID %s) disconnected from channel: %s", c.uid, info.ChannelId)
+			})
+		},
+		OnUserJoined: c.userJoined,
+		OnUserLeft:   c.userLeft,
+	})
+
+	// Data stream messages carry the targets' captions
+	c.conn.RegisterLocalUserObserver(&agoraservice.LocalUserObserver{
+		OnStreamMessage: c.streamMessage,
+	})
+
+	// Connect to channel FIRST
+	c.conn.Connect(first.token, first.channel, c.uid)
+	first.log(botipc.LogLevelINFO, "Connecting to channel %s as UID %s...", first.channel, c.uid)
+
+	// Wait for connection to complete (like the working example)
+	select {
+	case <-connSignal:
+	case <-time.After(agoraConnectTimeout):
+		c.conn.Disconnect()
+		c.conn.Release()
+		c.err = fmt.Errorf("timeout connecting to channel %s as UID %s", first.channel, c.uid)
+		return c.err
+	}
+	first.log(botipc.LogLevelINFO, "Connection established! Now registering audio observer...")
+
+	// Get localUser AFTER connection (critical!)
+	if localUser := c.conn.GetLocalUser(); localUser != nil {
+		// Set audio parameters (from working example)
+		localUser.SetPlaybackAudioFrameBeforeMixingParameters(1, inputSampleRate)
+		first.log(botipc.LogLevelINFO, "Audio parameters set")
+	}
+
+	c.registerAudioObserver(first.observerVAD())
+	return nil
+}
+
+// registerAudioObserver registers the observer handing frames to the bots.
+// A detector that reads the SDK's VAD result has the observer run the VAD;
+// the SDK keeps one per user, so bots do not share VAD state.
+func (c *botConnection) registerAudioObserver(vadConfig *agoraservice.AudioVadConfigV2) {
+	// A new observer replaces the registered one, VAD included
+	observer := &agoraservice.AudioFrameObserver{
+		OnPlaybackAudioFrameBeforeMixing: c.audioFrame,
+	}
+	if vadConfig != nil {
+		c.conn.RegisterAudioFrameObserver(observer, 1, vadConfig)
+	} else {
+		c.conn.RegisterAudioFrameObserver(observer, 0, nil)
+	}
+
+	c.mu.Lock()
+	c.observerVAD = vadConfig != nil
+	c.mu.Unlock()
+}
+
+// attach adds b to a connected connection, subscribing to its target if
+// that user is already in the channel. It returns false if the connection
+// was released meanwhile.
+func (c *botConnection) attach(b *AgoraBot) bool {
+	target := b.target()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.bots = append(c.bots[:len(c.bots):len(c.bots)], b)
+	c.targets[target]++
+	subscribe := c.joined[target] && c.targets[target] == 1
+	vadConfig := b.observerVAD()
+	needsVAD := vadConfig != nil && !c.observerVAD
+	bots := len(c.bots)
+	c.mu.Unlock()
+
+	b.log(botipc.LogLevelINFO, "Joined the connection of fan-out group %s (UID %s, %d bots)", b.fanoutGroup, c.uid, bots)
+	if needsVAD {
+		c.registerAudioObserver(vadConfig)
+	}
+	if subscribe {
+		c.subscribe(target, []*AgoraBot{b})
+	}
+	return true
+}
+
+// detach removes b, releasing the connection with its last bot. Once it
+// returns, the audio thread no longer hands frames to b.
+func (c *botConnection) detach(b *AgoraBot) {
+	target := b.target()
+
+	fanoutMu.Lock()
+	c.mu.Lock()
+	bots := make([]*AgoraBot, 0, len(c.bots))
+	for _, other := range c.bots {
+		if other != b {
+			bots = append(bots, other)
+		}
+	}
+	c.bots = bots
+	c.targets[target]--
+	unsubscribe := c.targets[target] == 0
+	if unsubscribe {
+		delete(c.targets, target)
+	}
+	last := len(c.bots) == 0
+	if last {
+		c.closed = true
+		if c.key != "" {
+			delete(fanoutConnections, c.key)
+		}
+	}
+	c.mu.Unlock()
+	fanoutMu.Unlock()
+
+	if last {
+		c.conn.Disconnect()
+		c.conn.Release()
+		b.log(botipc.LogLevelINFO, "Disconnected from channel")
+		return
+	}
+	if unsubscribe {
+		if localUser := c.conn.GetLocalUser(); localUser != nil {
+			localUser.UnsubscribeAudio(target)
+		}
+	}
+	b.log(botipc.LogLevelINFO, "Left the connection of fan-out group %s", b.fanoutGroup)
+}
+
+// switchTarget moves b to another target UID, subscribing to it unless
+// another bot already forwards it
+func (c *botConnection) switchTarget(b *AgoraBot, uid string) error {
+	localUser := c.conn.GetLocalUser()
+	if localUser == nil {
+		return fmt.Errorf("localUser is nil")
+	}
+	previous := b.target()
+
+	c.mu.Lock()
+	c.targets[uid]++
+	subscribe := c.targets[uid] == 1
+	c.mu.Unlock()
+
+	if subscribe {
+		if ret := localUser.SubscribeAudio(uid); ret != 0 {
+			c.mu.Lock()
+			if c.targets[uid]--; c.targets[uid] == 0 {
+				delete(c.targets, uid)
+			}
+			c.mu.Unlock()
+			return fmt.Errorf("failed to subscribe to audio from UID %s, ret=%d", uid, ret)
+		}
+	}
+
+	b.setTarget(uid)
+
+	c.mu.Lock()
+	c.targets[previous]--
+	unsubscribe := c.targets[previous] == 0
+	if unsubscribe {
+		delete(c.targets, previous)
+	}
+	c.mu.Unlock()
+
+	if unsubscribe {
+		if ret := localUser.UnsubscribeAudio(previous); ret != 0 {
+			b.log(botipc.LogLevelWARN, "Failed to unsubscribe from previous target UID %s, ret=%d", previous, ret)
+		}
+	}
+	return nil
+}
+
+// userJoined subscribes to a user joining the channel if a bot forwards it
+func (c *botConnection) userJoined(con *agoraservice.RtcConnection, uid string) {
+	c.mu.Lock()
+	c.joined[uid] = true
+	bots := c.bots
+	c.mu.Unlock()
+
+	var targeting []*AgoraBot
+	for _, b := range bots {
+		if b.userJoined(uid) {
+			targeting = append(targeting, b)
+		}
+	}
+	if len(targeting) > 0 {
+		c.subscribe(uid, targeting)
+	}
+}
+
+// userLeft tells the bots a user left the channel
+func (c *botConnection) userLeft(con *agoraservice.RtcConnection, uid string, reason int) {
+	c.mu.Lock()
+	delete(c.joined, uid)
+	bots := c.bots
+	c.mu.Unlock()
+
+	for _, b := range bots {
+		b.userLeft(uid, reason)
+	}
+}
+
+// subscribe subscribes to the audio of uid, logging the outcome to the bots
+// forwarding it
+func (c *botConnection) subscribe(uid string, bots []*AgoraBot) {
+	localUser := c.conn.GetLocalUser()
+	if localUser == nil {
+		for _, b := range bots {
+			b.log(botipc.LogLevelERROR, "localUser is nil, cannot subscribe")
+		}
+		return
+	}
+
+	ret := localUser.SubscribeAudio(uid)
+	for _, b := range bots {
+		if ret == 0 {
+			b.log(botipc.LogLevelINFO, "Successfully subscribed to audio from UID %s", uid)
+		} else {
+			b.log(botipc.LogLevelERROR, "Failed to subscribe to audio from UID %s, ret=%d", uid, ret)
+		}
+	}
+}
+
+// audioFrame hands a frame from the SDK audio thread to every bot
+func (c *botConnection) audioFrame(localUser *agoraservice.LocalUser, channelId string, userId string, frame *agoraservice.AudioFrame, vadResultState agoraservice.VadState, vadResultFrame *agoraservice.AudioFrame) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, b := range c.bots {
+		b.audioFrame(userId, frame, vadResultState, vadResultFrame)
+	}
+	return true
+}
+
+// streamMessage hands a data stream message to every bot
+func (c *botConnection) streamMessage(localUser *agoraservice.LocalUser, uid string, streamId int, data []byte) {
+	c.forEachBot(func(b *AgoraBot) {
+		b.streamMessage(uid, data)
+	})
+}
+
+// forEachBot runs fn on the connection's bots
+func (c *botConnection) forEachBot(fn func(b *AgoraBot)) {
+	c.mu.RLock()
+	bots := c.bots
+	c.mu.RUnlock()
+	for _, b := range bots {
+		fn(b)
+	}
+}
@@ -7,31 +7,52 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
 	"github.com/spf13/viper"
 )
 
+// DefaultAnamSampleRate is the audio rate sent to Anam unless the engine
+// session or ANAM_AUDIO_SAMPLE_RATE asks for another
+const DefaultAnamSampleRate = 24000
+
 // AnamClient handles communication with Anam API
 type AnamClient struct {
-	conn          *websocket.Conn
-	avatarID      string
-	appID         string
-	channel       string
-	anamUID       string
-	token         string
-	baseURL       string
-	apiKey        string
-	sessionToken  string
-	sessionID     string
-	wsAddress     string
-	mu            sync.Mutex
-	isConnected   bool
-	stopChan      chan struct{}
+	conn         *websocket.Conn
+	avatarID     string
+	appID        string
+	channel      string
+	anamUID      string
+	token        string
+	baseURL      string
+	apiKey       string
+	sessionToken string
+	sessionID    string
+	wsAddress    string
+	mu           sync.Mutex
+	isConnected  bool
+	stopChan     chan struct{}
+	sampleRate   int            // Audio rate the session takes (set by StartSession)
+	queue        *anamSendQueue // Audio and voice_end waiting for writeLoop
+
+	// API request timings not yet reported to the parent
+	timingsMu   sync.Mutex
+	httpTimings []ipc.HTTPTiming
+
+	// WebSocket health reported to the parent
+	sendErrors atomic.Uint64 // Failed audio / voice_end sends
+	wsRTT      atomic.Int64  // Last ping round-trip time in nanoseconds
+
+	logFunc LogFunc // Routes log output to the session (stdout if nil)
 }
 
 // AnamSessionTokenRequest represents the session token request
@@ -42,13 +63,13 @@ type AnamSessionTokenRequest struct {
 	} `json:"personaConfig"`
 	Environment struct {
 		AgoraSettings struct {
-			AppID              string `json:"appId"`
-			Token              string `json:"token"`
-			Channel            string `json:"channel"`
-			UID                string `json:"uid"`
-			Quality            string `json:"quality"`
-			VideoEncoding      string `json:"videoEncoding"`
-			EnableStringUIDs   bool   `json:"enableStringUids"`
+			AppID               string `json:"appId"`
+			Token               string `json:"token"`
+			Channel             string `json:"channel"`
+			UID                 string `json:"uid"`
+			Quality             string `json:"quality"`
+			VideoEncoding       string `json:"videoEncoding"`
+			EnableStringUIDs    bool   `json:"enableStringUids"`
 			ActivityIdleTimeout int    `json:"activityIdleTimeout"`
 		} `json:"agoraSettings"`
 	} `json:"environment"`
@@ -61,11 +82,12 @@ type AnamSessionTokenResponse struct {
 
 // AnamSessionResponse represents the engine session response
 type AnamSessionResponse struct {
-	SessionID         string `json:"sessionId"`
-	WebsocketAddress  string `json:"websocketAddress"`
-	WebsocketURL      string `json:"websocketUrl"`
-	WebSocketAddress  string `json:"webSocketAddress"`
-	WebSocketURL      string `json:"webSocketUrl"`
+	SessionID        string `json:"sessionId"`
+	WebsocketAddress string `json:"websocketAddress"`
+	WebsocketURL     string `json:"websocketUrl"`
+	WebSocketAddress string `json:"webSocketAddress"`
+	WebSocketURL     string `json:"webSocketUrl"`
+	AudioSampleRate  int    `json:"audioSampleRate"` // Audio rate the engine expects, if it says
 }
 
 // NewAnamClient creates a new Anam client
@@ -82,7 +104,27 @@ func NewAnamClient(avatarID, appID, channel, anamUID, token, baseURL, apiKey str
 		apiKey:      apiKey,
 		isConnected: false,
 		stopChan:    make(chan struct{}),
+		sampleRate:  configuredAnamSampleRate(),
+		queue:       newAnamSendQueue(),
+	}
+}
+
+// configuredAnamSampleRate reads ANAM_AUDIO_SAMPLE_RATE (default 24kHz)
+func configuredAnamSampleRate() int {
+	if env := os.Getenv("ANAM_AUDIO_SAMPLE_RATE"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			return parsed
+		}
 	}
+	return DefaultAnamSampleRate
+}
+
+// SampleRate returns the audio rate the Anam session takes. The engine's
+// answer to StartSession wins over the configured rate.
+func (c *AnamClient) SampleRate() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sampleRate
 }
 
 // Connect creates an Anam session (calls auth/session-token then engine/session)
@@ -144,8 +186,8 @@ func (c *AnamClient) StartSession() error {
 		return fmt.Errorf("failed to marshal token request: %w", err)
 	}
 
-	fmt.Printf("[Anam] Getting session token at %s\n", tokenURL)
-	fmt.Printf("[Anam] Token request body: %s\n", string(jsonData))
+	c.log(botipc.LogLevelINFO, "Getting session token at %s", tokenURL)
+	c.log(botipc.LogLevelDEBUG, "Token request body: %s", string(jsonData))
 
 	httpReq, err := http.NewRequest("POST", tokenURL, bytes.NewBuffer(jsonData))
 	if err != nil {
@@ -163,7 +205,7 @@ func (c *AnamClient) StartSession() error {
 		},
 	}
 
-	resp, err := httpClient.Do(httpReq)
+	resp, err := c.timedDo(httpClient, httpReq, "session-token")
 	if err != nil {
 		return fmt.Errorf("failed to get session token: %w", err)
 	}
@@ -175,7 +217,7 @@ func (c *AnamClient) StartSession() error {
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		fmt.Printf("[Anam] Token request failed: %d %s - %s\n", resp.StatusCode, resp.Status, string(body))
+		c.log(botipc.LogLevelERROR, "Token request failed: %d %s - %s", resp.StatusCode, resp.Status, string(body))
 		return fmt.Errorf("token request failed: %d %s", resp.StatusCode, resp.Status)
 	}
 
@@ -185,7 +227,7 @@ func (c *AnamClient) StartSession() error {
 	}
 
 	c.sessionToken = tokenResp.SessionToken
-	fmt.Printf("[Anam] Got session token\n")
+	c.log(botipc.LogLevelINFO, "Got session token")
 
 	// Step 2: Create engine session
 	sessionURL := fmt.Sprintf("%s/engine/session", baseURL)
@@ -196,8 +238,8 @@ func (c *AnamClient) StartSession() error {
 		return fmt.Errorf("failed to marshal session request: %w", err)
 	}
 
-	fmt.Printf("[Anam] Creating engine session at %s\n", sessionURL)
-	fmt.Printf("[Anam] Engine session request body: %s\n", string(sessionData))
+	c.log(botipc.LogLevelINFO, "Creating engine session at %s", sessionURL)
+	c.log(botipc.LogLevelDEBUG, "Engine session request body: %s", string(sessionData))
 
 	httpReq2, err := http.NewRequest("POST", sessionURL, bytes.NewBuffer(sessionData))
 	if err != nil {
@@ -208,7 +250,7 @@ func (c *AnamClient) StartSession() error {
 	httpReq2.Header.Set("Accept", "application/json")
 	httpReq2.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.sessionToken))
 
-	resp2, err := httpClient.Do(httpReq2)
+	resp2, err := c.timedDo(httpClient, httpReq2, "engine-session")
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
@@ -220,11 +262,11 @@ func (c *AnamClient) StartSession() error {
 	}
 
 	if resp2.StatusCode != http.StatusOK && resp2.StatusCode != http.StatusCreated {
-		fmt.Printf("[Anam] Session creation failed: %d %s - %s\n", resp2.StatusCode, resp2.Status, string(body2))
+		c.log(botipc.LogLevelERROR, "Session creation failed: %d %s - %s", resp2.StatusCode, resp2.Status, string(body2))
 		return fmt.Errorf("session creation failed: %d %s", resp2.StatusCode, resp2.Status)
 	}
 
-	fmt.Printf("[Anam] Engine session response body: %s\n", string(body2))
+	c.log(botipc.LogLevelDEBUG, "Engine session response body: %s", string(body2))
 
 	var sessionResp AnamSessionResponse
 	if err := json.Unmarshal(body2, &sessionResp); err != nil {
@@ -232,6 +274,10 @@ func (c *AnamClient) StartSession() error {
 	}
 
 	c.sessionID = sessionResp.SessionID
+	if sessionResp.AudioSampleRate > 0 {
+		c.sampleRate = sessionResp.AudioSampleRate
+	}
+	c.log(botipc.LogLevelINFO, "Sending audio at %d Hz", c.sampleRate)
 
 	// Try different field names for WebSocket URL (Anam API inconsistency)
 	if sessionResp.WebsocketAddress != "" {
@@ -245,7 +291,7 @@ func (c *AnamClient) StartSession() error {
 	}
 
 	// Per TEN framework: use WebSocket URL as-is, no cleanup needed
-	fmt.Printf("[Anam] Session created: %s, WebSocket: %s\n", c.sessionID, c.wsAddress)
+	c.log(botipc.LogLevelINFO, "Session created: %s, WebSocket: %s", c.sessionID, c.wsAddress)
 
 	// Step 3: Connect to WebSocket
 	if c.wsAddress != "" {
@@ -258,7 +304,7 @@ func (c *AnamClient) StartSession() error {
 		headers := http.Header{}
 		headers.Set("User-Agent", "Go-http-client/1.1")
 
-		fmt.Printf("[Anam] Connecting to WebSocket: %s\n", c.wsAddress)
+		c.log(botipc.LogLevelINFO, "Connecting to WebSocket: %s", c.wsAddress)
 		conn, resp, err := dialer.Dial(c.wsAddress, headers)
 
 		// If we get a redirect, follow it
@@ -275,17 +321,17 @@ func (c *AnamClient) StartSession() error {
 						location = "wss://" + hostParts[0] + location
 					}
 				}
-				fmt.Printf("[Anam] Following redirect to: %s\n", location)
+				c.log(botipc.LogLevelINFO, "Following redirect to: %s", location)
 				conn, resp, err = dialer.Dial(location, headers)
 			}
 		}
 
 		if err != nil {
 			if resp != nil {
-				fmt.Printf("[Anam] WebSocket handshake failed: %d %s\n", resp.StatusCode, resp.Status)
+				c.log(botipc.LogLevelERROR, "WebSocket handshake failed: %d %s", resp.StatusCode, resp.Status)
 				if resp.Body != nil {
 					bodyBytes, _ := ioutil.ReadAll(resp.Body)
-					fmt.Printf("[Anam] Response body: %s\n", string(bodyBytes))
+					c.log(botipc.LogLevelERROR, "Response body: %s", string(bodyBytes))
 				}
 			}
 			return fmt.Errorf("failed to connect to WebSocket: %w", err)
@@ -294,7 +340,15 @@ func (c *AnamClient) StartSession() error {
 		c.conn = conn
 		c.isConnected = true
 
-		fmt.Printf("[Anam] Connected to Anam WebSocket\n")
+		// Pings carry their send time; pongs are handled inside receiveLoop's reads
+		conn.SetPongHandler(func(appData string) error {
+			if sent, err := strconv.ParseInt(appData, 10, 64); err == nil {
+				c.wsRTT.Store(time.Now().UnixNano() - sent)
+			}
+			return nil
+		})
+
+		c.log(botipc.LogLevelINFO, "Connected to Anam WebSocket")
 
 		// Step 4: Send "init" command with full configuration (per anam_api_flow.md)
 		// WebSocket uses snake_case
@@ -317,26 +371,29 @@ func (c *AnamClient) StartSession() error {
 		}
 
 		initMsgJSON, _ := json.Marshal(initMsg)
-		fmt.Printf("[Anam] 📤 Sending init - Avatar will join as UID %s in channel %s\n", c.anamUID, c.channel)
-	fmt.Printf("[Anam] Init command: %s\n", string(initMsgJSON))
+		c.log(botipc.LogLevelINFO, "📤 Sending init - Avatar will join as UID %s in channel %s", c.anamUID, c.channel)
+		c.log(botipc.LogLevelDEBUG, "Init command: %s", string(initMsgJSON))
 
 		if err := conn.WriteJSON(initMsg); err != nil {
 			return fmt.Errorf("failed to send init command: %w", err)
 		}
 
-		fmt.Printf("[Anam] Init command sent successfully\n")
+		c.log(botipc.LogLevelINFO, "Init command sent successfully")
 
 		// CRITICAL: Wait 500ms after init before starting heartbeat/audio
 		// Per anam_ws_flow.md: "give Anam time to set up before sending audio"
-		fmt.Printf("[Anam] Waiting 500ms for Anam to initialize...\n")
+		c.log(botipc.LogLevelINFO, "Waiting 500ms for Anam to initialize...")
 		time.Sleep(500 * time.Millisecond)
-		fmt.Printf("[Anam] Init delay complete, starting heartbeat\n")
+		c.log(botipc.LogLevelINFO, "Init delay complete, starting heartbeat")
 
 		// Start heartbeat to keep connection alive (required by Anam)
 		go c.sendHeartbeat()
 
 		// Start listening for messages from Anam
 		go c.receiveLoop()
+
+		// Send queued audio off the SDK audio thread
+		go c.writeLoop()
 	} else {
 		return fmt.Errorf("no WebSocket address provided by Anam")
 	}
@@ -344,6 +401,32 @@ func (c *AnamClient) StartSession() error {
 	return nil
 }
 
+// timedDo performs an API request and records how long it took
+func (c *AnamClient) timedDo(httpClient *http.Client, req *http.Request, endpoint string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+
+	timing := ipc.HTTPTiming{Endpoint: endpoint, Duration: time.Since(start)}
+	if err == nil {
+		timing.StatusCode = resp.StatusCode
+	}
+	c.timingsMu.Lock()
+	c.httpTimings = append(c.httpTimings, timing)
+	c.timingsMu.Unlock()
+
+	return resp, err
+}
+
+// DrainHTTPTimings returns the API request timings recorded since the last call
+func (c *AnamClient) DrainHTTPTimings() []ipc.HTTPTiming {
+	c.timingsMu.Lock()
+	defer c.timingsMu.Unlock()
+
+	timings := c.httpTimings
+	c.httpTimings = nil
+	return timings
+}
+
 // SendAudio sends base64-encoded PCM audio to Anam with default 16kHz sample rate
 func (c *AnamClient) SendAudio(audioB64 string) error {
 	return c.SendAudioWithSampleRate(audioB64, 16000)
@@ -367,7 +450,7 @@ func (c *AnamClient) SendAudioWithSampleRate(audioB64 string, sampleRate int) er
 		"event_id":    uuid.Must(uuid.NewV4()).String(),
 	}
 
-	return c.conn.WriteJSON(msg)
+	return c.countSendError(c.conn.WriteJSON(msg))
 }
 
 // SendVoiceEnd sends voice_end signal to Anam (called after silence detected)
@@ -384,24 +467,43 @@ func (c *AnamClient) SendVoiceEnd() error {
 		"event_id": uuid.Must(uuid.NewV4()).String(),
 	}
 
-	fmt.Printf("[Anam] Sending voice_end signal\n")
-	return c.conn.WriteJSON(msg)
+	c.log(botipc.LogLevelINFO, "Sending voice_end signal")
+	return c.countSendError(c.conn.WriteJSON(msg))
+}
+
+// countSendError counts a failed WebSocket send
+func (c *AnamClient) countSendError(err error) error {
+	if err != nil {
+		c.sendErrors.Add(1)
+	}
+	return err
+}
+
+// SendErrors returns the number of failed audio and voice_end sends
+func (c *AnamClient) SendErrors() uint64 {
+	return c.sendErrors.Load()
+}
+
+// RoundTrip returns the last measured WebSocket ping round-trip time, or 0
+// before the first pong
+func (c *AnamClient) RoundTrip() time.Duration {
+	return time.Duration(c.wsRTT.Load())
 }
 
 // receiveLoop continuously receives messages from Anam
 func (c *AnamClient) receiveLoop() {
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Printf("[Anam] Recovered from panic in receiveLoop: %v\n", r)
+			c.log(botipc.LogLevelERROR, "Recovered from panic in receiveLoop: %v", r)
 		}
 	}()
 
-	fmt.Printf("[Anam] Starting receive loop\n")
+	c.log(botipc.LogLevelINFO, "Starting receive loop")
 
 	for {
 		select {
 		case <-c.stopChan:
-			fmt.Printf("[Anam] Stopping receive loop\n")
+			c.log(botipc.LogLevelINFO, "Stopping receive loop")
 			return
 		default:
 			if c.conn == nil {
@@ -411,21 +513,21 @@ func (c *AnamClient) receiveLoop() {
 			var msg map[string]interface{}
 			err := c.conn.ReadJSON(&msg)
 			if err != nil {
-				fmt.Printf("[Anam] Error reading message: %v\n", err)
+				c.log(botipc.LogLevelERROR, "Error reading message: %v", err)
 				return
 			}
 
 			// Log ALL messages from Anam for debugging
 			msgType, ok := msg["type"].(string)
 			if ok {
-				fmt.Printf("[Anam] Received message type: %s, full: %+v\n", msgType, msg)
+				c.log(botipc.LogLevelDEBUG, "Received message type: %s, full: %+v", msgType, msg)
 			} else {
-				fmt.Printf("[Anam] Received message (no type field): %+v\n", msg)
+				c.log(botipc.LogLevelDEBUG, "Received message (no type field): %+v", msg)
 			}
 
 			// Check for error messages
 			if errMsg, ok := msg["error"].(string); ok && errMsg != "" {
-				fmt.Printf("[Anam] ERROR from server: %s\n", errMsg)
+				c.log(botipc.LogLevelERROR, "ERROR from server: %s", errMsg)
 			}
 		}
 	}
@@ -435,19 +537,19 @@ func (c *AnamClient) receiveLoop() {
 func (c *AnamClient) sendHeartbeat() {
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Printf("[Anam] Recovered from panic in sendHeartbeat: %v\n", r)
+			c.log(botipc.LogLevelERROR, "Recovered from panic in sendHeartbeat: %v", r)
 		}
 	}()
 
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
-	fmt.Printf("[Anam] Starting heartbeat sender (every 5 seconds)\n")
+	c.log(botipc.LogLevelINFO, "Starting heartbeat sender (every 5 seconds)")
 
 	for {
 		select {
 		case <-c.stopChan:
-			fmt.Printf("[Anam] Stopping heartbeat sender\n")
+			c.log(botipc.LogLevelINFO, "Stopping heartbeat sender")
 			return
 		case <-ticker.C:
 			c.mu.Lock()
@@ -463,12 +565,17 @@ func (c *AnamClient) sendHeartbeat() {
 			}
 
 			err := c.conn.WriteJSON(heartbeat)
+			if err == nil {
+				// Measure the round-trip time alongside the heartbeat
+				ping := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+				err = c.conn.WriteControl(websocket.PingMessage, ping, time.Now().Add(time.Second))
+			}
 			c.mu.Unlock()
 
 			if err != nil {
-				fmt.Printf("[Anam] Error sending heartbeat: %v\n", err)
+				c.log(botipc.LogLevelERROR, "Error sending heartbeat: %v", err)
 			} else {
-				fmt.Printf("[Anam] Sent heartbeat\n")
+				c.log(botipc.LogLevelDEBUG, "Sent heartbeat")
 			}
 		}
 	}
@@ -485,6 +592,7 @@ func (c *AnamClient) Close() error {
 
 	if c.conn != nil {
 		close(c.stopChan)
+		c.queue.close()
 
 		// Send close message
 		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
@@ -496,11 +604,20 @@ func (c *AnamClient) Close() error {
 
 	c.isConnected = false
 
-	fmt.Printf("[Anam] Connection closed\n")
+	c.log(botipc.LogLevelINFO, "Connection closed")
 
 	return nil
 }
 
+// log writes a log line through logFunc, falling back to stdout
+func (c *AnamClient) log(level botipc.LogLevel, format string, args ...interface{}) {
+	if c.logFunc != nil {
+		c.logFunc(level, format, args...)
+		return
+	}
+	fmt.Printf("[Anam] "+format+"\n", args...)
+}
+
 // IsConnected returns whether the client is connected
 func (c *AnamClient) IsConnected() bool {
 	c.mu.Lock()
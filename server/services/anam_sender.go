@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/base64"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/audio"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// What QueueAudio does when the send queue is full (ANAM_SEND_POLICY)
+const (
+	SendPolicyDropOldest = "drop_oldest" // Drop the oldest queued audio (default)
+	SendPolicyDropNewest = "drop_newest" // Drop the audio being queued
+	SendPolicyBlock      = "block"       // Wait up to ANAM_SEND_BLOCK_TIMEOUT_MS, then drop the audio being queued
+)
+
+// Send queue defaults, overridable through the child's environment
+const (
+	DefaultSendQueueSize    = 50 // Audio messages, 2s of 40ms chunks
+	DefaultSendPolicy       = SendPolicyDropOldest
+	DefaultSendBlockTimeout = 20 * time.Millisecond // Blocks the SDK audio thread, keep it short
+)
+
+// anamMessage is a queued voice or voice_end command
+type anamMessage struct {
+	voiceEnd   bool
+	pcm        []byte // Encoded by the writer
+	sampleRate int
+	frames     int // 10ms frames in pcm
+}
+
+// anamSendQueue buffers commands between the SDK audio thread and the
+// WebSocket writer. Its size bounds the audio messages; voice_end is always
+// queued, so a segment is closed even when its audio was dropped.
+type anamSendQueue struct {
+	policy  string
+	size    int
+	timeout time.Duration // Block policy
+
+	mu     sync.Mutex
+	items  []anamMessage
+	audio  int // Audio messages in items
+	closed bool
+	ready  chan struct{} // Tells the writer items is not empty
+	space  chan struct{} // Tells a blocked producer the writer took an item
+
+	dropped atomic.Uint64 // 10ms frames dropped
+	peak    atomic.Int64  // Deepest queue since the last SendQueueStats
+}
+
+// newAnamSendQueue creates the send queue configured by ANAM_SEND_QUEUE_SIZE,
+// ANAM_SEND_POLICY and ANAM_SEND_BLOCK_TIMEOUT_MS
+func newAnamSendQueue() *anamSendQueue {
+	q := &anamSendQueue{
+		policy:  DefaultSendPolicy,
+		size:    DefaultSendQueueSize,
+		timeout: DefaultSendBlockTimeout,
+		ready:   make(chan struct{}, 1),
+		space:   make(chan struct{}, 1),
+	}
+	switch policy := os.Getenv("ANAM_SEND_POLICY"); policy {
+	case SendPolicyDropOldest, SendPolicyDropNewest, SendPolicyBlock:
+		q.policy = policy
+	}
+	if parsed, err := strconv.Atoi(os.Getenv("ANAM_SEND_QUEUE_SIZE")); err == nil && parsed > 0 {
+		q.size = parsed
+	}
+	if parsed, err := strconv.Atoi(os.Getenv("ANAM_SEND_BLOCK_TIMEOUT_MS")); err == nil && parsed > 0 {
+		q.timeout = time.Duration(parsed) * time.Millisecond
+	}
+	return q
+}
+
+// push queues a message and reports whether it was queued. When the queue
+// is full, audio is dropped according to the policy.
+func (q *anamSendQueue) push(msg anamMessage) bool {
+	var deadline time.Time
+
+	q.mu.Lock()
+	for !msg.voiceEnd && q.audio >= q.size && !q.closed {
+		switch q.policy {
+		case SendPolicyDropNewest:
+			q.mu.Unlock()
+			q.dropped.Add(uint64(msg.frames))
+			return false
+
+		case SendPolicyBlock:
+			if deadline.IsZero() {
+				deadline = time.Now().Add(q.timeout)
+			}
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				q.mu.Unlock()
+				q.dropped.Add(uint64(msg.frames))
+				return false
+			}
+			q.mu.Unlock()
+			timer := time.NewTimer(remaining)
+			select {
+			case <-q.space:
+			case <-timer.C:
+			}
+			timer.Stop()
+			q.mu.Lock()
+
+		default:
+			q.dropOldestAudio()
+		}
+	}
+	if q.closed {
+		q.mu.Unlock()
+		return false
+	}
+
+	q.items = append(q.items, msg)
+	if !msg.voiceEnd {
+		q.audio++
+	}
+	depth := int64(len(q.items))
+	q.mu.Unlock()
+
+	for {
+		peak := q.peak.Load()
+		if depth <= peak || q.peak.CompareAndSwap(peak, depth) {
+			break
+		}
+	}
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// dropOldestAudio removes the oldest queued audio message. Must be called
+// with q.mu held.
+func (q *anamSendQueue) dropOldestAudio() {
+	for i, item := range q.items {
+		if !item.voiceEnd {
+			q.dropped.Add(uint64(item.frames))
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.audio--
+			return
+		}
+	}
+}
+
+// pop waits for the next message until stop is closed
+func (q *anamSendQueue) pop(stop <-chan struct{}) (anamMessage, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			msg := q.items[0]
+			q.items[0] = anamMessage{}
+			q.items = q.items[1:]
+			if !msg.voiceEnd {
+				q.audio--
+			}
+			q.mu.Unlock()
+
+			select {
+			case q.space <- struct{}{}:
+			default:
+			}
+			return msg, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-stop:
+			return anamMessage{}, false
+		}
+	}
+}
+
+// close drops the queued messages and refuses new ones
+func (q *anamSendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.items = nil
+	q.audio = 0
+}
+
+// depth returns the number of queued messages
+func (q *anamSendQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// QueueAudio queues PCM for the writer and returns at once, or after the
+// block policy's timeout. It reports whether the audio was queued rather than
+// dropped.
+func (c *AnamClient) QueueAudio(pcm []byte, sampleRate int) bool {
+	frames := 0
+	if sampleRate > 0 {
+		frames = (len(pcm)/2*int(time.Second/audio.FrameDuration) + sampleRate/2) / sampleRate
+	}
+	return c.queue.push(anamMessage{pcm: pcm, sampleRate: sampleRate, frames: frames})
+}
+
+// QueueVoiceEnd queues voice_end after the audio queued so far
+func (c *AnamClient) QueueVoiceEnd() {
+	c.queue.push(anamMessage{voiceEnd: true})
+}
+
+// SendQueueStats returns the queued messages, the deepest the queue got since
+// the previous call and the 10ms frames dropped since the client started
+func (c *AnamClient) SendQueueStats() (depth, peak int, droppedFrames uint64) {
+	depth = c.queue.depth()
+	peak = int(c.queue.peak.Swap(int64(depth)))
+	return depth, max(peak, depth), c.queue.dropped.Load()
+}
+
+// writeLoop sends queued commands over the WebSocket until the client closes
+func (c *AnamClient) writeLoop() {
+	defer func() {
+		if r := recover(); r != nil {
+			c.log(botipc.LogLevelERROR, "Recovered from panic in writeLoop: %v", r)
+		}
+	}()
+
+	c.log(botipc.LogLevelINFO, "Starting audio writer (queue of %d, %s when full)", c.queue.size, c.queue.policy)
+
+	for {
+		msg, ok := c.queue.pop(c.stopChan)
+		if !ok {
+			c.log(botipc.LogLevelINFO, "Stopping audio writer")
+			return
+		}
+
+		if msg.voiceEnd {
+			if err := c.SendVoiceEnd(); err != nil {
+				c.log(botipc.LogLevelERROR, "❌ Error sending voice_end: %v", err)
+			}
+			continue
+		}
+		if err := c.SendAudioWithSampleRate(base64.StdEncoding.EncodeToString(msg.pcm), msg.sampleRate); err != nil {
+			c.log(botipc.LogLevelERROR, "❌ Error forwarding audio: %v", err)
+		}
+	}
+}
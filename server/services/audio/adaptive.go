@@ -0,0 +1,333 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Adaptive gate tuning
+const (
+	adaptiveFloorBlock    = 10                     // Frames per block whose quietest frame feeds the noise floor
+	adaptiveFloorWindow   = 50                     // Blocks the noise floor is the minimum of (5s)
+	adaptiveMinMargin     = 6.0                    // dB the open threshold stays above the noise floor
+	adaptiveSpeechShare   = 0.3                    // Open threshold's share of the way from the noise floor to the speech level
+	adaptiveHysteresis    = 3.0                    // dB the close threshold sits below the open threshold
+	adaptiveSpeechAverage = 2 * time.Second        // Time constant of the speech level
+	adaptiveMinGap        = 30 * time.Millisecond  // Shorter dips are within a word
+	adaptiveGapRise       = 0.3                    // Weight of a gap longer than the estimate, so it tracks the long gaps
+	adaptiveGapFall       = 0.05                   // Weight of a gap shorter than the estimate
+	adaptiveHangoverPad   = 100 * time.Millisecond // Added to 1.5 times the gap estimate
+	adaptiveRecentEnds    = 10                     // voice_end decisions kept for diagnostics
+)
+
+// Bounds and starting values of the adaptive hangover and pre-roll
+const (
+	DefaultAdaptiveHangover = 500 * time.Millisecond
+	MinAdaptiveHangover     = 200 * time.Millisecond
+	MaxAdaptiveHangover     = 1500 * time.Millisecond
+	DefaultAdaptivePreroll  = 100 * time.Millisecond
+	MinAdaptivePreroll      = 50 * time.Millisecond
+	MaxAdaptivePreroll      = 300 * time.Millisecond
+)
+
+// Why a segment of the adaptive gate ended
+const (
+	GateEndSilence = "silence" // The level stayed under the close threshold for the hangover
+	GateEndClosed  = "closed"  // Closed from outside: paused or the target switched
+)
+
+// GateEnd records why the adaptive gate ended a segment
+type GateEnd struct {
+	At         time.Duration // Stream time of the end
+	Reason     string        // GateEndSilence or GateEndClosed
+	Segment    time.Duration // Length of the segment
+	Silence    time.Duration // Audio under the close threshold before the end
+	Hangover   time.Duration // Hangover in force
+	Level      float64       // Level of the last frame
+	Threshold  float64       // Close threshold, dBFS
+	NoiseFloor float64       // dBFS
+}
+
+func (e GateEnd) String() string {
+	if e.Reason == GateEndClosed {
+		return fmt.Sprintf("closed from outside after %s", e.Segment)
+	}
+	return fmt.Sprintf("%s under %.1f dBFS >= hangover %s (last level %.1f dBFS, noise floor %.1f dBFS)",
+		e.Silence, e.Threshold, e.Hangover, e.Level, e.NoiseFloor)
+}
+
+// GateDiagnostics is a snapshot of the adaptive gate's estimates
+type GateDiagnostics struct {
+	NoiseFloor  float64       // dBFS
+	SpeechLevel float64       // dBFS, -120 until speech was heard
+	Threshold   float64       // dBFS a frame must exceed to open a segment
+	Hangover    time.Duration // In force
+	Preroll     time.Duration // In force
+	Gap         time.Duration // Estimated inter-word gap the hangover follows
+	Ends        []GateEnd     // Latest segment ends, oldest first
+}
+
+// AdaptiveGate is a level gate whose threshold follows the noise floor and
+// the speech level, and whose hangover and pre-roll follow the gaps between
+// words and the length of speech onsets. Detect and Reset must be called
+// from one goroutine; Diagnostics may be called concurrently.
+type AdaptiveGate struct {
+	mu sync.Mutex // Guards the estimates and ends for Diagnostics
+
+	// Estimates, in dBFS and frames
+	floors      []float64 // Quietest frame of the last blocks
+	blockMin    float64   // Quietest frame of the current block
+	blockFrames int
+	floor       float64
+	speech      float64
+	heardSpeech bool
+	gapFrames   float64 // Inter-word gap estimate
+	onsetFrames float64 // Onset length estimate
+	hangover    int
+	preroll     int
+	ends        []GateEnd
+
+	// Detect state
+	frames        int64       // Frames seen, the stream time
+	ring          []gateFrame // Last frames, for the pre-roll and onsets
+	next          int
+	speaking      bool
+	segmentFrames int
+	silenceFrames int
+	lastEnd       int64 // Stream frame of the last speech frame before the previous end, -1 before any
+	sentUntil     int64 // Stream frame of the last frame forwarded, so the pre-roll never repeats audio
+}
+
+type gateFrame struct {
+	pcm   []byte
+	level float64
+}
+
+// NewAdaptiveGate creates an adaptive gate with the starting estimates
+func NewAdaptiveGate() *AdaptiveGate {
+	g := &AdaptiveGate{
+		ring:     make([]gateFrame, MaxAdaptivePreroll/FrameDuration),
+		blockMin: math.Inf(1),
+		floor:    silenceLevel,
+		speech:   silenceLevel,
+		hangover: int(DefaultAdaptiveHangover / FrameDuration),
+		preroll:  int(DefaultAdaptivePreroll / FrameDuration),
+		lastEnd:  -1,
+	}
+	g.gapFrames = float64(g.hangover) / 1.5
+	g.onsetFrames = float64(g.preroll)
+	return g
+}
+
+// Detect gates on the level Convert (or Loudness) measured
+func (g *AdaptiveGate) Detect(p Packet) (VoiceEvent, [][]byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	level := Level(p.Level)
+	g.frames++
+	g.trackFloor(level)
+	defer g.remember(p.PCM, level)
+
+	open := g.threshold()
+	if !g.speaking {
+		if level <= open {
+			return VoiceSilence, nil
+		}
+		return VoiceStart, g.onset(p.PCM, level, open)
+	}
+
+	g.segmentFrames++
+	if level > open-adaptiveHysteresis {
+		g.trackSpeech(level)
+		if g.silenceFrames > 0 {
+			g.trackGap(g.silenceFrames)
+			g.silenceFrames = 0
+		}
+		return VoiceContinue, [][]byte{p.PCM}
+	}
+
+	g.silenceFrames++
+	if g.silenceFrames < g.hangover {
+		return VoiceContinue, [][]byte{p.PCM}
+	}
+	g.end(GateEndSilence, level)
+	return VoiceEnd, nil
+}
+
+// onset starts a segment with pcm and returns the pre-roll and pcm
+func (g *AdaptiveGate) onset(pcm []byte, level, open float64) [][]byte {
+	// Learn how long soft beginnings last from the frames before the onset
+	// that were half way from the floor to the threshold
+	rising := 0
+	for i := 1; i <= len(g.ring); i++ {
+		frame := g.ring[(g.next-i+len(g.ring))%len(g.ring)]
+		if frame.pcm == nil || frame.level <= (g.floor+open)/2 {
+			break
+		}
+		rising++
+	}
+	g.onsetFrames += (float64(rising) - g.onsetFrames) * 0.2
+	g.preroll = clampFrames(time.Duration(g.onsetFrames)*FrameDuration+50*time.Millisecond, MinAdaptivePreroll, MaxAdaptivePreroll)
+
+	// A segment starting soon after the previous one ended was cut by a
+	// hangover too short for this speaker: learn from that gap too
+	if g.lastEnd >= 0 {
+		if gap := int(g.frames - 1 - g.lastEnd); time.Duration(gap)*FrameDuration <= MaxAdaptiveHangover {
+			g.trackGap(gap)
+		}
+	}
+
+	g.speaking = true
+	g.segmentFrames = 1
+	g.silenceFrames = 0
+	g.trackSpeech(level)
+
+	n := int(min(int64(g.preroll), g.frames-1-g.sentUntil))
+	audio := make([][]byte, 0, n+1)
+	for i := n; i >= 1; i-- {
+		if buf := g.ring[(g.next-i+len(g.ring))%len(g.ring)].pcm; buf != nil {
+			audio = append(audio, buf)
+		}
+	}
+	return append(audio, pcm)
+}
+
+// threshold returns the level a frame must exceed to open a segment
+func (g *AdaptiveGate) threshold() float64 {
+	threshold := g.floor + adaptiveMinMargin
+	if g.heardSpeech {
+		threshold = math.Max(threshold, g.floor+(g.speech-g.floor)*adaptiveSpeechShare)
+	}
+	// Never open below the energy gate's default, whatever the floor
+	return math.Max(threshold, Level(DefaultEnergyThreshold))
+}
+
+// trackFloor feeds a frame's level to the noise floor: the quietest frame of
+// the last few seconds, so it follows rising noise within the window
+func (g *AdaptiveGate) trackFloor(level float64) {
+	g.blockMin = math.Min(g.blockMin, level)
+	g.blockFrames++
+	if g.blockFrames < adaptiveFloorBlock {
+		if len(g.floors) == 0 {
+			g.floor = g.blockMin
+		}
+		return
+	}
+	g.floors = append(g.floors, g.blockMin)
+	if len(g.floors) > adaptiveFloorWindow {
+		g.floors = g.floors[1:]
+	}
+	g.blockMin = math.Inf(1)
+	g.blockFrames = 0
+
+	g.floor = g.floors[0]
+	for _, floor := range g.floors[1:] {
+		g.floor = math.Min(g.floor, floor)
+	}
+}
+
+// trackSpeech feeds the level of a speech frame to the speech level
+func (g *AdaptiveGate) trackSpeech(level float64) {
+	if !g.heardSpeech {
+		g.heardSpeech = true
+		g.speech = level
+		return
+	}
+	g.speech += (level - g.speech) * float64(FrameDuration) / float64(adaptiveSpeechAverage)
+}
+
+// trackGap feeds a pause between words to the gap estimate and adapts the
+// hangover to it
+func (g *AdaptiveGate) trackGap(frames int) {
+	if time.Duration(frames)*FrameDuration < adaptiveMinGap {
+		return
+	}
+	weight := adaptiveGapFall
+	if float64(frames) > g.gapFrames {
+		weight = adaptiveGapRise
+	}
+	g.gapFrames += (float64(frames) - g.gapFrames) * weight
+	g.hangover = clampFrames(time.Duration(g.gapFrames*1.5)*FrameDuration+adaptiveHangoverPad, MinAdaptiveHangover, MaxAdaptiveHangover)
+}
+
+// end closes the segment and records why
+func (g *AdaptiveGate) end(reason string, level float64) {
+	g.ends = append(g.ends, GateEnd{
+		At:         time.Duration(g.frames) * FrameDuration,
+		Reason:     reason,
+		Segment:    time.Duration(g.segmentFrames) * FrameDuration,
+		Silence:    time.Duration(g.silenceFrames) * FrameDuration,
+		Hangover:   time.Duration(g.hangover) * FrameDuration,
+		Level:      level,
+		Threshold:  g.threshold() - adaptiveHysteresis,
+		NoiseFloor: g.floor,
+	})
+	if len(g.ends) > adaptiveRecentEnds {
+		g.ends = g.ends[1:]
+	}
+	if reason == GateEndSilence {
+		g.lastEnd = g.frames - int64(g.silenceFrames)
+		g.sentUntil = g.frames - 1
+	} else {
+		g.lastEnd = -1
+		g.sentUntil = g.frames
+	}
+	g.speaking = false
+	g.segmentFrames = 0
+	g.silenceFrames = 0
+}
+
+// remember stores a frame in the ring buffer
+func (g *AdaptiveGate) remember(pcm []byte, level float64) {
+	g.ring[g.next] = gateFrame{pcm: pcm, level: level}
+	g.next = (g.next + 1) % len(g.ring)
+}
+
+// Reset records the end of a segment closed from outside
+func (g *AdaptiveGate) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.speaking {
+		g.end(GateEndClosed, silenceLevel)
+	}
+}
+
+// Diagnostics returns the gate's current estimates and latest segment ends
+func (g *AdaptiveGate) Diagnostics() GateDiagnostics {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return GateDiagnostics{
+		NoiseFloor:  g.floor,
+		SpeechLevel: g.speech,
+		Threshold:   g.threshold(),
+		Hangover:    time.Duration(g.hangover) * FrameDuration,
+		Preroll:     time.Duration(g.preroll) * FrameDuration,
+		Gap:         time.Duration(g.gapFrames * float64(FrameDuration)),
+		Ends:        append([]GateEnd(nil), g.ends...),
+	}
+}
+
+// LastEnd returns why the latest segment ended
+func (g *AdaptiveGate) LastEnd() (GateEnd, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.ends) == 0 {
+		return GateEnd{}, false
+	}
+	return g.ends[len(g.ends)-1], true
+}
+
+// Settings describes the current estimates for logs
+func (g *AdaptiveGate) Settings() string {
+	d := g.Diagnostics()
+	return fmt.Sprintf("adaptive threshold=%.1f dBFS (floor %.1f, speech %.1f), hangover=%s, pre-roll=%s",
+		d.Threshold, d.NoiseFloor, d.SpeechLevel, d.Hangover, d.Preroll)
+}
+
+// clampFrames converts d, clamped to lo - hi, to frames
+func clampFrames(d, lo, hi time.Duration) int {
+	return int(max(min(d, hi), lo) / FrameDuration)
+}
@@ -0,0 +1,162 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// noiseAmplitude returns the peak of uniform noise whose RMS level is dbfs
+func noiseAmplitude(dbfs float64) int16 {
+	return int16(fullScale * math.Pow(10, dbfs/20) * math.Sqrt(3))
+}
+
+// adaptiveHarness returns a harness running an adaptive gate
+func adaptiveHarness() (*Harness, *AdaptiveGate) {
+	gate := NewAdaptiveGate()
+	return NewHarness(16000, Convert(), Detect(gate)), gate
+}
+
+func TestAdaptiveGateTracksNoiseFloor(t *testing.T) {
+	h, gate := adaptiveHarness()
+	h.Noise(2*time.Second, noiseAmplitude(-50))
+
+	if segments := h.Segments(); len(segments) != 0 {
+		t.Fatalf("steady noise opened %d segments", len(segments))
+	}
+	d := gate.Diagnostics()
+	if d.NoiseFloor < -53 || d.NoiseFloor > -49 {
+		t.Errorf("noise floor %.1f dBFS, want about -50 dBFS", d.NoiseFloor)
+	}
+	if d.Threshold < d.NoiseFloor+adaptiveMinMargin {
+		t.Errorf("threshold %.1f dBFS less than %g dB over the noise floor %.1f dBFS", d.Threshold, adaptiveMinMargin, d.NoiseFloor)
+	}
+}
+
+func TestAdaptiveGateThresholdFloor(t *testing.T) {
+	h, gate := adaptiveHarness()
+	h.Silence(time.Second)
+
+	if got, want := gate.Diagnostics().Threshold, Level(DefaultEnergyThreshold); got != want {
+		t.Errorf("threshold over digital silence is %.1f dBFS, want the energy gate's %.1f dBFS", got, want)
+	}
+}
+
+func TestAdaptiveGateSpeechOverNoise(t *testing.T) {
+	h, gate := adaptiveHarness()
+	h.Noise(time.Second, noiseAmplitude(-50))
+	h.Tone(500*time.Millisecond, 200, amplitudeFor(-20))
+	h.Noise(2*time.Second, noiseAmplitude(-50))
+
+	segments := h.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("got %d segments, want 1", len(segments))
+	}
+	if segments[0].Start != time.Second+FrameDuration {
+		t.Errorf("segment starts at %v, want at the tone", segments[0].Start)
+	}
+	if segments[0].End == 0 {
+		t.Fatal("segment did not end in the noise after the tone")
+	}
+
+	end, ok := gate.LastEnd()
+	if !ok || end.Reason != GateEndSilence {
+		t.Fatalf("last end %+v, want one for silence", end)
+	}
+	if end.Silence < end.Hangover-FrameDuration {
+		t.Errorf("ended after %v under the threshold, before the %v hangover", end.Silence, end.Hangover)
+	}
+	if d := gate.Diagnostics(); math.Abs(d.SpeechLevel-(-20)) > 1 {
+		t.Errorf("speech level %.1f dBFS, want about -20 dBFS", d.SpeechLevel)
+	}
+}
+
+// speakWithGaps pushes words of 300ms separated by gaps of silence, after
+// a second of noise the gate learns its floor from
+func speakWithGaps(h *Harness, words int, gap time.Duration) {
+	h.Noise(time.Second, noiseAmplitude(-60))
+	for i := 0; i < words; i++ {
+		h.Tone(300*time.Millisecond, 200, amplitudeFor(-20))
+		h.Silence(gap)
+	}
+}
+
+func TestAdaptiveGateHangoverFollowsGaps(t *testing.T) {
+	long, longGate := adaptiveHarness()
+	speakWithGaps(long, 10, 400*time.Millisecond)
+	if got := longGate.Diagnostics().Hangover; got <= DefaultAdaptiveHangover {
+		t.Errorf("hangover %v after 400ms gaps, want it raised above %v", got, DefaultAdaptiveHangover)
+	}
+	if segments := long.Segments(); len(segments) != 1 {
+		t.Errorf("400ms gaps split the speech into %d segments, want 1", len(segments))
+	}
+
+	short, shortGate := adaptiveHarness()
+	speakWithGaps(short, 30, 100*time.Millisecond)
+	if got := shortGate.Diagnostics().Hangover; got >= DefaultAdaptiveHangover {
+		t.Errorf("hangover %v after 100ms gaps, want it lowered below %v", got, DefaultAdaptiveHangover)
+	}
+}
+
+func TestAdaptiveGateHangoverBounds(t *testing.T) {
+	h, gate := adaptiveHarness()
+	speakWithGaps(h, 20, 1200*time.Millisecond)
+	if got := gate.Diagnostics().Hangover; got != MaxAdaptiveHangover {
+		t.Errorf("hangover %v after 1.2s gaps, want the %v maximum", got, MaxAdaptiveHangover)
+	}
+
+	h, gate = adaptiveHarness()
+	speakWithGaps(h, 200, 40*time.Millisecond)
+	if got := gate.Diagnostics().Hangover; got < MinAdaptiveHangover {
+		t.Errorf("hangover %v after 40ms gaps, under the %v minimum", got, MinAdaptiveHangover)
+	}
+}
+
+func TestAdaptiveGateNeverRepeatsAudio(t *testing.T) {
+	h, _ := adaptiveHarness()
+	speakWithGaps(h, 20, 700*time.Millisecond)
+
+	var audio time.Duration
+	for _, segment := range h.Segments() {
+		audio += segment.Audio
+	}
+	if audio > h.Elapsed() {
+		t.Errorf("forwarded %v of audio for %v pushed, the pre-roll repeated frames", audio, h.Elapsed())
+	}
+}
+
+func TestAdaptiveGateClosedFromOutside(t *testing.T) {
+	h, gate := adaptiveHarness()
+	h.Noise(time.Second, noiseAmplitude(-60))
+	h.Tone(200*time.Millisecond, 200, amplitudeFor(-20))
+	h.EndSegment()
+
+	end, ok := gate.LastEnd()
+	if !ok || end.Reason != GateEndClosed {
+		t.Fatalf("last end %+v, want one closed from outside", end)
+	}
+	if end.Segment != 200*time.Millisecond {
+		t.Errorf("closed segment lasted %v, want 200ms", end.Segment)
+	}
+
+	// A reset without a segment records nothing
+	gate.Reset()
+	if n := len(gate.Diagnostics().Ends); n != 1 {
+		t.Errorf("got %d ends, want 1", n)
+	}
+}
+
+func TestAdaptiveGateKeepsRecentEnds(t *testing.T) {
+	h, gate := adaptiveHarness()
+	speakWithGaps(h, adaptiveRecentEnds+5, 2*time.Second)
+
+	ends := gate.Diagnostics().Ends
+	if len(ends) != adaptiveRecentEnds {
+		t.Fatalf("got %d ends, want the latest %d", len(ends), adaptiveRecentEnds)
+	}
+	for i := 1; i < len(ends); i++ {
+		if ends[i].At <= ends[i-1].At {
+			t.Fatalf("ends are not oldest first: %v after %v", ends[i].At, ends[i-1].At)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"fmt"
+	"time"
+)
+
+// Chunk durations a session can choose
+const (
+	DefaultChunkDuration = 40 * time.Millisecond
+	MinChunkDuration     = FrameDuration
+	MaxChunkDuration     = 200 * time.Millisecond // Longer chunks delay the avatar's lips noticeably
+)
+
+// ValidChunkDuration returns an error unless d is a chunk duration a session
+// can choose (0 selects the default)
+func ValidChunkDuration(d time.Duration) error {
+	if d != 0 && (d < MinChunkDuration || d > MaxChunkDuration) {
+		return fmt.Errorf("chunk duration %s outside %s - %s", d, MinChunkDuration, MaxChunkDuration)
+	}
+	return nil
+}
+
+// Chunk returns the stage that joins audio into chunks of duration d, so the
+// sink sends one message per chunk instead of one per 10ms frame. A chunk
+// goes out as soon as it is full, so no audio waits longer than d; a segment
+// end flushes the partial chunk before it.
+func Chunk(d time.Duration) Stage {
+	if d <= 0 {
+		d = DefaultChunkDuration
+	}
+	return &chunker{duration: d}
+}
+
+type chunker struct {
+	duration time.Duration
+	buf      []byte
+	rate     int   // Rate of buf
+	level    int64 // Highest level of the audio in buf
+}
+
+func (c *chunker) Process(p Packet, emit func(Packet)) {
+	switch p.Kind {
+	case KindAudio:
+		if p.SampleRate != c.rate {
+			c.flush(emit)
+			c.rate = p.SampleRate
+		}
+		c.buf = append(c.buf, p.PCM...)
+		c.level = max(c.level, p.Level)
+
+		size := max(int(int64(c.rate)*int64(c.duration)/int64(time.Second))*2, 2)
+		for len(c.buf) >= size {
+			c.emit(size, emit)
+		}
+	case KindSegmentEnd:
+		c.flush(emit)
+		emit(p)
+	default:
+		emit(p)
+	}
+}
+
+// flush emits the partial chunk
+func (c *chunker) flush(emit func(Packet)) {
+	if len(c.buf) > 0 {
+		c.emit(len(c.buf), emit)
+	}
+}
+
+// emit sends the first size bytes of buf as a chunk. The chunk gets its own
+// copy, so sinks may keep it.
+func (c *chunker) emit(size int, emit func(Packet)) {
+	pcm := append([]byte(nil), c.buf[:size]...)
+	c.buf = append(c.buf[:0], c.buf[size:]...)
+	level := c.level
+	if len(c.buf) == 0 {
+		c.level = 0
+	}
+	emit(Packet{Kind: KindAudio, PCM: pcm, SampleRate: c.rate, Level: level})
+}
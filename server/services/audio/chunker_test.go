@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// chunks runs packets through a Chunk stage and returns what it emits
+func chunks(d time.Duration, packets ...Packet) []Packet {
+	var out []Packet
+	pipeline := NewPipeline(Chunk(d), StageFunc(func(p Packet, _ func(Packet)) {
+		out = append(out, p)
+	}))
+	for _, p := range packets {
+		pipeline.Push(p)
+	}
+	return out
+}
+
+// audioFrame returns a 10ms frame at rate whose samples all hold value
+func audioFrame(rate int, value int16, level int64) Packet {
+	samples := make([]int16, rate/100)
+	for i := range samples {
+		samples[i] = value
+	}
+	return Packet{Kind: KindAudio, PCM: Bytes(samples), SampleRate: rate, Level: level}
+}
+
+func TestChunkSizes(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		frames   int
+		want     int // Full chunks
+	}{
+		{40 * time.Millisecond, 8, 2},
+		{40 * time.Millisecond, 7, 1},
+		{FrameDuration, 5, 5},
+		{25 * time.Millisecond, 10, 4},
+		{0, 4, 1}, // Default duration
+	}
+
+	for _, tt := range tests {
+		var packets []Packet
+		for i := 0; i < tt.frames; i++ {
+			packets = append(packets, audioFrame(16000, int16(i), 0))
+		}
+		out := chunks(tt.duration, packets...)
+
+		duration := tt.duration
+		if duration == 0 {
+			duration = DefaultChunkDuration
+		}
+		size := int(16000*duration/time.Second) * 2
+		if len(out) != tt.want {
+			t.Errorf("%v chunks of %d frames: got %d chunks, want %d", duration, tt.frames, len(out), tt.want)
+			continue
+		}
+		for i, p := range out {
+			if p.Kind != KindAudio || len(p.PCM) != size || p.SampleRate != 16000 {
+				t.Errorf("%v chunk %d: got %v of %d bytes at %dHz, want audio of %d bytes at 16000Hz",
+					duration, i, p.Kind, len(p.PCM), p.SampleRate, size)
+			}
+		}
+	}
+}
+
+func TestChunkKeepsOrder(t *testing.T) {
+	out := chunks(30*time.Millisecond,
+		audioFrame(16000, 1, 0), audioFrame(16000, 2, 0), audioFrame(16000, 3, 0))
+	if len(out) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(out))
+	}
+	samples := Samples(out[0].PCM)
+	for i, want := range []int16{1, 2, 3} {
+		if got := samples[i*160]; got != want {
+			t.Errorf("frame %d of the chunk holds %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestChunkFlushesOnSegmentEnd(t *testing.T) {
+	out := chunks(40*time.Millisecond,
+		Packet{Kind: KindSegmentStart},
+		audioFrame(16000, 1, 500),
+		audioFrame(16000, 2, 200),
+		Packet{Kind: KindSegmentEnd},
+		audioFrame(16000, 3, 100),
+	)
+
+	if len(out) != 3 {
+		t.Fatalf("got %d packets, want the start, the partial chunk and the end", len(out))
+	}
+	if out[0].Kind != KindSegmentStart {
+		t.Errorf("first packet is %v, want %v", out[0].Kind, KindSegmentStart)
+	}
+	partial := out[1]
+	if partial.Kind != KindAudio || len(partial.PCM) != 2*160*2 {
+		t.Errorf("partial chunk: got %v of %d bytes, want audio of %d bytes", partial.Kind, len(partial.PCM), 2*160*2)
+	}
+	if partial.Level != 500 {
+		t.Errorf("partial chunk level is %d, want the highest of its frames (500)", partial.Level)
+	}
+	if out[2].Kind != KindSegmentEnd {
+		t.Errorf("last packet is %v, want %v", out[2].Kind, KindSegmentEnd)
+	}
+}
+
+func TestChunkFlushesOnRateChange(t *testing.T) {
+	out := chunks(40*time.Millisecond, audioFrame(16000, 1, 0), audioFrame(24000, 2, 0))
+	if len(out) != 1 {
+		t.Fatalf("got %d chunks, want the 16kHz audio flushed", len(out))
+	}
+	if out[0].SampleRate != 16000 || len(out[0].PCM) != 160*2 {
+		t.Errorf("got %d bytes at %dHz, want 320 bytes at 16000Hz", len(out[0].PCM), out[0].SampleRate)
+	}
+}
+
+func TestChunkOwnsItsPCM(t *testing.T) {
+	var out []Packet
+	pipeline := NewPipeline(Chunk(FrameDuration), StageFunc(func(p Packet, _ func(Packet)) {
+		out = append(out, p)
+	}))
+	pipeline.Push(audioFrame(16000, 1, 0))
+	pipeline.Push(audioFrame(16000, 2, 0))
+
+	// The second chunk must not reuse the memory of the first
+	if got := Samples(out[0].PCM)[0]; got != 1 {
+		t.Errorf("first chunk holds %d after the second, want 1", got)
+	}
+}
+
+func TestValidChunkDuration(t *testing.T) {
+	for _, d := range []time.Duration{0, MinChunkDuration, DefaultChunkDuration, MaxChunkDuration} {
+		if err := ValidChunkDuration(d); err != nil {
+			t.Errorf("ValidChunkDuration(%v): %v", d, err)
+		}
+	}
+	for _, d := range []time.Duration{time.Millisecond, MaxChunkDuration + time.Millisecond} {
+		if err := ValidChunkDuration(d); err == nil {
+			t.Errorf("ValidChunkDuration(%v) accepted it", d)
+		}
+	}
+}
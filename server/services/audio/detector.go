@@ -0,0 +1,177 @@
+package audio
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// VoiceEvent is a Detector's decision for one frame
+type VoiceEvent int
+
+const (
+	VoiceSilence  VoiceEvent = iota // Not speaking, nothing is forwarded
+	VoiceStart                      // Speech onset; the audio starts with the pre-roll
+	VoiceContinue                   // Speech (or its hangover) continues
+	VoiceEnd                        // Speech ended; the segment closes after the audio
+)
+
+// Detector decides which frames belong to speech
+type Detector interface {
+	// Detect classifies a frame and returns the PCM to forward, oldest first
+	Detect(p Packet) (VoiceEvent, [][]byte)
+
+	// Reset forgets the current segment after it was closed from outside
+	// (pause or target switch)
+	Reset()
+}
+
+// Detect returns the stage that runs a Detector on audio packets and passes
+// on only speech, framed by KindSegmentStart and KindSegmentEnd. A
+// KindSegmentEnd from upstream closes the current segment early.
+func Detect(d Detector) Stage {
+	return &detectStage{detector: d}
+}
+
+type detectStage struct {
+	detector Detector
+	speaking bool
+}
+
+func (s *detectStage) Process(p Packet, emit func(Packet)) {
+	switch p.Kind {
+	case KindSegmentEnd:
+		s.detector.Reset()
+		if s.speaking {
+			s.speaking = false
+			emit(p)
+		}
+		return
+	case KindSegmentStart:
+		// Segments are the detector's to start
+		return
+	}
+
+	event, frames := s.detector.Detect(p)
+	if event == VoiceSilence {
+		return
+	}
+	if event == VoiceStart {
+		s.speaking = true
+		emit(Packet{Kind: KindSegmentStart, SampleRate: p.SampleRate, Level: p.Level})
+	}
+	for _, pcm := range frames {
+		emit(Packet{Kind: KindAudio, PCM: pcm, SampleRate: p.SampleRate, Level: p.Level})
+	}
+	if event == VoiceEnd && s.speaking {
+		s.speaking = false
+		emit(Packet{Kind: KindSegmentEnd, SampleRate: p.SampleRate, Level: p.Level})
+	}
+}
+
+// Default energy gate settings; one frame is 10ms
+const (
+	// Palabra audio has a lower amplitude than typical speech; 1000 filtered
+	// out actual speech
+	DefaultEnergyThreshold = 100
+	DefaultHangoverFrames  = 50 // 500ms of silence before the segment ends
+	DefaultPrerollFrames   = 10 // 100ms of audio before speech onset
+)
+
+// EnergyGate forwards frames whose level is above a threshold, plus a
+// hangover of silent frames after speech and a pre-roll of the frames before
+// it. Detect and Reset must be called from one goroutine; Update may be
+// called concurrently.
+type EnergyGate struct {
+	// Tunable at runtime through Update
+	threshold      atomic.Int64 // Level above which a frame is speech
+	hangoverFrames atomic.Int64 // Silent frames still forwarded before the segment ends
+	prerollFrames  atomic.Int64 // Pre-roll ring buffer length
+
+	// Detect state
+	preroll       [][]byte // Ring buffer of the last frames before speech
+	next          int      // Next position in the ring buffer
+	speaking      bool
+	silenceFrames int // Consecutive silent frames while speaking
+}
+
+// NewEnergyGate creates an energy gate with the default settings
+func NewEnergyGate() *EnergyGate {
+	g := &EnergyGate{preroll: make([][]byte, DefaultPrerollFrames)}
+	g.threshold.Store(DefaultEnergyThreshold)
+	g.hangoverFrames.Store(DefaultHangoverFrames)
+	g.prerollFrames.Store(DefaultPrerollFrames)
+	return g
+}
+
+// Detect gates on the level Convert measured
+func (g *EnergyGate) Detect(p Packet) (VoiceEvent, [][]byte) {
+	// Resize the pre-roll buffer if Update changed it
+	if n := int(g.prerollFrames.Load()); n != len(g.preroll) {
+		g.preroll = make([][]byte, n)
+		g.next = 0
+	}
+	defer g.remember(p.PCM)
+
+	if p.Level > g.threshold.Load() {
+		g.silenceFrames = 0
+		if g.speaking {
+			return VoiceContinue, [][]byte{p.PCM}
+		}
+
+		// Onset: send the pre-roll first to catch the beginning of the speech
+		g.speaking = true
+		audio := make([][]byte, 0, len(g.preroll)+1)
+		for i := range g.preroll {
+			if buf := g.preroll[(g.next+i)%len(g.preroll)]; buf != nil {
+				audio = append(audio, buf)
+			}
+		}
+		return VoiceStart, append(audio, p.PCM)
+	}
+
+	if !g.speaking {
+		return VoiceSilence, nil
+	}
+
+	// Keep sending for the hangover time after voice stops (to avoid cutting off)
+	g.silenceFrames++
+	if g.silenceFrames < int(g.hangoverFrames.Load()) {
+		return VoiceContinue, [][]byte{p.PCM}
+	}
+	g.speaking = false
+	g.silenceFrames = 0
+	return VoiceEnd, nil
+}
+
+// remember stores a frame in the pre-roll ring buffer
+func (g *EnergyGate) remember(buf []byte) {
+	g.preroll[g.next] = buf
+	g.next = (g.next + 1) % len(g.preroll)
+}
+
+// Reset forgets the current segment
+func (g *EnergyGate) Reset() {
+	g.speaking = false
+	g.silenceFrames = 0
+}
+
+// Update changes the gate's settings. Zero values keep the current setting.
+func (g *EnergyGate) Update(threshold int64, hangover, preroll time.Duration) error {
+	if threshold > 0 {
+		g.threshold.Store(threshold)
+	}
+	if hangover > 0 {
+		g.hangoverFrames.Store(int64(max(hangover/FrameDuration, 1)))
+	}
+	if preroll > 0 {
+		g.prerollFrames.Store(int64(max(preroll/FrameDuration, 1)))
+	}
+	return nil
+}
+
+// Settings describes the current settings for logs
+func (g *EnergyGate) Settings() string {
+	return fmt.Sprintf("energy threshold=%d, hangover=%dms, pre-roll=%dms",
+		g.threshold.Load(), g.hangoverFrames.Load()*10, g.prerollFrames.Load()*10)
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// frame returns a packet whose PCM identifies it by n, at the given level
+func frame(n int, level int64) Packet {
+	return Packet{Kind: KindAudio, PCM: []byte{byte(n), byte(n >> 8)}, SampleRate: 16000, Level: level}
+}
+
+// frameID returns the n frame built the PCM with
+func frameID(pcm []byte) int {
+	return int(pcm[0]) | int(pcm[1])<<8
+}
+
+const (
+	quiet = 0
+	loud  = DefaultEnergyThreshold * 10
+)
+
+func TestEnergyGatePreroll(t *testing.T) {
+	g := NewEnergyGate()
+
+	n := 0
+	for ; n < DefaultPrerollFrames+5; n++ {
+		if event, audio := g.Detect(frame(n, quiet)); event != VoiceSilence || audio != nil {
+			t.Fatalf("quiet frame %d: got %v with %d frames, want silence", n, event, len(audio))
+		}
+	}
+
+	event, audio := g.Detect(frame(n, loud))
+	if event != VoiceStart {
+		t.Fatalf("onset: got %v, want VoiceStart", event)
+	}
+	if len(audio) != DefaultPrerollFrames+1 {
+		t.Fatalf("onset: got %d frames, want the %d frame pre-roll and the onset", len(audio), DefaultPrerollFrames)
+	}
+	for i, pcm := range audio {
+		if want := n - DefaultPrerollFrames + i; frameID(pcm) != want {
+			t.Errorf("onset frame %d is frame %d, want %d (oldest first)", i, frameID(pcm), want)
+		}
+	}
+}
+
+func TestEnergyGatePrerollAtStreamStart(t *testing.T) {
+	g := NewEnergyGate()
+	g.Detect(frame(0, quiet))
+	g.Detect(frame(1, quiet))
+
+	// Only the frames seen so far lead in
+	if _, audio := g.Detect(frame(2, loud)); len(audio) != 3 {
+		t.Errorf("onset after 2 frames: got %d frames, want 3", len(audio))
+	}
+}
+
+func TestEnergyGateHangover(t *testing.T) {
+	g := NewEnergyGate()
+	g.Detect(frame(0, loud))
+
+	for i := 1; i < DefaultHangoverFrames; i++ {
+		event, audio := g.Detect(frame(i, quiet))
+		if event != VoiceContinue || len(audio) != 1 || frameID(audio[0]) != i {
+			t.Fatalf("hangover frame %d: got %v with %d frames, want VoiceContinue with the frame", i, event, len(audio))
+		}
+	}
+	if event, audio := g.Detect(frame(DefaultHangoverFrames, quiet)); event != VoiceEnd || len(audio) != 0 {
+		t.Fatalf("end of hangover: got %v with %d frames, want VoiceEnd without audio", event, len(audio))
+	}
+	if event, _ := g.Detect(frame(DefaultHangoverFrames+1, quiet)); event != VoiceSilence {
+		t.Fatalf("after the segment: got %v, want VoiceSilence", event)
+	}
+}
+
+func TestEnergyGateSpeechRestartsHangover(t *testing.T) {
+	g := NewEnergyGate()
+	g.Detect(frame(0, loud))
+	for i := 1; i < DefaultHangoverFrames-1; i++ {
+		g.Detect(frame(i, quiet))
+	}
+
+	if event, _ := g.Detect(frame(DefaultHangoverFrames, loud)); event != VoiceContinue {
+		t.Fatalf("speech during hangover: got %v, want VoiceContinue", event)
+	}
+	for i := 1; i < DefaultHangoverFrames; i++ {
+		if event, _ := g.Detect(frame(i, quiet)); event != VoiceContinue {
+			t.Fatalf("hangover frame %d after speech resumed: got %v, want VoiceContinue", i, event)
+		}
+	}
+}
+
+func TestEnergyGateReset(t *testing.T) {
+	g := NewEnergyGate()
+	g.Detect(frame(0, loud))
+	g.Detect(frame(1, quiet))
+
+	g.Reset()
+	if event, _ := g.Detect(frame(2, quiet)); event != VoiceSilence {
+		t.Fatalf("quiet frame after Reset: got %v, want VoiceSilence", event)
+	}
+	if event, _ := g.Detect(frame(3, loud)); event != VoiceStart {
+		t.Fatalf("loud frame after Reset: got %v, want VoiceStart", event)
+	}
+}
+
+func TestEnergyGateUpdate(t *testing.T) {
+	g := NewEnergyGate()
+	if err := g.Update(loud*2, 30*time.Millisecond, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		g.Detect(frame(i, quiet))
+	}
+	if event, _ := g.Detect(frame(5, loud)); event != VoiceSilence {
+		t.Fatalf("frame under the raised threshold: got %v, want VoiceSilence", event)
+	}
+	event, audio := g.Detect(frame(6, loud*3))
+	if event != VoiceStart || len(audio) != 3 {
+		t.Fatalf("onset: got %v with %d frames, want VoiceStart with a 2 frame pre-roll", event, len(audio))
+	}
+
+	g.Detect(frame(7, quiet))
+	g.Detect(frame(8, quiet))
+	if event, _ := g.Detect(frame(9, quiet)); event != VoiceEnd {
+		t.Fatalf("third silent frame: got %v, want VoiceEnd after 30ms", event)
+	}
+}
+
+func TestDetectStageFraming(t *testing.T) {
+	var kinds []Kind
+	pipeline := NewPipeline(Detect(NewEnergyGate()), StageFunc(func(p Packet, _ func(Packet)) {
+		kinds = append(kinds, p.Kind)
+	}))
+
+	pipeline.Push(frame(0, loud))
+	pipeline.Push(frame(1, loud))
+	pipeline.EndSegment()
+	pipeline.EndSegment() // No segment is open
+
+	want := []Kind{KindSegmentStart, KindAudio, KindAudio, KindSegmentEnd}
+	if len(kinds) != len(want) {
+		t.Fatalf("got %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("got %v, want %v", kinds, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Harness drives stages the way the bot's SDK callback does, one 10ms frame
+// at a time, with synthetic or recorded audio, and records what comes out of
+// the last stage. It needs no SDK, so the audio path can be exercised without
+// joining a channel.
+type Harness struct {
+	rate     int
+	pipeline *Pipeline
+	pushed   int      // Frames pushed so far
+	outputs  []Output // In the order they came out
+	rng      *rand.Rand
+	phase    float64 // Tone phase, kept across calls
+}
+
+// Output is a packet that came out of the harnessed stages
+type Output struct {
+	At     time.Duration // Stream time of the frame that pushed it out
+	Packet Packet
+}
+
+// Segment summarizes the output of one speech segment
+type Segment struct {
+	Start   time.Duration // Stream time the segment started
+	End     time.Duration // Stream time the segment ended, 0 if it is still open
+	Audio   time.Duration // Audio delivered for the segment
+	Packets int           // Audio packets delivered for the segment
+}
+
+// NewHarness creates a harness that feeds frames at rate to stages
+func NewHarness(rate int, stages ...Stage) *Harness {
+	h := &Harness{rate: rate, rng: rand.New(rand.NewSource(1))}
+	h.pipeline = NewPipeline(append(stages, StageFunc(h.record))...)
+	return h
+}
+
+func (h *Harness) record(p Packet, _ func(Packet)) {
+	h.outputs = append(h.outputs, Output{At: h.Elapsed(), Packet: p})
+}
+
+// Elapsed returns the stream time of the frames pushed so far
+func (h *Harness) Elapsed() time.Duration {
+	return time.Duration(h.pushed) * FrameDuration
+}
+
+// frameSamples returns the samples in one frame
+func (h *Harness) frameSamples() int {
+	return h.rate * int(FrameDuration/time.Millisecond) / 1000
+}
+
+// Silence pushes d of digital silence
+func (h *Harness) Silence(d time.Duration) {
+	h.generate(d, func() int16 { return 0 })
+}
+
+// Tone pushes d of a sine tone. amplitude is the peak sample value.
+func (h *Harness) Tone(d time.Duration, freq float64, amplitude int16) {
+	step := 2 * math.Pi * freq / float64(h.rate)
+	h.generate(d, func() int16 {
+		v := float64(amplitude) * math.Sin(h.phase)
+		h.phase = math.Mod(h.phase+step, 2*math.Pi)
+		return int16(v)
+	})
+}
+
+// Noise pushes d of uniform white noise with the given peak sample value.
+// The noise is seeded, so runs repeat.
+func (h *Harness) Noise(d time.Duration, amplitude int16) {
+	h.generate(d, func() int16 {
+		return int16((h.rng.Float64()*2 - 1) * float64(amplitude))
+	})
+}
+
+func (h *Harness) generate(d time.Duration, sample func() int16) {
+	frames := int(d / FrameDuration)
+	for i := 0; i < frames; i++ {
+		samples := make([]int16, h.frameSamples())
+		for j := range samples {
+			samples[j] = sample()
+		}
+		h.Push(Bytes(samples))
+	}
+}
+
+// PCM pushes recorded little-endian mono PCM16 at the harness rate, split
+// into frames. A trailing partial frame is dropped, as the SDK never
+// delivers one.
+func (h *Harness) PCM(pcm []byte) {
+	size := h.frameSamples() * 2
+	for len(pcm) >= size {
+		h.Push(pcm[:size])
+		pcm = pcm[size:]
+	}
+}
+
+// Push feeds one frame of PCM
+func (h *Harness) Push(frame []byte) {
+	h.pushed++
+	h.pipeline.Push(Packet{
+		Kind:       KindAudio,
+		PCM:        frame,
+		SampleRate: h.rate,
+		VAD:        VADResult{State: VADInvalid},
+	})
+}
+
+// EndSegment closes the current segment, as pausing the bot does
+func (h *Harness) EndSegment() {
+	h.pipeline.EndSegment()
+}
+
+// Outputs returns every packet that came out so far
+func (h *Harness) Outputs() []Output {
+	return h.outputs
+}
+
+// Segments summarizes the output as speech segments
+func (h *Harness) Segments() []Segment {
+	var segments []Segment
+	var current *Segment
+	var samples int64 // Of the current segment, summed to avoid rounding per packet
+	for _, out := range h.outputs {
+		switch out.Packet.Kind {
+		case KindSegmentStart:
+			segments = append(segments, Segment{Start: out.At})
+			current = &segments[len(segments)-1]
+			samples = 0
+		case KindAudio:
+			if current == nil || out.Packet.SampleRate <= 0 {
+				continue
+			}
+			current.Packets++
+			samples += int64(len(out.Packet.PCM) / 2)
+			current.Audio = time.Duration(samples * int64(time.Second) / int64(out.Packet.SampleRate))
+		case KindSegmentEnd:
+			if current != nil {
+				current.End = out.At
+				current = nil
+			}
+		}
+	}
+	return segments
+}
@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHarnessSegments(t *testing.T) {
+	h := NewHarness(16000, Convert(), Detect(NewEnergyGate()))
+	h.Silence(200 * time.Millisecond)
+	h.Tone(300*time.Millisecond, 200, 3000)
+	h.Silence(time.Second)
+
+	segments := h.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("got %d segments, want 1", len(segments))
+	}
+	segment := segments[0]
+
+	// The onset frame ends at 210ms and the last hangover frame at 1s
+	if segment.Start != 210*time.Millisecond {
+		t.Errorf("segment starts at %v, want 210ms", segment.Start)
+	}
+	if segment.End != time.Second {
+		t.Errorf("segment ends at %v, want 1s", segment.End)
+	}
+
+	// Pre-roll, tone and the hangover minus the frame that ends it
+	frames := DefaultPrerollFrames + 30 + DefaultHangoverFrames - 1
+	if segment.Packets != frames {
+		t.Errorf("segment has %d packets, want %d", segment.Packets, frames)
+	}
+	if want := time.Duration(frames) * FrameDuration; segment.Audio != want {
+		t.Errorf("segment has %v of audio, want %v", segment.Audio, want)
+	}
+}
+
+func TestHarnessSegmentsEndedFromOutside(t *testing.T) {
+	h := NewHarness(16000, Convert(), Detect(NewEnergyGate()))
+	h.Tone(100*time.Millisecond, 200, 3000)
+	h.EndSegment()
+	h.Tone(100*time.Millisecond, 200, 3000)
+
+	segments := h.Segments()
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segments))
+	}
+	if segments[0].End != 100*time.Millisecond {
+		t.Errorf("first segment ends at %v, want 100ms", segments[0].End)
+	}
+	if segments[1].End != 0 {
+		t.Errorf("open segment ends at %v, want 0", segments[1].End)
+	}
+}
+
+func TestHarnessSegmentsResampled(t *testing.T) {
+	resampler, _ := NewResampler(16000, 24000, 1)
+	h := NewHarness(16000, Convert(), Detect(NewEnergyGate()), Resample(resampler))
+	h.Tone(200*time.Millisecond, 200, 3000)
+	h.EndSegment()
+
+	segments := h.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("got %d segments, want 1", len(segments))
+	}
+	// The flush at the segment end completes the resampled audio
+	if segments[0].Audio != 200*time.Millisecond {
+		t.Errorf("segment has %v of audio, want 200ms", segments[0].Audio)
+	}
+}
+
+func TestHarnessPCM(t *testing.T) {
+	h := NewHarness(16000, Convert(), Detect(NewEnergyGate()))
+	pcm := Bytes(tone(16000, 1600+80, 0, 200, 3000))
+	h.PCM(pcm)
+
+	// The trailing half frame is dropped
+	if h.Elapsed() != 100*time.Millisecond {
+		t.Errorf("pushed %v, want 100ms", h.Elapsed())
+	}
+	if outputs := h.Outputs(); len(outputs) != 11 {
+		t.Errorf("got %d outputs, want the segment start and 10 frames", len(outputs))
+	}
+}
@@ -0,0 +1,181 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Loudness settings a session can choose
+const (
+	DefaultLoudnessTarget  = -23.0 // dBFS, RMS of speech after normalization
+	DefaultLoudnessMaxGain = 24.0  // dB
+	DefaultLoudnessCeiling = -1.0  // dBFS, peak
+)
+
+// Tuning of the Loudness stage
+const (
+	loudnessGate      = -60.0                  // dBFS; quieter frames leave the AGC alone and are not amplified
+	loudnessAverage   = 400 * time.Millisecond // Time constant of the speech level estimate
+	loudnessRiseRate  = 6.0                    // dB/s the gain rises at most, so pauses are not pumped up
+	loudnessFallRate  = 30.0                   // dB/s the gain falls at most
+	limiterRelease    = 50 * time.Millisecond  // Time the limiter takes to let go after a peak
+	silenceLevel      = -120.0                 // dBFS reported for digital silence
+	fullScale         = 32768.0
+	fullScaleSquared  = fullScale * fullScale
+	minLoudnessTarget = -40.0
+	maxLoudnessTarget = -6.0
+	maxLoudnessGain   = 40.0
+	minLimiterCeiling = -12.0
+)
+
+// LoudnessConfig sets the level the Loudness stage brings speech to. Zero
+// fields select the defaults.
+type LoudnessConfig struct {
+	TargetDBFS  float64 // RMS level speech is brought to
+	MaxGainDB   float64 // Most the AGC amplifies quiet speech (and attenuates loud speech)
+	CeilingDBFS float64 // Peak level the limiter keeps the output under
+}
+
+// WithDefaults returns c with its zero fields set to the defaults
+func (c LoudnessConfig) WithDefaults() LoudnessConfig {
+	if c.TargetDBFS == 0 {
+		c.TargetDBFS = DefaultLoudnessTarget
+	}
+	if c.MaxGainDB == 0 {
+		c.MaxGainDB = DefaultLoudnessMaxGain
+	}
+	if c.CeilingDBFS == 0 {
+		c.CeilingDBFS = DefaultLoudnessCeiling
+	}
+	return c
+}
+
+// Validate returns an error unless c, with its defaults, is a loudness
+// configuration a session can choose
+func (c LoudnessConfig) Validate() error {
+	c = c.WithDefaults()
+	switch {
+	case c.TargetDBFS < minLoudnessTarget || c.TargetDBFS > maxLoudnessTarget:
+		return fmt.Errorf("loudness target %g dBFS outside %g - %g dBFS", c.TargetDBFS, minLoudnessTarget, maxLoudnessTarget)
+	case c.MaxGainDB < 0 || c.MaxGainDB > maxLoudnessGain:
+		return fmt.Errorf("loudness max gain %g dB outside 0 - %g dB", c.MaxGainDB, maxLoudnessGain)
+	case c.CeilingDBFS < minLimiterCeiling || c.CeilingDBFS > 0:
+		return fmt.Errorf("limiter ceiling %g dBFS outside %g - 0 dBFS", c.CeilingDBFS, minLimiterCeiling)
+	case c.CeilingDBFS <= c.TargetDBFS:
+		return fmt.Errorf("limiter ceiling %g dBFS must be above the loudness target %g dBFS", c.CeilingDBFS, c.TargetDBFS)
+	}
+	return nil
+}
+
+func (c LoudnessConfig) String() string {
+	c = c.WithDefaults()
+	return fmt.Sprintf("target=%g dBFS, maxGain=%g dB, ceiling=%g dBFS", c.TargetDBFS, c.MaxGainDB, c.CeilingDBFS)
+}
+
+// Loudness returns the stage that brings speech to the configured level: a
+// slow AGC follows the level of the frames above the gate and moves its gain
+// by a few dB per second, and a limiter keeps the peaks the gain produces
+// under the ceiling. The gain carries from segment to segment, so the first
+// words of a segment are not ramped. Packets leave with their Level measured
+// after the gain.
+func Loudness(c LoudnessConfig) Stage {
+	c = c.WithDefaults()
+	return &normalizer{config: c, agc: true, applied: 1, limiter: 1}
+}
+
+// Limit returns the stage that keeps peaks under ceilingDBFS (0 for the
+// default) without changing the level otherwise, for audio an AGC already ran
+// on
+func Limit(ceilingDBFS float64) Stage {
+	c := LoudnessConfig{CeilingDBFS: ceilingDBFS}.WithDefaults()
+	return &normalizer{config: c, applied: 1, limiter: 1}
+}
+
+type normalizer struct {
+	config   LoudnessConfig
+	agc      bool
+	tracked  bool    // A frame above the gate was seen, so estimate is set
+	estimate float64 // Speech level, dBFS
+	gain     float64 // AGC gain, dB
+	applied  float64 // Linear gain applied at the end of the previous packet
+	limiter  float64 // Linear gain of the limiter, 1 when idle
+}
+
+func (n *normalizer) Process(p Packet, emit func(Packet)) {
+	if p.Kind != KindAudio || p.SampleRate <= 0 {
+		emit(p)
+		return
+	}
+	samples := p.Samples
+	if samples == nil {
+		samples = Samples(p.PCM)
+	}
+
+	target := 1.0
+	if n.agc {
+		target = math.Pow(10, n.adapt(samples, p.SampleRate)/20)
+	}
+
+	// Ramp the gain over the packet, so gain steps do not click
+	ceiling := fullScale * math.Pow(10, n.config.CeilingDBFS/20)
+	release := 1 - math.Exp(-1/(limiterRelease.Seconds()*float64(p.SampleRate)))
+	out := make([]int16, len(samples))
+	for i, sample := range samples {
+		g := n.applied + (target-n.applied)*float64(i+1)/float64(len(samples))
+		v := float64(sample) * g
+		if peak := math.Abs(v) * n.limiter; peak > ceiling {
+			n.limiter = ceiling / math.Abs(v)
+		}
+		v *= n.limiter
+		n.limiter += (1 - n.limiter) * release
+		out[i] = int16(math.Max(math.Min(math.Round(v), math.MaxInt16), math.MinInt16))
+	}
+	n.applied = target
+
+	p.Samples = out
+	p.PCM = Bytes(out)
+	p.Level = MeanSquare(out)
+	emit(p)
+}
+
+// adapt updates the AGC with the level of samples and returns the gain in dB
+// to apply to them
+func (n *normalizer) adapt(samples []int16, rate int) float64 {
+	level := Level(MeanSquare(samples))
+	if level < loudnessGate {
+		// Hold the gain for the next speech, but do not amplify noise
+		return math.Min(n.gain, 0)
+	}
+
+	dt := float64(len(samples)) / float64(rate)
+	if !n.tracked {
+		// Start at the gain the first speech needs instead of ramping up to it
+		n.tracked = true
+		n.estimate = level
+		n.gain = n.wanted()
+		return n.gain
+	}
+
+	n.estimate += (level - n.estimate) * math.Min(dt/loudnessAverage.Seconds(), 1)
+	wanted := n.wanted()
+	if wanted > n.gain {
+		n.gain = math.Min(wanted, n.gain+loudnessRiseRate*dt)
+	} else {
+		n.gain = math.Max(wanted, n.gain-loudnessFallRate*dt)
+	}
+	return n.gain
+}
+
+// wanted returns the gain that brings the estimated speech level to the target
+func (n *normalizer) wanted() float64 {
+	return math.Max(-n.config.MaxGainDB, math.Min(n.config.TargetDBFS-n.estimate, n.config.MaxGainDB))
+}
+
+// Level converts a mean square to dBFS
+func Level(meanSquare int64) float64 {
+	if meanSquare <= 0 {
+		return silenceLevel
+	}
+	return 10 * math.Log10(float64(meanSquare)/fullScaleSquared)
+}
@@ -0,0 +1,150 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// amplitudeFor returns the peak of a sine tone whose RMS level is dbfs
+func amplitudeFor(dbfs float64) int16 {
+	return int16(fullScale * math.Pow(10, dbfs/20) * math.Sqrt2)
+}
+
+// normalize pushes d of a 200Hz tone at dbfs through stage in 10ms frames and
+// returns the audio that comes out
+func normalize(stage Stage, d time.Duration, dbfs float64) []Packet {
+	var out []Packet
+	pipeline := NewPipeline(stage, StageFunc(func(p Packet, _ func(Packet)) {
+		out = append(out, p)
+	}))
+	frames := int(d / FrameDuration)
+	for i := 0; i < frames; i++ {
+		pcm := Bytes(tone(16000, 160, i*160, 200, amplitudeFor(dbfs)))
+		pipeline.Push(Packet{Kind: KindAudio, PCM: pcm, SampleRate: 16000})
+	}
+	return out
+}
+
+// levelOf returns the RMS level of a packet in dBFS
+func levelOf(p Packet) float64 {
+	return Level(MeanSquare(Samples(p.PCM)))
+}
+
+func TestLoudnessReachesTarget(t *testing.T) {
+	for _, input := range []float64{-40, -30, -15} {
+		out := normalize(Loudness(LoudnessConfig{}), time.Second, input)
+		if got := levelOf(out[len(out)-1]); math.Abs(got-DefaultLoudnessTarget) > 1 {
+			t.Errorf("%g dBFS input: output at %.1f dBFS, want %g dBFS", input, got, DefaultLoudnessTarget)
+		}
+	}
+}
+
+func TestLoudnessStartsAtTheNeededGain(t *testing.T) {
+	// The first frame of speech is not ramped up from 0dB
+	out := normalize(Loudness(LoudnessConfig{}), 20*time.Millisecond, -35)
+	if got := levelOf(out[1]); math.Abs(got-DefaultLoudnessTarget) > 1 {
+		t.Errorf("second frame at %.1f dBFS, want %g dBFS", got, DefaultLoudnessTarget)
+	}
+}
+
+func TestLoudnessMaxGain(t *testing.T) {
+	out := normalize(Loudness(LoudnessConfig{MaxGainDB: 10}), time.Second, -45)
+	if got := levelOf(out[len(out)-1]); math.Abs(got-(-35)) > 1 {
+		t.Errorf("output at %.1f dBFS, want the input raised by the 10dB max gain (-35 dBFS)", got)
+	}
+}
+
+func TestLoudnessLeavesNoiseAlone(t *testing.T) {
+	out := normalize(Loudness(LoudnessConfig{}), 500*time.Millisecond, -70)
+	for i, p := range out {
+		if got := levelOf(p); got > -69 {
+			t.Fatalf("frame %d below the gate raised to %.1f dBFS", i, got)
+		}
+	}
+}
+
+func TestLoudnessRiseRate(t *testing.T) {
+	stage := Loudness(LoudnessConfig{})
+	normalize(stage, time.Second, -35)
+
+	// The speech drops 10dB; half a second lets the gain rise 3dB at most
+	out := normalize(stage, 500*time.Millisecond, -45)
+	got := levelOf(out[len(out)-1])
+	if got > DefaultLoudnessTarget-10+loudnessRiseRate/2+0.5 {
+		t.Errorf("output at %.1f dBFS, the gain rose faster than %g dB/s", got, loudnessRiseRate)
+	}
+	if got < DefaultLoudnessTarget-10 {
+		t.Errorf("output at %.1f dBFS, the gain did not rise", got)
+	}
+}
+
+func TestLoudnessSetsLevel(t *testing.T) {
+	out := normalize(Loudness(LoudnessConfig{}), 100*time.Millisecond, -40)
+	p := out[len(out)-1]
+	if p.Level != MeanSquare(Samples(p.PCM)) {
+		t.Errorf("Level %d is not the mean square of the normalized audio", p.Level)
+	}
+}
+
+func TestLimitKeepsPeaksUnderCeiling(t *testing.T) {
+	ceiling := fullScale * math.Pow(10, -6.0/20)
+	for i, p := range normalize(Limit(-6), 200*time.Millisecond, -3) {
+		for _, sample := range Samples(p.PCM) {
+			if math.Abs(float64(sample)) > ceiling+1 {
+				t.Fatalf("frame %d: peak %d over the %.0f ceiling", i, sample, ceiling)
+			}
+		}
+	}
+}
+
+func TestLimitLeavesQuietAudio(t *testing.T) {
+	out := normalize(Limit(0), 100*time.Millisecond, -30)
+	for i, p := range out {
+		want := Bytes(tone(16000, 160, i*160, 200, amplitudeFor(-30)))
+		if string(p.PCM) != string(want) {
+			t.Fatalf("frame %d under the ceiling was changed", i)
+		}
+	}
+}
+
+func TestLoudnessPassesMarkers(t *testing.T) {
+	var kinds []Kind
+	pipeline := NewPipeline(Loudness(LoudnessConfig{}), StageFunc(func(p Packet, _ func(Packet)) {
+		kinds = append(kinds, p.Kind)
+	}))
+	pipeline.Push(Packet{Kind: KindSegmentStart})
+	pipeline.EndSegment()
+
+	if len(kinds) != 2 || kinds[0] != KindSegmentStart || kinds[1] != KindSegmentEnd {
+		t.Errorf("got %v, want the markers unchanged", kinds)
+	}
+}
+
+func TestLoudnessConfigValidate(t *testing.T) {
+	valid := []LoudnessConfig{
+		{},
+		{TargetDBFS: -40, MaxGainDB: 40, CeilingDBFS: -12},
+		{TargetDBFS: -6, CeilingDBFS: -1},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%+v: %v", c, err)
+		}
+	}
+
+	invalid := []LoudnessConfig{
+		{TargetDBFS: -41},
+		{TargetDBFS: -5},
+		{MaxGainDB: -1},
+		{MaxGainDB: 41},
+		{CeilingDBFS: -13},
+		{CeilingDBFS: 1},
+		{TargetDBFS: -10, CeilingDBFS: -12},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
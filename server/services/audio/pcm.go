@@ -0,0 +1,20 @@
+package audio
+
+// Samples converts little-endian PCM16 bytes to samples
+func Samples(buf []byte) []int16 {
+	samples := make([]int16, len(buf)/2)
+	for i := range samples {
+		samples[i] = int16(buf[i*2]) | int16(buf[i*2+1])<<8
+	}
+	return samples
+}
+
+// Bytes converts samples to little-endian PCM16 bytes
+func Bytes(samples []int16) []byte {
+	buf := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		buf[i*2] = byte(sample)
+		buf[i*2+1] = byte(sample >> 8)
+	}
+	return buf
+}
@@ -0,0 +1,175 @@
+package audio
+
+import (
+	"fmt"
+	"time"
+)
+
+// FrameDuration is the length of the frames the SDK delivers
+const FrameDuration = 10 * time.Millisecond
+
+// Kind tells what a Packet carries
+type Kind int
+
+const (
+	KindAudio        Kind = iota // PCM of the stream
+	KindSegmentStart             // A speech segment starts; its audio follows
+	KindSegmentEnd               // The speech segment ended; stages flush what they hold before passing it on
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindAudio:
+		return "audio"
+	case KindSegmentStart:
+		return "segment_start"
+	case KindSegmentEnd:
+		return "segment_end"
+	default:
+		return fmt.Sprintf("kind_%d", int(k))
+	}
+}
+
+// FrameAnalysis is the APM analysis the SDK attaches to observed frames
+// (zero for frames from elsewhere)
+type FrameAnalysis struct {
+	FarFieldFlag int
+	Rms          int
+	VoiceProb    int
+	MusicProb    int
+	Pitch        int
+}
+
+// VADState is the state of a VAD result. The values match the SDK's VadState.
+type VADState int
+
+const (
+	VADInvalid       VADState = -1 // No VAD ran on the frame
+	VADNoSpeaking    VADState = 0
+	VADStartSpeaking VADState = 1
+	VADSpeaking      VADState = 2
+	VADStopSpeaking  VADState = 3
+)
+
+// VADResult is the result of a VAD that ran before the pipeline
+type VADResult struct {
+	State VADState
+	PCM   []byte // Audio the VAD releases with the state (the lead-in at the start)
+}
+
+// Packet is the unit moving through a Pipeline: audio, or a marker framing a
+// speech segment
+type Packet struct {
+	Kind       Kind
+	PCM        []byte  // Little-endian mono PCM16 (KindAudio)
+	SampleRate int     // Rate of PCM
+	Samples    []int16 // PCM decoded, nil until a stage decodes it
+	Level      int64   // Mean square of the frame that produced the packet, set by Convert
+
+	// Set by the source for stages that need more than the PCM
+	Analysis FrameAnalysis
+	VAD      VADResult
+}
+
+// Stage is one step of a Pipeline. Process handles a packet and passes its
+// output to the next stage through emit, zero or more packets at a time and
+// in order. Stages pass on packets they do not handle.
+type Stage interface {
+	Process(p Packet, emit func(Packet))
+}
+
+// StageFunc adapts a function to a Stage
+type StageFunc func(p Packet, emit func(Packet))
+
+// Process calls f
+func (f StageFunc) Process(p Packet, emit func(Packet)) {
+	f(p, emit)
+}
+
+// Pipeline chains stages: each stage's output is the next stage's input and
+// the output of the last one is dropped, so the last stage is the sink.
+//
+// A Pipeline is not safe for concurrent use. The bot pushes from the SDK's
+// audio thread only.
+type Pipeline struct {
+	push func(Packet)
+}
+
+// NewPipeline chains stages in the order given
+func NewPipeline(stages ...Stage) *Pipeline {
+	emit := func(Packet) {}
+	for i := len(stages) - 1; i >= 0; i-- {
+		stage, next := stages[i], emit
+		emit = func(p Packet) { stage.Process(p, next) }
+	}
+	return &Pipeline{push: emit}
+}
+
+// Push feeds a packet to the first stage and returns once every stage is done
+// with it
+func (p *Pipeline) Push(pkt Packet) {
+	p.push(pkt)
+}
+
+// EndSegment closes the current speech segment, if any, as if the speaker
+// had stopped
+func (p *Pipeline) EndSegment() {
+	p.push(Packet{Kind: KindSegmentEnd})
+}
+
+// Convert returns the stage that decodes the PCM of audio packets and
+// measures their level
+func Convert() Stage {
+	return StageFunc(func(p Packet, emit func(Packet)) {
+		if p.Kind == KindAudio {
+			p.Samples = Samples(p.PCM)
+			p.Level = MeanSquare(p.Samples)
+		}
+		emit(p)
+	})
+}
+
+// MeanSquare returns the mean square of samples, the level the bot's
+// thresholds are expressed in
+func MeanSquare(samples []int16) int64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum int64
+	for _, sample := range samples {
+		sum += int64(sample) * int64(sample)
+	}
+	return sum / int64(len(samples))
+}
+
+// Resample returns the stage that converts audio to the resampler's output
+// rate. The filter state carries from packet to packet; KindSegmentEnd
+// flushes the tail of the segment and starts a new stream.
+func Resample(r *Resampler) Stage {
+	return StageFunc(func(p Packet, emit func(Packet)) {
+		switch p.Kind {
+		case KindAudio:
+			samples := p.Samples
+			if samples == nil {
+				samples = Samples(p.PCM)
+			}
+			resampled(p, r.Process(samples), r.OutRate(), emit)
+		case KindSegmentEnd:
+			resampled(Packet{Kind: KindAudio, Level: p.Level}, r.Flush(), r.OutRate(), emit)
+			emit(p)
+		default:
+			emit(p)
+		}
+	})
+}
+
+// resampled emits p with resampled audio, unless there is none
+func resampled(p Packet, samples []int16, rate int, emit func(Packet)) {
+	if len(samples) == 0 {
+		return
+	}
+	p.Samples = samples
+	p.PCM = Bytes(samples)
+	p.SampleRate = rate
+	emit(p)
+}
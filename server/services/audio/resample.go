@@ -0,0 +1,228 @@
+// Package audio holds PCM processing shared by the bot's audio path.
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// Filter design of the resampler
+const (
+	zeroCrossings = 16   // Sinc zero crossings on each side of the filter center
+	kaiserBeta    = 8.6  // Kaiser window shape (about 80dB stopband attenuation)
+	cutoffMargin  = 0.95 // Cutoff as a fraction of the lower Nyquist frequency
+	maxPhases     = 1024 // Largest polyphase table; larger ratios compute coefficients per sample
+)
+
+// Resampler converts interleaved PCM16 between two sample rates with a
+// Kaiser-windowed sinc filter. It keeps the filter state between calls, so a
+// stream fed in 10ms frames comes out as if it had been converted at once.
+// Output lags the input by the filter's half width (1ms at 16kHz); Flush
+// emits the held-back tail at the end of a stream.
+//
+// A Resampler is not safe for concurrent use.
+type Resampler struct {
+	inRate, outRate int
+	channels        int
+
+	// Output sample n sits at input position n*step/phases, tracked as an
+	// integer position plus a phase in [0, phases)
+	step, phases int
+	pos, phase   int
+
+	cutoff  float64     // Normalized to the input rate
+	taps    int         // Input frames on each side of the output position
+	table   [][]float64 // Coefficients per phase, nil if there are too many phases
+	history []int16     // Interleaved input from frame base on
+	base    int         // Input position of history[0]
+}
+
+// NewResampler creates a resampler for interleaved PCM16 with the given
+// number of channels
+func NewResampler(inRate, outRate, channels int) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rates %d -> %d", inRate, outRate)
+	}
+	if channels <= 0 {
+		return nil, fmt.Errorf("invalid channel count %d", channels)
+	}
+
+	g := gcd(inRate, outRate)
+	r := &Resampler{
+		inRate:   inRate,
+		outRate:  outRate,
+		channels: channels,
+		step:     inRate / g,
+		phases:   outRate / g,
+		cutoff:   cutoffMargin * math.Min(1, float64(outRate)/float64(inRate)),
+	}
+	r.taps = int(math.Ceil(zeroCrossings / r.cutoff))
+
+	if r.phases <= maxPhases {
+		r.table = make([][]float64, r.phases)
+		for p := range r.table {
+			r.table[p] = r.coefficients(p)
+		}
+	}
+	r.Reset()
+	return r, nil
+}
+
+// InRate returns the input sample rate
+func (r *Resampler) InRate() int { return r.inRate }
+
+// OutRate returns the output sample rate
+func (r *Resampler) OutRate() int { return r.outRate }
+
+// Channels returns the number of interleaved channels
+func (r *Resampler) Channels() int { return r.channels }
+
+// Reset drops the filter state to start a new stream
+func (r *Resampler) Reset() {
+	// The stream starts after taps frames of silence
+	r.history = make([]int16, r.taps*r.channels)
+	r.base = -r.taps
+	r.pos = 0
+	r.phase = 0
+}
+
+// Process resamples interleaved samples. A trailing partial frame is ignored.
+func (r *Resampler) Process(in []int16) []int16 {
+	in = in[:len(in)-len(in)%r.channels]
+	r.history = append(r.history, in...)
+	return r.drain(r.base + len(r.history)/r.channels)
+}
+
+// ProcessPCM resamples interleaved little-endian PCM16 bytes
+func (r *Resampler) ProcessPCM(buf []byte) []byte {
+	return Bytes(r.Process(Samples(buf)))
+}
+
+// Flush returns the output still held back by the filter and resets the
+// resampler for a new stream
+func (r *Resampler) Flush() []int16 {
+	end := r.base + len(r.history)/r.channels
+	done := r.produced()
+	r.history = append(r.history, make([]int16, r.taps*r.channels)...)
+	out := r.drain(end + r.taps)
+
+	// Keep only the output that belongs to real input
+	keep := r.outputsBefore(end) - done
+	out = out[:min(len(out), max(keep, 0)*r.channels)]
+	r.Reset()
+	return out
+}
+
+// FlushPCM is Flush for little-endian PCM16 bytes
+func (r *Resampler) FlushPCM() []byte {
+	return Bytes(r.Flush())
+}
+
+// drain computes every output whose filter window lies before input frame
+// end, then discards history no longer needed
+func (r *Resampler) drain(end int) []int16 {
+	var out []int16
+	for r.pos+r.taps < end {
+		coef := r.coefficientsAt(r.phase)
+		start := (r.pos - r.taps + 1 - r.base) * r.channels
+		for c := 0; c < r.channels; c++ {
+			var acc float64
+			for k, h := range coef {
+				acc += h * float64(r.history[start+k*r.channels+c])
+			}
+			out = append(out, clamp16(acc))
+		}
+
+		r.phase += r.step
+		r.pos += r.phase / r.phases
+		r.phase %= r.phases
+	}
+
+	if drop := r.pos - r.taps + 1 - r.base; drop > 0 {
+		r.history = append(r.history[:0], r.history[drop*r.channels:]...)
+		r.base += drop
+	}
+	return out
+}
+
+// outputsBefore returns how many outputs fall before input frame end
+func (r *Resampler) outputsBefore(end int) int {
+	// Output n sits at n*step/phases; count n with n*step < end*phases
+	return int((int64(end)*int64(r.phases) + int64(r.step) - 1) / int64(r.step))
+}
+
+// produced returns how many outputs were computed since Reset
+func (r *Resampler) produced() int {
+	return int((int64(r.pos)*int64(r.phases) + int64(r.phase)) / int64(r.step))
+}
+
+// coefficientsAt returns the filter for an output at the given phase
+func (r *Resampler) coefficientsAt(phase int) []float64 {
+	if r.table != nil {
+		return r.table[phase]
+	}
+	return r.coefficients(phase)
+}
+
+// coefficients computes the filter for an output phase/phases input frames
+// after frame pos, applied to frames pos-taps+1 ... pos+taps
+func (r *Resampler) coefficients(phase int) []float64 {
+	frac := float64(phase) / float64(r.phases)
+	coef := make([]float64, 2*r.taps)
+	var sum float64
+	for k := range coef {
+		t := frac - float64(k-r.taps+1) // Distance from the output position
+		h := r.cutoff * sinc(r.cutoff*t) * kaiser(t/float64(r.taps))
+		coef[k] = h
+		sum += h
+	}
+	// Unity gain at DC
+	for k := range coef {
+		coef[k] /= sum
+	}
+	return coef
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser evaluates the Kaiser window at x in [-1, 1]
+func kaiser(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return besselI0(kaiserBeta*math.Sqrt(1-x*x)) / besselI0(kaiserBeta)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > 1e-12*sum; k++ {
+		half := x / (2 * float64(k))
+		term *= half * half
+		sum += term
+	}
+	return sum
+}
+
+func clamp16(v float64) int16 {
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,122 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// tone returns n samples of a sine tone at rate, starting at sample offset
+func tone(rate, n, offset int, freq float64, amplitude int16) []int16 {
+	samples := make([]int16, n)
+	for i := range samples {
+		samples[i] = int16(float64(amplitude) * math.Sin(2*math.Pi*freq*float64(offset+i)/float64(rate)))
+	}
+	return samples
+}
+
+func TestResamplerLength(t *testing.T) {
+	tests := []struct {
+		inRate, outRate int
+	}{
+		{16000, 24000},
+		{48000, 16000},
+		{16000, 16000},
+		{44100, 16000},
+		{8000, 48000},
+	}
+
+	for _, tt := range tests {
+		r, err := NewResampler(tt.inRate, tt.outRate, 1)
+		if err != nil {
+			t.Fatalf("NewResampler(%d, %d): %v", tt.inRate, tt.outRate, err)
+		}
+
+		// One second in 10ms frames
+		frame := tt.inRate / 100
+		var out int
+		for i := 0; i < 100; i++ {
+			out += len(r.Process(tone(tt.inRate, frame, i*frame, 440, 8000)))
+		}
+		out += len(r.Flush())
+
+		if out != tt.outRate {
+			t.Errorf("%d -> %d: got %d samples for one second, want %d", tt.inRate, tt.outRate, out, tt.outRate)
+		}
+	}
+}
+
+func TestResamplerFrameContinuity(t *testing.T) {
+	const inRate, outRate = 16000, 24000
+	input := tone(inRate, inRate/2, 0, 300, 12000)
+
+	whole, _ := NewResampler(inRate, outRate, 1)
+	want := append(whole.Process(input), whole.Flush()...)
+
+	framed, _ := NewResampler(inRate, outRate, 1)
+	var got []int16
+	for frame := inRate / 100; len(input) > 0; input = input[frame:] {
+		got = append(got, framed.Process(input[:frame])...)
+	}
+	got = append(got, framed.Flush()...)
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples in 10ms frames, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sample %d: got %d in 10ms frames, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResamplerFlushResets(t *testing.T) {
+	r, _ := NewResampler(16000, 24000, 1)
+	input := tone(16000, 160, 0, 440, 8000)
+
+	first := append(r.Process(input), r.Flush()...)
+	second := append(r.Process(input), r.Flush()...)
+
+	if len(first) != len(second) {
+		t.Fatalf("second stream has %d samples, want %d", len(second), len(first))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("sample %d of the second stream is %d, want %d", i, second[i], first[i])
+		}
+	}
+	if tail := r.Flush(); len(tail) != 0 {
+		t.Errorf("Flush without input returned %d samples", len(tail))
+	}
+}
+
+func TestResamplerStereo(t *testing.T) {
+	r, _ := NewResampler(48000, 16000, 2)
+	in := make([]int16, 480*2)
+	for i := 0; i < 480; i++ {
+		in[2*i] = 1000
+		in[2*i+1] = -1000
+	}
+
+	// Well past the filter's start, both channels settle on their DC level
+	var out []int16
+	for i := 0; i < 10; i++ {
+		out = append(out, r.Process(in)...)
+	}
+	if len(out)%2 != 0 {
+		t.Fatalf("got %d samples, not whole stereo frames", len(out))
+	}
+	for i := len(out) / 4 * 2; i < len(out); i += 2 {
+		if out[i] != 1000 || out[i+1] != -1000 {
+			t.Fatalf("frame %d: got (%d, %d), want (1000, -1000)", i/2, out[i], out[i+1])
+		}
+	}
+}
+
+func TestNewResamplerInvalid(t *testing.T) {
+	if _, err := NewResampler(0, 16000, 1); err == nil {
+		t.Error("NewResampler accepted an input rate of 0")
+	}
+	if _, err := NewResampler(16000, 16000, 0); err == nil {
+		t.Error("NewResampler accepted 0 channels")
+	}
+}
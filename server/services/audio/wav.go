@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReadWAV reads a PCM16 WAV file, such as an audio capture, and returns its
+// interleaved little-endian samples
+func ReadWAV(r io.Reader) (pcm []byte, sampleRate, channels int, err error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, 0, 0, err
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, 0, 0, errors.New("not a WAV file")
+	}
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if err == io.EOF {
+				err = errors.New("WAV file has no data chunk")
+			}
+			return nil, 0, 0, err
+		}
+		id, size := string(chunk[0:4]), int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, 0, 0, err
+			}
+			if size < 16 {
+				return nil, 0, 0, errors.New("short WAV fmt chunk")
+			}
+			format, bits := binary.LittleEndian.Uint16(body[0:2]), binary.LittleEndian.Uint16(body[14:16])
+			if format != 1 || bits != 16 {
+				return nil, 0, 0, fmt.Errorf("unsupported WAV format %d with %d bits (expected PCM16)", format, bits)
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+		case "data":
+			if sampleRate == 0 {
+				return nil, 0, 0, errors.New("WAV data chunk before fmt chunk")
+			}
+			// The header of a capture cut short by a crash counts less than
+			// the file holds, so the data runs to the end of the file
+			pcm, err = io.ReadAll(r)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			return pcm[:len(pcm)-len(pcm)%2], sampleRate, channels, nil
+		default:
+			// Chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, 0, 0, err
+			}
+		}
+	}
+}
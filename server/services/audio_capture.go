@@ -0,0 +1,344 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	agoraservice "github.com/AgoraIO-Extensions/Agora-Golang-Server-SDK/v2/go_sdk/rtc"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Audio capture defaults, overridable through config
+const (
+	DefaultAudioCaptureDir         = "./audio_captures"
+	DefaultAudioCaptureMaxBytes    = 50 << 20 // Per file
+	DefaultAudioCaptureMaxDuration = 30 * time.Minute
+	DefaultAudioCaptureMaxAge      = 24 * time.Hour
+	audioCaptureSweepInterval      = 10 * time.Minute
+	audioCaptureExtension          = ".wav"
+	wavHeaderSize                  = 44
+)
+
+// Audio capture kinds; each captured session writes one file of each
+const (
+	AudioCaptureInput = "input" // Raw audio of the Palabra UID
+	AudioCaptureAnam  = "anam"  // Audio sent to Anam after VAD and resampling
+)
+
+// ErrAudioCaptureNotFound is returned for unknown audio capture names
+var ErrAudioCaptureNotFound = errors.New("audio capture not found")
+
+// wavCapture writes PCM16 to a WAV file until it reaches its size limit
+type wavCapture struct {
+	path        string
+	file        *os.File
+	sampleRate  int
+	channels    int
+	limit       int64 // Max data bytes
+	written     int64 // Data bytes written
+	headerAt    int64 // Data bytes the header was last written for
+	bytesPerSec int64
+}
+
+func createWAVCapture(path string, sampleRate, channels int, limit int64) (*wavCapture, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	w := &wavCapture{
+		path:        path,
+		file:        file,
+		sampleRate:  sampleRate,
+		channels:    channels,
+		limit:       limit,
+		bytesPerSec: int64(sampleRate * channels * 2),
+	}
+	if err := w.writeHeader(); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return w, nil
+}
+
+// writeHeader (re)writes the WAV header for the data written so far
+func (w *wavCapture) writeHeader() error {
+	w.headerAt = w.written
+	_, err := w.file.WriteAt(agoraservice.GenerateWAVHeader(w.sampleRate, w.channels, int(w.written)), 0)
+	return err
+}
+
+// write appends PCM and reports whether the file is full
+func (w *wavCapture) write(pcm []byte) (full bool, err error) {
+	if remaining := w.limit - w.written; int64(len(pcm)) > remaining {
+		pcm = pcm[:remaining-remaining%2]
+		full = true
+	}
+	if _, err := w.file.WriteAt(pcm, wavHeaderSize+w.written); err != nil {
+		return true, err
+	}
+	w.written += int64(len(pcm))
+
+	// Keep the header current about once a second, so a crash leaves a playable file
+	if full || w.written-w.headerAt >= w.bytesPerSec {
+		err = w.writeHeader()
+	}
+	return full, err
+}
+
+func (w *wavCapture) close() error {
+	err := w.writeHeader()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// audioCapture records the audio of one session to WAV files. A nil
+// audioCapture records nothing.
+type audioCapture struct {
+	mu    sync.Mutex
+	files map[string]*wavCapture // By kind; removed once full
+	logf  LogFunc
+}
+
+// newAudioCapture creates the capture files of a session in config.Dir
+func newAudioCapture(config *ipc.AudioCapture, taskID string, inputRate, anamRate int, logf LogFunc) (*audioCapture, error) {
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("%s-%s-", time.Now().UTC().Format(crashBundleTimeFormat), sanitizeFileName(taskID))
+	rates := map[string]int{AudioCaptureInput: inputRate, AudioCaptureAnam: anamRate}
+
+	c := &audioCapture{files: make(map[string]*wavCapture), logf: logf}
+	for _, kind := range []string{AudioCaptureInput, AudioCaptureAnam} {
+		limit := int64(config.MaxBytes)
+		if config.MaxDuration > 0 {
+			limit = min(limit, int64(config.MaxDuration/time.Second)*int64(rates[kind])*2)
+		}
+
+		file, err := createWAVCapture(filepath.Join(config.Dir, prefix+kind+audioCaptureExtension), rates[kind], 1, limit)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		c.files[kind] = file
+		logf(botipc.LogLevelINFO, "Capturing %s audio to %s", kind, file.path)
+	}
+	return c, nil
+}
+
+// Input records a frame of the Palabra UID's audio
+func (c *audioCapture) Input(pcm []byte) {
+	c.write(AudioCaptureInput, pcm)
+}
+
+// Anam records audio sent to Anam
+func (c *audioCapture) Anam(pcm []byte) {
+	c.write(AudioCaptureAnam, pcm)
+}
+
+func (c *audioCapture) write(kind string, pcm []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file := c.files[kind]
+	if file == nil {
+		return
+	}
+	full, err := file.write(pcm)
+	if err != nil {
+		c.logf(botipc.LogLevelWARN, "Stopped %s audio capture: %v", kind, err)
+	} else if full {
+		c.logf(botipc.LogLevelINFO, "Stopped %s audio capture at its limit (%d bytes)", kind, file.written)
+	}
+	if full {
+		file.close()
+		delete(c.files, kind)
+	}
+}
+
+// Close finishes the capture files
+func (c *audioCapture) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for kind, file := range c.files {
+		if err := file.close(); err != nil {
+			c.logf(botipc.LogLevelWARN, "Failed to finish %s audio capture: %v", kind, err)
+		}
+	}
+	c.files = nil
+}
+
+// sanitizeFileName keeps a task ID usable in a file name
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// AudioCaptureInfo describes an audio capture file
+type AudioCaptureInfo struct {
+	Name       string    `json:"name"`
+	Time       time.Time `json:"time"`
+	TaskID     string    `json:"taskId"`
+	Kind       string    `json:"kind"`
+	SampleRate int       `json:"sampleRate"`
+	DurationMs int64     `json:"durationMs"`
+	SizeBytes  int64     `json:"sizeBytes"`
+}
+
+// parseAudioCaptureName splits <time>-<task>-<kind>.wav
+func parseAudioCaptureName(name string) (info AudioCaptureInfo, ok bool) {
+	base, found := strings.CutSuffix(name, audioCaptureExtension)
+	if !found {
+		return info, false
+	}
+	stamp, rest, found := strings.Cut(base, "-")
+	if !found {
+		return info, false
+	}
+	created, err := time.Parse(crashBundleTimeFormat, stamp)
+	if err != nil {
+		return info, false
+	}
+	sep := strings.LastIndex(rest, "-")
+	if sep <= 0 {
+		return info, false
+	}
+	info = AudioCaptureInfo{Name: name, Time: created, TaskID: rest[:sep], Kind: rest[sep+1:]}
+	return info, info.Kind == AudioCaptureInput || info.Kind == AudioCaptureAnam
+}
+
+// sessionAudioCapture returns the capture settings sent with a session that
+// asked for audio capture
+func (m *BotProcessManager) sessionAudioCapture() *ipc.AudioCapture {
+	capture := m.audioCapture
+	return &capture
+}
+
+// sweepAudioCaptures removes expired audio captures until shutdown
+func (m *BotProcessManager) sweepAudioCaptures() {
+	ticker := time.NewTicker(audioCaptureSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		m.pruneAudioCaptures()
+		select {
+		case <-m.shutdownChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneAudioCaptures removes captures not written to for longer than the max age
+func (m *BotProcessManager) pruneAudioCaptures() {
+	entries, err := os.ReadDir(m.audioCapture.Dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Error().Err(err).Msg("Failed to list audio captures")
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if _, ok := parseAudioCaptureName(entry.Name()); !ok {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil || time.Since(fi.ModTime()) <= m.audioCaptureMaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.audioCapture.Dir, entry.Name())); err != nil {
+			m.logger.Error().Err(err).Str("capture", entry.Name()).Msg("Failed to remove audio capture")
+		}
+	}
+}
+
+// ListAudioCaptures returns the audio captures written by local workers, newest first
+func (m *BotProcessManager) ListAudioCaptures() ([]AudioCaptureInfo, error) {
+	entries, err := os.ReadDir(m.audioCapture.Dir)
+	if os.IsNotExist(err) {
+		return []AudioCaptureInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	captures := make([]AudioCaptureInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, ok := parseAudioCaptureName(entry.Name())
+		if !ok {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		info.SizeBytes = fi.Size()
+
+		// The sample rate comes from the WAV header
+		if header, err := readWAVHeader(filepath.Join(m.audioCapture.Dir, entry.Name())); err == nil {
+			info.SampleRate = int(binary.LittleEndian.Uint32(header[24:28]))
+			if byteRate := int64(binary.LittleEndian.Uint32(header[28:32])); byteRate > 0 {
+				info.DurationMs = max(info.SizeBytes-wavHeaderSize, 0) * 1000 / byteRate
+			}
+		}
+		captures = append(captures, info)
+	}
+
+	// Names start with a UTC timestamp
+	sort.Slice(captures, func(i, j int) bool { return captures[i].Name > captures[j].Name })
+	return captures, nil
+}
+
+func readWAVHeader(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, wavHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// OpenAudioCapture opens an audio capture for download
+func (m *BotProcessManager) OpenAudioCapture(name string) (*os.File, error) {
+	if name != filepath.Base(name) {
+		return nil, ErrAudioCaptureNotFound
+	}
+	if _, ok := parseAudioCaptureName(name); !ok {
+		return nil, ErrAudioCaptureNotFound
+	}
+	file, err := os.Open(filepath.Join(m.audioCapture.Dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrAudioCaptureNotFound
+	}
+	return file, err
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Modes of a translation stream, telling clients which UID carries it
+const (
+	StreamModeConnecting = "connecting" // The avatar is starting; uid is the Anam UID, placeholderUid shows meanwhile
+	StreamModeAvatar     = "avatar"     // The avatar is streaming as uid
+	StreamModeAudio      = "audio"      // No avatar; uid is Palabra's audio-only translation UID
+)
+
+// Reasons a stream fell back to Palabra's audio, besides the fatal error
+// codes of the bot session
+const (
+	fallbackStartFailed  = "START_FAILED"  // The bot session could not be started
+	fallbackSessionEnded = "SESSION_ENDED" // The bot session ended (idle, target left, timeout, ...)
+)
+
+// Reasons a stream lost its placeholder video other than the avatar's video
+// arriving
+const (
+	placeholderFailed  = "PLACEHOLDER_FAILED"  // The placeholder could not be published
+	placeholderTimeout = "PLACEHOLDER_TIMEOUT" // The avatar showed no video in time
+)
+
+// Updates queued for a slow client before it is disconnected. It gets the
+// current modes again when it reconnects.
+const streamUpdateBuffer = 32
+
+// StreamUpdate tells the clients of a channel which UID a translation stream
+// plays from
+type StreamUpdate struct {
+	TaskID         string `json:"taskId"`
+	Channel        string `json:"channel"`
+	Language       string `json:"language"`
+	UID            string `json:"uid"`                      // UID to subscribe to
+	FallbackUID    string `json:"fallbackUid,omitempty"`    // Palabra UID to switch to if the avatar fails
+	PlaceholderUID string `json:"placeholderUid,omitempty"` // UID showing a placeholder video while connecting
+	Mode           string `json:"mode"`
+	Reason         string `json:"reason,omitempty"` // Why the stream fell back to audio or lost its placeholder
+}
+
+// avatarStream is a translation stream whose avatar is played by a bot session
+type avatarStream struct {
+	taskID    string // Palabra task
+	channel   string
+	streams   []PalabraStreamInfo // The task's streams, shared with activeTasksByKey
+	index     int
+	streaming bool // The bot session is streaming
+}
+
+// avatarStreamRegistry tracks whether the avatar of each translation stream
+// is ready, and falls the stream back to Palabra's UID when its bot session
+// fails or ends. Mode changes are pushed to the clients of the channel.
+type avatarStreamRegistry struct {
+	mu          sync.Mutex
+	sessions    map[string]*avatarStream     // Bot session task ID -> stream
+	subscribers map[chan StreamUpdate]string // Client -> channel
+}
+
+var avatarStreams = &avatarStreamRegistry{
+	sessions:    make(map[string]*avatarStream),
+	subscribers: make(map[chan StreamUpdate]string),
+}
+
+// track starts following the bot session playing streams[index] of a task.
+// The stream must already carry the Anam UID, its fallback UID and its
+// placeholder UID, if any.
+func (r *avatarStreamRegistry) track(sessionID, taskID, channel string, streams []PalabraStreamInfo, index int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[sessionID] = &avatarStream{taskID: taskID, channel: channel, streams: streams, index: index}
+}
+
+// forgetTask stops following the sessions of a task, so stopping it does not
+// push fallbacks
+func (r *avatarStreamRegistry) forgetTask(taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sessionID, stream := range r.sessions {
+		if stream.taskID == taskID {
+			delete(r.sessions, sessionID)
+		}
+	}
+}
+
+// setMode changes the mode of the stream played by a bot session. Falling
+// back to audio switches the stream to its Palabra UID for good.
+func (r *avatarStreamRegistry) setMode(sessionID, mode, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stream, ok := r.sessions[sessionID]
+	if !ok {
+		return
+	}
+	info := &stream.streams[stream.index]
+	if info.Mode == mode || info.Mode == StreamModeAudio {
+		return
+	}
+	info.Mode = mode
+	info.PlaceholderUID = ""
+	if mode == StreamModeAudio {
+		info.UID = info.FallbackUID
+	}
+	r.push(stream, reason)
+}
+
+// avatarVideo follows the placeholder videos of bot sessions. A stream with
+// a placeholder is ready when the avatar's first frames arrive rather than
+// when its session streams.
+func (r *avatarStreamRegistry) avatarVideo(proc *BotProcess, event ipc.AvatarVideoEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stream, ok := r.sessions[proc.TaskID]
+	if !ok {
+		return
+	}
+	info := &stream.streams[stream.index]
+	if info.Mode != StreamModeConnecting || info.PlaceholderUID == "" {
+		return
+	}
+
+	var reason string
+	switch event.Event {
+	case ipc.AvatarVideoReady:
+		info.Mode = StreamModeAvatar
+	case ipc.AvatarVideoTimeout:
+		info.Mode = StreamModeAvatar
+		reason = placeholderTimeout
+	case ipc.AvatarVideoPlaceholderFailed:
+		if stream.streaming {
+			info.Mode = StreamModeAvatar
+		}
+		reason = placeholderFailed
+	default:
+		return
+	}
+	info.PlaceholderUID = ""
+	r.push(stream, reason)
+}
+
+// streaming records that a bot session streams. Streams without a
+// placeholder are ready from then on.
+func (r *avatarStreamRegistry) streaming(sessionID string) {
+	r.mu.Lock()
+	stream, ok := r.sessions[sessionID]
+	if ok {
+		stream.streaming = true
+		ok = stream.streams[stream.index].PlaceholderUID == ""
+	}
+	r.mu.Unlock()
+
+	if ok {
+		r.setMode(sessionID, StreamModeAvatar, "")
+	}
+}
+
+// push sends the current mode of a stream to the clients of its channel.
+// Must be called with r.mu held.
+func (r *avatarStreamRegistry) push(stream *avatarStream, reason string) {
+	update := stream.update()
+	update.Reason = reason
+	for ch, channel := range r.subscribers {
+		if channel != stream.channel {
+			continue
+		}
+		select {
+		case ch <- update:
+		default:
+			// Too far behind: drop the client rather than an update
+			delete(r.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// update describes the stream's current mode
+func (s *avatarStream) update() StreamUpdate {
+	info := s.streams[s.index]
+	return StreamUpdate{
+		TaskID:         s.taskID,
+		Channel:        s.channel,
+		Language:       info.Language,
+		UID:            info.UID,
+		FallbackUID:    info.FallbackUID,
+		PlaceholderUID: info.PlaceholderUID,
+		Mode:           info.Mode,
+	}
+}
+
+// snapshot copies the streams of a task, which the registry may change
+func (r *avatarStreamRegistry) snapshot(streams []PalabraStreamInfo) []PalabraStreamInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PalabraStreamInfo(nil), streams...)
+}
+
+// subscribe registers a client for the mode changes of a channel and returns
+// the current modes of the channel's avatar streams. The updates channel is
+// closed if the client falls behind.
+func (r *avatarStreamRegistry) subscribe(channel string) (chan StreamUpdate, []StreamUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updates := make(chan StreamUpdate, streamUpdateBuffer)
+	r.subscribers[updates] = channel
+
+	var current []StreamUpdate
+	for _, stream := range r.sessions {
+		if stream.channel == channel {
+			current = append(current, stream.update())
+		}
+	}
+	return updates, current
+}
+
+// unsubscribe removes a client registered with subscribe
+func (r *avatarStreamRegistry) unsubscribe(updates chan StreamUpdate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscribers[updates]; ok {
+		delete(r.subscribers, updates)
+		close(updates)
+	}
+}
+
+// sessionTransition follows the status of bot sessions: the avatar is ready
+// once its session streams (or its placeholder hands over, see avatarVideo),
+// and gone once the session fails or ends
+func (r *avatarStreamRegistry) sessionTransition(proc *BotProcess, to botipc.SessionStatus, errorCode string) {
+	switch to {
+	case botipc.SessionStatusSTREAMING:
+		r.streaming(proc.TaskID)
+	case botipc.SessionStatusFAILED:
+		r.setMode(proc.TaskID, StreamModeAudio, errorCode)
+	case botipc.SessionStatusDISCONNECTED:
+		r.setMode(proc.TaskID, StreamModeAudio, fallbackSessionEnded)
+	}
+}
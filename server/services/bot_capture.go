@@ -0,0 +1,35 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+)
+
+// openCapture tees a worker connection's IPC traffic into a new capture file
+// in PALABRA_IPC_CAPTURE_DIR, named after the time and the worker. It returns
+// nil (which records nothing) when capturing is disabled or fails.
+func (m *BotProcessManager) openCapture(name string, reader *ipc.MessageReader, writer *ipc.MessageWriter, logger zerolog.Logger) *ipc.CaptureWriter {
+	if m.captureDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.captureDir, 0o700); err != nil {
+		logger.Error().Err(err).Msg("Failed to create IPC capture directory")
+		return nil
+	}
+
+	path := filepath.Join(m.captureDir, time.Now().UTC().Format(crashBundleTimeFormat)+"-"+name+ipc.CaptureExtension)
+	capture, err := ipc.CreateCapture(path)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create IPC capture")
+		return nil
+	}
+
+	reader.Tee(capture, ipc.FromWorker)
+	writer.Tee(capture, ipc.ToWorker)
+	logger.Info().Str("capture", path).Msg("Capturing IPC traffic")
+	return capture
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Time a newly connected node has to send HELLO and REGISTER_NODE
+const nodeRegisterTimeout = 10 * time.Second
+
+// startNodeListener accepts connections from bot_worker daemons running on
+// other hosts. Nodes receive Agora and Anam credentials, so a listener
+// reachable over the network requires the node token; only a Unix socket
+// may go without. tlsConfig carries the certificate of tls:// addresses.
+func (m *BotProcessManager) startNodeListener(addr string, tlsConfig *tls.Config) error {
+	network, _, err := ipc.ParseAddress(addr)
+	if err != nil {
+		return err
+	}
+	if network != ipc.SchemeUnix && m.nodeToken == "" {
+		return fmt.Errorf("PALABRA_BOT_NODE_TOKEN must be set to accept nodes on %s", addr)
+	}
+
+	listener, err := ipc.Listen(addr, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	m.nodeListener = listener
+
+	m.logger.Info().Str("addr", addr).Msg("Accepting bot_worker nodes")
+	switch {
+	case m.nodeToken == "":
+		m.logger.Warn().Msgf("PALABRA_BOT_NODE_TOKEN is not set - any process that can open %s may register", addr)
+	case network == ipc.SchemeTCP:
+		m.logger.Warn().Msg("Nodes connect over plain TCP - the node token and session credentials travel unencrypted, use tls:// outside a private network")
+	}
+
+	go m.acceptNodes(listener)
+	return nil
+}
+
+// acceptNodes runs the accept loop until the listener is closed
+func (m *BotProcessManager) acceptNodes(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			m.logger.Error().Err(err).Msg("Error accepting node connection")
+			time.Sleep(time.Second)
+			continue
+		}
+		go m.registerNode(conn)
+	}
+}
+
+// registerNode performs the HELLO and REGISTER_NODE handshake and adds the node
+// to the scheduling pool. Nodes speaking another protocol version are refused.
+// A node re-registering under the same ID replaces its previous connection.
+func (m *BotProcessManager) registerNode(conn net.Conn) {
+	remote := conn.RemoteAddr().String()
+	logger := m.logger.With().Str("remote", remote).Logger()
+	reader := ipc.NewMessageReader(conn)
+	writer := ipc.NewMessageWriter(conn)
+	capture := m.openCapture("node-"+strings.ReplaceAll(remote, ":", "_"), reader, writer, logger)
+	registered := false
+	defer func() {
+		if !registered {
+			capture.Close()
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(nodeRegisterTimeout))
+	hello, err := ipc.ReadHello(reader)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Rejecting node: incompatible bot_worker")
+		botWorkerHandshakeFailures.WithLabelValues(workerKindRemote).Inc()
+		conn.Close()
+		return
+	}
+	msgBytes, err := reader.ReadMessage()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Node did not register")
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	msgType, payloadBytes, err := ipc.ParseIPCMessage(msgBytes)
+	if err != nil || msgType != botipc.MessageTypeREGISTER_NODE {
+		logger.Warn().Msgf("Rejecting node: expected REGISTER_NODE, got %s", msgType)
+		conn.Close()
+		return
+	}
+
+	payload := ipc.ParseNodeRegisterPayload(payloadBytes)
+	nodeID := string(payload.NodeId())
+	if nodeID == "" || payload.Capacity() == 0 {
+		logger.Warn().Msg("Rejecting node: missing node ID or zero capacity")
+		conn.Close()
+		return
+	}
+	if m.nodeToken != "" && subtle.ConstantTimeCompare(payload.Token(), []byte(m.nodeToken)) != 1 {
+		logger.Warn().Str("node", nodeID).Msg("Rejecting node: invalid token")
+		conn.Close()
+		return
+	}
+
+	node := &workerProcess{
+		writer:        writer,
+		reader:        reader,
+		requests:      ipc.NewRequests(),
+		capture:       capture,
+		maxSessions:   int(payload.Capacity()),
+		sessions:      make(map[string]*BotProcess),
+		exited:        make(chan struct{}),
+		conn:          conn,
+		nodeID:        nodeID,
+		hostname:      string(payload.Hostname()),
+		lastHeartbeat: time.Now(),
+		logger:        m.logger.With().Str("node", nodeID).Str("build", hello.BuildID).Logger(),
+		hello:         hello,
+	}
+	if err := node.writer.WriteMessage(ipc.BuildHelloMessage(ipc.LocalHello())); err != nil {
+		node.logger.Warn().Err(err).Msg("Failed to send HELLO to node")
+		conn.Close()
+		return
+	}
+
+	m.mu.Lock()
+	previous := m.nodes[nodeID]
+	m.nodes[nodeID] = node
+	m.mu.Unlock()
+	registered = true
+
+	if previous != nil {
+		// Reconnected before the old connection was noticed as dead
+		node.logger.Info().Msg("Node re-registered, dropping its previous connection")
+		previous.conn.Close()
+	}
+
+	node.logger.Info().
+		Str("remote", remote).
+		Str("host", node.hostname).
+		Int("capacity", node.maxSessions).
+		Msg("Node registered")
+	botWorkerStarts.WithLabelValues(workerKindRemote).Inc()
+
+	go m.handleChildMessages(node)
+	go m.monitorNode(node)
+}
+
+// monitorNode drops a node that stops sending heartbeats. Closing the
+// connection makes handleChildMessages fail the sessions it hosted.
+func (m *BotProcessManager) monitorNode(node *workerProcess) {
+	ticker := time.NewTicker(ipc.NodeHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-node.exited:
+			return
+		case <-ticker.C:
+			m.mu.RLock()
+			silence := time.Since(node.lastHeartbeat)
+			m.mu.RUnlock()
+
+			if silence > ipc.NodeHeartbeatTimeout {
+				node.logger.Warn().Msgf("Node sent no heartbeat for %v, dropping it", silence.Round(time.Second))
+				node.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// pickNode returns the registered node with the lowest relative load that
+// still has room for need sessions, or nil if there is none.
+// Must be called with m.mu held.
+func (m *BotProcessManager) pickNode(need int) *workerProcess {
+	var best *workerProcess
+	var bestLoad float64
+
+	for _, node := range m.nodes {
+		// The node may still be tearing down sessions the parent already forgot
+		active := max(len(node.sessions), node.reportedSessions)
+		if node.retiring || active+need > node.maxSessions {
+			continue
+		}
+		load := float64(active) / float64(node.maxSessions)
+		if best == nil || load < bestLoad {
+			best, bestLoad = node, load
+		}
+	}
+
+	return best
+}
@@ -2,46 +2,197 @@ package services
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
 	"github.com/samyak-jain/agora_backend/services/ipc"
 	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+	"github.com/samyak-jain/agora_backend/utils"
 	"github.com/spf13/viper"
 )
 
 // Default session timeout in minutes
 const DefaultSessionTimeoutMinutes = 10
 
-// BotProcess represents a running child process
+// How long the worker has to answer START_SESSION (connect to Anam and Agora)
+// and STOP_SESSION
+const (
+	sessionConnectTimeout = 30 * time.Second
+	sessionStopTimeout    = 5 * time.Second
+)
+
+// Default number of sessions a bot_worker may host when processes are shared
+const DefaultSessionsPerProcess = 4
+
+// Placement policies deciding how sessions are packed into bot_worker processes.
+// They trade crash isolation (one SDK segfault takes down every session in the
+// process) against the cost of loading the Agora SDK once per process.
+const (
+	PlacementPerProcess = "process" // One session per bot_worker (default)
+	PlacementPerChannel = "channel" // Sessions of the same channel share a bot_worker
+	PlacementShared     = "shared"  // Up to N sessions of any channel share a bot_worker
+)
+
+// BotProcess represents a bot session hosted by a bot_worker child process
 type BotProcess struct {
-	cmd          *exec.Cmd
-	stdin        io.WriteCloser
-	stdout       io.ReadCloser
-	stderr       io.ReadCloser
-	stdinWriter  *ipc.MessageWriter
+	worker       *workerProcess
 	TaskID       string
-	Status       botipc.SessionStatus
+	Channel      string
+	Language     string
+	config       StartSessionConfig   // Guarded by mu once the session is placed
+	Status       botipc.SessionStatus // Changed only through transition (guarded by mu)
 	AnamUID      uint32
 	StartTime    time.Time
+	metrics      ipc.SessionMetrics  // Latest counters reported by the worker (guarded by mu)
+	metricsAt    time.Time           // When metrics were received, zero before the first report (guarded by mu)
+	history      []SessionTransition // Status transitions, oldest first (guarded by mu)
+	ended        bool                // Removed from the active sessions (guarded by mu)
+	logs         *sessionLog         // Recent log lines of the session
+	logger       zerolog.Logger      // Manager messages about the session, also copied to logs
+	outputLogger zerolog.Logger      // Child output of the session (copied to logs explicitly)
 	mu           sync.RWMutex
 	shutdownChan chan struct{}
 	timeoutTimer *time.Timer
+	stopping     bool // Set when the parent requested the stop (guarded by manager mu)
+	onTransition SessionTransitionFunc
+}
+
+// SessionTransitionFunc is called after a session moved to another status
+type SessionTransitionFunc func(proc *BotProcess, to botipc.SessionStatus, errorCode string)
+
+// AvatarVideoFunc is called when the placeholder video of a session starts,
+// fails or hands over to the avatar
+type AvatarVideoFunc func(proc *BotProcess, event ipc.AvatarVideoEvent)
+
+// CaptionFunc is called with each caption a session relays
+type CaptionFunc func(proc *BotProcess, caption ipc.Caption)
+
+// Pid returns the OS process ID of the bot_worker hosting this session,
+// or 0 when it runs on a remote node
+func (p *BotProcess) Pid() int {
+	return p.worker.pid
+}
+
+// Node returns the ID of the remote node hosting this session, or "" when it
+// runs in a local bot_worker
+func (p *BotProcess) Node() string {
+	return p.worker.nodeID
+}
+
+// workerProcess represents a running bot_worker, either a local process
+// listening on a Unix socket in the runtime directory or a remote daemon node
+// connected over the network (nodeID set)
+type workerProcess struct {
+	conn        net.Conn
+	writer      *ipc.MessageWriter
+	reader      *ipc.MessageReader
+	channel     string                 // Channel pinned to this worker (per-channel placement only)
+	maxSessions int                    // Session cap passed to the child or advertised by the node
+	sessions    map[string]*BotProcess // taskID -> session (guarded by manager mu)
+	reserved    int                    // Sessions waiting for the worker to start (guarded by manager mu)
+	retiring    bool                   // Set once the parent asked the worker to exit (guarded by manager mu)
+	exited      chan struct{}          // Closed when the process has exited or the node is lost
+	logger      zerolog.Logger         // Tagged with the PID or node ID
+	hello       ipc.Hello              // Version and capabilities from the worker's HELLO
+	requests    *ipc.Requests          // Commands awaiting ACK/NACK
+	capture     *ipc.CaptureWriter     // IPC traffic recording (nil unless PALABRA_IPC_CAPTURE_DIR is set)
+
+	// Local workers only
+	pid        int
+	process    *os.Process // Signalled to retire the worker
+	socketPath string
+
+	// Local workers spawned by this server, kept for crash bundles. Workers
+	// reattached after a restart are not our children and have none of these.
+	starting   chan struct{} // Closed once the handshake completed or failed
+	startErr   error         // Why the worker failed to start, set before starting is closed
+	cmd        *exec.Cmd
+	stderr     io.ReadCloser
+	stderrTail *lineRing      // Recent stderr lines
+	logTail    *lineRing      // Recent LOG_MESSAGE lines
+	readers    sync.WaitGroup // Done once the socket and stderr reached EOF
+
+	// Remote nodes only (guarded by manager mu)
+	nodeID           string
+	hostname         string
+	lastHeartbeat    time.Time
+	reportedSessions int // Active sessions from the node's last heartbeat
+}
+
+// kind returns the worker kind label used in metrics
+func (w *workerProcess) kind() string {
+	if w.nodeID != "" {
+		return workerKindRemote
+	}
+	return workerKindLocal
+}
+
+// ready reports whether the worker finished starting. Adopted workers and
+// remote nodes are connected before they are known.
+func (w *workerProcess) ready() bool {
+	if w.starting == nil {
+		return true
+	}
+	select {
+	case <-w.starting:
+		return true
+	default:
+		return false
+	}
+}
+
+// load returns the sessions hosted by the worker or waiting for it to start.
+// Must be called with manager mu held.
+func (w *workerProcess) load() int {
+	return len(w.sessions) + w.reserved
+}
+
+// label identifies the worker in log messages
+func (w *workerProcess) label() string {
+	if w.nodeID != "" {
+		return "node " + w.nodeID
+	}
+	return fmt.Sprintf("PID %d", w.pid)
 }
 
 // BotProcessManager manages child bot processes
 type BotProcessManager struct {
-	processes      map[string]*BotProcess // taskID -> process
-	mu             sync.RWMutex
-	logger         *log.Logger
-	workerPath     string        // Path to bot_worker binary
-	sessionTimeout time.Duration // Max session duration
-	shutdownChan   chan struct{}
+	processes          map[string]*BotProcess    // taskID -> session
+	workers            []*workerProcess          // Running or starting bot_worker processes
+	placing            map[string]bool           // Sessions waiting for their worker to start (taskID)
+	nodes              map[string]*workerProcess // nodeID -> registered remote node
+	finished           []*BotProcess             // Recently ended sessions, kept for their history
+	mu                 sync.RWMutex
+	logger             zerolog.Logger
+	workerPath         string                // Path to bot_worker binary
+	sessionTimeout     time.Duration         // Max session duration
+	placement          string                // Session placement policy
+	sessionsPerProcess int                   // Session cap for shared placements
+	runtimeDir         string                // Unix sockets of local bot_workers
+	captureDir         string                // Where IPC captures are written ("" disables capturing)
+	nodeListener       net.Listener          // Accepts remote bot_worker nodes (nil if disabled)
+	nodeToken          string                // Shared secret remote nodes must present
+	crashDir           string                // Where crash bundles of local workers are written
+	crashBundlesMax    int                   // Max crash bundles kept
+	crashBundleMaxAge  time.Duration         // Crash bundles older than this are removed
+	audioCapture       ipc.AudioCapture      // Where and how much audio captured sessions record
+	audioCaptureMaxAge time.Duration         // Audio captures older than this are removed
+	onTransition       SessionTransitionFunc // Told about the status changes of sessions (nil if unset)
+	onAvatarVideo      AvatarVideoFunc       // Told about the placeholder videos of sessions (nil if unset)
+	onCaption          CaptionFunc           // Told about the captions sessions relay (nil if unset)
+	shutdownChan       chan struct{}
 }
 
 // StartSessionConfig contains configuration for starting a bot session
@@ -58,6 +209,35 @@ type StartSessionConfig struct {
 	AnamUID        uint32
 	AnamToken      string
 	TargetLanguage string
+	VADMode        string        // Voice detector of the bot (see VADMode*), "" for the default
+	CaptureAudio   bool          // Record the session's audio to WAV files (see audio_capture.go)
+	ChunkMs        uint32        // Audio per message sent to Anam, 0 for the worker's default
+	Loudness       *ipc.Loudness // Normalize the audio sent to Anam (see loudness.go), nil to send it as received
+
+	// Publish a placeholder video until the avatar's first frames (see placeholder_video.go), nil to disable
+	Placeholder *ipc.PlaceholderVideo
+
+	// Sessions of the same fan-out group are placed on one bot_worker and
+	// share its Agora connection (see agora_connection.go), "" for their own
+	FanoutGroup string
+	FanoutSize  int // Sessions the group will have, reserved on the worker of its first
+
+	Captions bool // Relay the captions of the target's data stream (see captions.go)
+
+	// Task metadata the worker hands back after a server restart (see
+	// restoreTasks), "" for none
+	TaskState string
+}
+
+// secrets returns the fields of the config holding credentials. A field
+// added with a new secret must be listed here, so copies written to disk
+// (see redacted) mask it.
+func (c *StartSessionConfig) secrets() []*string {
+	secrets := []*string{&c.BotToken, &c.AnamAPIKey, &c.AnamToken}
+	if c.Placeholder != nil {
+		secrets = append(secrets, &c.Placeholder.Token)
+	}
+	return secrets
 }
 
 // Global instance (initialized once)
@@ -66,16 +246,31 @@ var (
 	globalBotManagerOnce sync.Once
 )
 
-// GetBotProcessManager returns the global BotProcessManager instance
-func GetBotProcessManager() *BotProcessManager {
+// GetBotProcessManager returns the global BotProcessManager instance. The
+// logger is only used by the first call, which creates the manager.
+func GetBotProcessManager(logger *utils.Logger) *BotProcessManager {
 	globalBotManagerOnce.Do(func() {
-		globalBotManager = NewBotProcessManager()
+		globalBotManager = NewBotProcessManager(logger)
+		prometheus.MustRegister(newBotSessionCollector(globalBotManager))
+		globalBotManager.OnSessionTransition(func(proc *BotProcess, to botipc.SessionStatus, errorCode string) {
+			avatarStreams.sessionTransition(proc, to, errorCode)
+			captionRelay.sessionTransition(proc, to, errorCode)
+		})
+		globalBotManager.OnAvatarVideo(avatarStreams.avatarVideo)
+		globalBotManager.OnCaption(captionRelay.caption)
+
+		// Adopt the workers of a previous server run once the callbacks are
+		// set, so their sessions report like new ones, then resume their tasks
+		globalBotManager.reattachWorkers()
+		restoreTasks(globalBotManager.GetAllSessions())
 	})
 	return globalBotManager
 }
 
-// NewBotProcessManager creates a new BotProcessManager
-func NewBotProcessManager() *BotProcessManager {
+// NewBotProcessManager creates a new BotProcessManager. It does not adopt the
+// workers of a previous server run; GetBotProcessManager does once the
+// callbacks are set (see reattachWorkers).
+func NewBotProcessManager(baseLogger *utils.Logger) *BotProcessManager {
 	// Look for bot_worker in same directory as server, or in PATH
 	workerPath := "./bot_worker"
 	if _, err := os.Stat(workerPath); os.IsNotExist(err) {
@@ -89,148 +284,621 @@ func NewBotProcessManager() *BotProcessManager {
 	}
 	sessionTimeout := time.Duration(timeoutMinutes) * time.Minute
 
-	logger := log.New(os.Stderr, "[BotProcessManager] ", log.LstdFlags|log.Lshortfile)
-	logger.Printf("Session timeout configured: %v", sessionTimeout)
+	// Read placement policy from config (default one session per process)
+	placement := viper.GetString("PALABRA_BOT_PLACEMENT")
+	switch placement {
+	case PlacementPerProcess, PlacementPerChannel, PlacementShared:
+	default:
+		placement = PlacementPerProcess
+	}
+	sessionsPerProcess := viper.GetInt("PALABRA_BOT_SESSIONS_PER_PROCESS")
+	if sessionsPerProcess <= 0 {
+		sessionsPerProcess = DefaultSessionsPerProcess
+	}
+
+	// Read the directory local workers listen in from config
+	runtimeDir := viper.GetString("PALABRA_BOT_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = DefaultRuntimeDir
+	}
+
+	// Read crash bundle location and retention from config
+	crashDir := viper.GetString("PALABRA_CRASH_DIR")
+	if crashDir == "" {
+		crashDir = DefaultCrashDir
+	}
+	crashBundlesMax := viper.GetInt("PALABRA_CRASH_BUNDLES_MAX")
+	if crashBundlesMax <= 0 {
+		crashBundlesMax = DefaultCrashBundlesMax
+	}
+	crashBundleMaxAge := time.Duration(viper.GetInt("PALABRA_CRASH_BUNDLE_MAX_AGE_HOURS")) * time.Hour
+	if crashBundleMaxAge <= 0 {
+		crashBundleMaxAge = DefaultCrashBundleMaxAge
+	}
+
+	// Read audio capture location, limits and retention from config
+	audioCaptureDir := viper.GetString("PALABRA_AUDIO_CAPTURE_DIR")
+	if audioCaptureDir == "" {
+		audioCaptureDir = DefaultAudioCaptureDir
+	}
+	// Local workers may run elsewhere than the server's working directory
+	if abs, err := filepath.Abs(audioCaptureDir); err == nil {
+		audioCaptureDir = abs
+	}
+	audioCaptureMaxBytes := uint64(viper.GetInt("PALABRA_AUDIO_CAPTURE_MAX_MB")) << 20
+	if audioCaptureMaxBytes == 0 {
+		audioCaptureMaxBytes = DefaultAudioCaptureMaxBytes
+	}
+	audioCaptureMaxDuration := time.Duration(viper.GetInt("PALABRA_AUDIO_CAPTURE_MAX_MINUTES")) * time.Minute
+	if audioCaptureMaxDuration <= 0 {
+		audioCaptureMaxDuration = DefaultAudioCaptureMaxDuration
+	}
+	audioCaptureMaxAge := time.Duration(viper.GetInt("PALABRA_AUDIO_CAPTURE_MAX_AGE_HOURS")) * time.Hour
+	if audioCaptureMaxAge <= 0 {
+		audioCaptureMaxAge = DefaultAudioCaptureMaxAge
+	}
+
+	logger := baseLogger.With().Str("component", "BotProcessManager").Logger()
+	logger.Info().Dur("sessionTimeout", sessionTimeout).Msg("Session timeout configured")
+	logger.Info().
+		Str("placement", placement).
+		Int("sessionsPerProcess", sessionsPerProcess).
+		Msg("Session placement configured")
+
+	m := &BotProcessManager{
+		processes:          make(map[string]*BotProcess),
+		placing:            make(map[string]bool),
+		nodes:              make(map[string]*workerProcess),
+		logger:             logger,
+		workerPath:         workerPath,
+		sessionTimeout:     sessionTimeout,
+		placement:          placement,
+		sessionsPerProcess: sessionsPerProcess,
+		runtimeDir:         runtimeDir,
+		captureDir:         viper.GetString("PALABRA_IPC_CAPTURE_DIR"),
+		nodeToken:          viper.GetString("PALABRA_BOT_NODE_TOKEN"),
+		crashDir:           crashDir,
+		crashBundlesMax:    crashBundlesMax,
+		crashBundleMaxAge:  crashBundleMaxAge,
+		audioCapture: ipc.AudioCapture{
+			Dir:         audioCaptureDir,
+			MaxBytes:    audioCaptureMaxBytes,
+			MaxDuration: audioCaptureMaxDuration,
+		},
+		audioCaptureMaxAge: audioCaptureMaxAge,
+		shutdownChan:       make(chan struct{}),
+	}
+	go m.sweepAudioCaptures()
+
+	// Optionally accept bot_worker daemons running on other hosts
+	if addr := viper.GetString("PALABRA_BOT_NODE_LISTEN"); addr != "" {
+		tlsConfig, err := ipc.ServerTLSConfig(viper.GetString("PALABRA_BOT_NODE_TLS_CERT"), viper.GetString("PALABRA_BOT_NODE_TLS_KEY"))
+		if err == nil {
+			err = m.startNodeListener(addr, tlsConfig)
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Remote nodes disabled")
+		}
+	}
+
+	return m
+}
 
-	return &BotProcessManager{
-		processes:      make(map[string]*BotProcess),
-		logger:         logger,
-		workerPath:     workerPath,
-		sessionTimeout: sessionTimeout,
-		shutdownChan:   make(chan struct{}),
+// OnSessionTransition sets the function told about the status changes of
+// sessions. Sessions keep the function set when they were added, so it must
+// be set before the first session is started or adopted. It is called
+// without locks held.
+func (m *BotProcessManager) OnSessionTransition(fn SessionTransitionFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onTransition = fn
+}
+
+// OnAvatarVideo sets the function told about the placeholder videos of
+// sessions. It is called without locks held.
+func (m *BotProcessManager) OnAvatarVideo(fn AvatarVideoFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onAvatarVideo = fn
+}
+
+// avatarVideo tells the OnAvatarVideo function about a placeholder video event
+func (m *BotProcessManager) avatarVideo(proc *BotProcess, event ipc.AvatarVideoEvent) {
+	m.mu.RLock()
+	fn := m.onAvatarVideo
+	m.mu.RUnlock()
+	if fn != nil {
+		fn(proc, event)
 	}
 }
 
-// StartSession spawns a new child process for a translation session
-func (m *BotProcessManager) StartSession(config StartSessionConfig) (*BotProcess, error) {
+// OnCaption sets the function told about the captions sessions relay. It
+// is called without locks held.
+func (m *BotProcessManager) OnCaption(fn CaptionFunc) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.onCaption = fn
+}
+
+// caption tells the OnCaption function about a caption
+func (m *BotProcessManager) caption(proc *BotProcess, caption ipc.Caption) {
+	m.mu.RLock()
+	fn := m.onCaption
+	m.mu.RUnlock()
+	if fn != nil {
+		fn(proc, caption)
+	}
+}
+
+// StartSession places a translation session on a bot_worker process, spawning one if needed
+func (m *BotProcessManager) StartSession(config StartSessionConfig) (*BotProcess, error) {
+	m.mu.Lock()
 
 	// Check if session already exists
 	if existing, ok := m.processes[config.TaskID]; ok {
+		m.mu.Unlock()
 		return existing, fmt.Errorf("session already exists for task %s", config.TaskID)
 	}
+	if m.placing[config.TaskID] {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("session already being started for task %s", config.TaskID)
+	}
 
-	m.logger.Printf("Starting session for task %s", config.TaskID)
+	m.logger.Info().Str("task_id", config.TaskID).Msg("Starting session")
 
-	// Create child process command
-	cmd := exec.Command(m.workerPath)
+	worker, spawn := m.placeSession(config)
+	if !worker.ready() {
+		// Starting a worker forks it, dials its socket and waits for its
+		// HELLO. The session holds its slot meanwhile, without m.mu, so the
+		// other workers' messages and the API are not held up.
+		m.placing[config.TaskID] = true
+		worker.reserved++
+		m.mu.Unlock()
 
-	// Setup pipes
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+		if spawn {
+			m.startWorker(worker)
+		}
+		<-worker.starting
+
+		m.mu.Lock()
+		delete(m.placing, config.TaskID)
+		worker.reserved--
+		err := worker.startErr
+		if err == nil && worker.retiring {
+			err = fmt.Errorf("bot_worker %s exited before the session was placed", worker.label())
+		}
+		if err != nil {
+			m.mu.Unlock()
+			botSessionFailures.WithLabelValues("spawn_failed").Inc()
+			return nil, err
+		}
 	}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		stdin.Close()
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	// The session record holds the settings the worker actually gets
+	requested := config
+	warnings := gateCapabilities(worker.hello, &config)
+
+	// Create session record
+	proc := m.addSession(worker, config, botipc.SessionStatusINITIALIZING, time.Now(),
+		"Placed on bot_worker "+worker.label())
+	m.mu.Unlock()
+	botSessionsStarted.Inc()
+
+	proc.logger.Info().Msgf("Placed on bot_worker %s (%d/%d sessions)",
+		worker.label(), len(worker.sessions), worker.maxSessions)
+
+	for _, warning := range warnings {
+		proc.logger.Warn().Msg(warning)
+	}
+	if requested.Placeholder != nil && config.Placeholder == nil {
+		message := fmt.Sprintf("bot_worker build %s cannot publish a placeholder video", worker.hello.BuildID)
+		m.avatarVideo(proc, ipc.AvatarVideoEvent{Event: ipc.AvatarVideoPlaceholderFailed, Message: message})
 	}
 
-	stderr, err := cmd.StderrPipe()
+	var capture *ipc.AudioCapture
+	if config.CaptureAudio {
+		capture = m.sessionAudioCapture()
+	}
+
+	// Send START_SESSION command to child
+	startMsg := ipc.BuildStartSessionMessage(ipc.StartSession{
+		TaskID:         config.TaskID,
+		AppID:          config.AppID,
+		Channel:        config.Channel,
+		BotUID:         config.BotUID,
+		BotToken:       config.BotToken,
+		PalabraUID:     config.PalabraUID,
+		AnamAPIKey:     config.AnamAPIKey,
+		AnamBaseURL:    config.AnamBaseURL,
+		AnamAvatarID:   config.AnamAvatarID,
+		AnamUID:        config.AnamUID,
+		AnamToken:      config.AnamToken,
+		TargetLanguage: config.TargetLanguage,
+		VADMode:        config.VADMode,
+		FanoutGroup:    config.FanoutGroup,
+		Captions:       config.Captions,
+		TaskState:      config.TaskState,
+		ChunkMs:        config.ChunkMs,
+		Loudness:       config.Loudness,
+		Placeholder:    config.Placeholder,
+		Capture:        capture,
+	})
+
+	// The worker answers once the session has connected or failed
+	_, err := worker.requests.Do(worker.writer, startMsg, sessionConnectTimeout)
 	if err != nil {
-		stdin.Close()
-		stdout.Close()
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+		var nack *ipc.CommandError
+		switch {
+		case errors.As(err, &nack):
+			// The worker already reported the failure as a fatal ERROR_RESPONSE
+			proc.logger.Error().Str("errorCode", nack.Code).Msgf("Session failed to connect: %s", nack.Message)
+		case errors.Is(err, ipc.ErrRequestTimeout):
+			proc.logger.Error().Msg("Timeout waiting for session to connect")
+			botSessionFailures.WithLabelValues("connect_timeout").Inc()
+		default:
+			proc.logger.Error().Err(err).Msg("Failed to start session")
+			botSessionFailures.WithLabelValues("send_failed").Inc()
+		}
+		m.stopSession(config.TaskID, stopReasonStartFailed)
+		return nil, fmt.Errorf("session failed to connect: %w", err)
 	}
 
-	// Inherit environment variables (for Agora SDK libs)
-	cmd.Env = append(os.Environ(),
-		"LD_LIBRARY_PATH=/usr/local/lib:/go/agora_sdk",
-	)
+	proc.logger.Info().Msg("Session connected successfully")
+	return proc, nil
+}
 
-	// Start the child process
-	if err := cmd.Start(); err != nil {
-		stdin.Close()
-		stdout.Close()
-		stderr.Close()
-		return nil, fmt.Errorf("failed to start child process: %w", err)
+// gateCapabilities clears the settings of config a worker build without the
+// matching capability cannot honour, returning a warning for each
+func gateCapabilities(hello ipc.Hello, config *StartSessionConfig) []string {
+	var warnings []string
+
+	// Older workers ignore the field and always use the energy gate
+	if config.VADMode != "" && !hello.HasCapability(ipc.CapabilityVADMode) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot choose the voice detector (%s), using its default", hello.BuildID, config.VADMode))
+		config.VADMode = ""
+	}
+
+	// Older workers reject the adaptive gate as an unknown mode
+	if config.VADMode == VADModeAdaptive && !hello.HasCapability(ipc.CapabilityAdaptiveGate) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s has no adaptive gate, using its default voice detector", hello.BuildID))
+		config.VADMode = ""
+	}
+
+	// Older workers send every 10ms frame as a message
+	if config.ChunkMs != 0 && !hello.HasCapability(ipc.CapabilityAudioChunking) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot chunk audio (%dms), sending it frame by frame", hello.BuildID, config.ChunkMs))
+		config.ChunkMs = 0
 	}
 
-	m.logger.Printf("Child process started with PID %d for task %s", cmd.Process.Pid, config.TaskID)
+	// Older workers send the audio as received
+	if config.Loudness != nil && !hello.HasCapability(ipc.CapabilityLoudness) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot normalize loudness, sending audio as received", hello.BuildID))
+		config.Loudness = nil
+	}
 
-	// Create process record
+	// Older workers open a connection per session
+	if config.FanoutGroup != "" && !hello.HasCapability(ipc.CapabilityBotFanout) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot share connections, the session connects on its own", hello.BuildID))
+		config.FanoutGroup = ""
+		config.FanoutSize = 0
+	}
+
+	// Older workers ignore the target's data stream
+	if config.Captions && !hello.HasCapability(ipc.CapabilityCaptions) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot relay captions, the stream has none", hello.BuildID))
+		config.Captions = false
+	}
+
+	// Older workers leave the avatar's tile blank until Anam publishes video
+	if config.Placeholder != nil && !hello.HasCapability(ipc.CapabilityPlaceholderVideo) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot publish a placeholder video", hello.BuildID))
+		config.Placeholder = nil
+	}
+
+	if config.CaptureAudio && !hello.HasCapability(ipc.CapabilityAudioCapture) {
+		warnings = append(warnings, fmt.Sprintf("bot_worker build %s cannot capture audio, not recording the session", hello.BuildID))
+		config.CaptureAudio = false
+	}
+	return warnings
+}
+
+// addSession creates the record of a session hosted by worker and arms its
+// timeout, counted from startTime.
+// Must be called with m.mu held.
+func (m *BotProcessManager) addSession(worker *workerProcess, config StartSessionConfig, status botipc.SessionStatus, startTime time.Time, message string) *BotProcess {
 	proc := &BotProcess{
-		cmd:          cmd,
-		stdin:        stdin,
-		stdout:       stdout,
-		stderr:       stderr,
-		stdinWriter:  ipc.NewMessageWriter(stdin),
+		worker:       worker,
 		TaskID:       config.TaskID,
-		Status:       botipc.SessionStatusINITIALIZING,
-		StartTime:    time.Now(),
+		Channel:      config.Channel,
+		Language:     config.TargetLanguage,
+		config:       config,
+		Status:       status,
+		AnamUID:      config.AnamUID,
+		StartTime:    startTime,
 		shutdownChan: make(chan struct{}),
+		logs:         newSessionLog(),
+		onTransition: m.onTransition,
 	}
+	sessionLogger := worker.logger.With().
+		Str("task_id", config.TaskID).
+		Str("channel", config.Channel).
+		Str("language", config.TargetLanguage).
+		Logger()
+	proc.logger = sessionLogger.Hook(proc.logs.hook(logSourceManager))
+	proc.outputLogger = sessionLogger
+	proc.history = []SessionTransition{{
+		To:      botipc.EnumNamesSessionStatus[status],
+		At:      time.Now(),
+		Message: message,
+	}}
 
-	m.processes[config.TaskID] = proc
+	// Armed before the session is published, so the stop paths that read
+	// the timer without m.mu never miss it
+	m.startSessionTimer(proc, max(m.sessionTimeout-time.Since(startTime), 0))
 
-	// Start goroutines to handle child output
-	go m.handleChildStderr(proc)
-	go m.handleChildMessages(proc)
-	go m.monitorChildProcess(proc)
+	worker.sessions[config.TaskID] = proc
+	m.processes[config.TaskID] = proc
+	return proc
+}
 
-	// Start session timeout timer
-	proc.timeoutTimer = time.AfterFunc(m.sessionTimeout, func() {
-		m.logger.Printf("Session %s timed out after %v - auto-stopping", config.TaskID, m.sessionTimeout)
-		m.StopSession(config.TaskID)
+// startSessionTimer stops the session once the remaining session time is up.
+// Must be called with m.mu held.
+func (m *BotProcessManager) startSessionTimer(proc *BotProcess, remaining time.Duration) {
+	proc.timeoutTimer = time.AfterFunc(remaining, func() {
+		proc.logger.Warn().Msgf("Session timed out after %v - auto-stopping", m.sessionTimeout)
+		m.stopSession(proc.TaskID, stopReasonTimeout)
 	})
-	m.logger.Printf("Session timeout timer started: %v", m.sessionTimeout)
+	proc.logger.Debug().Msgf("Session timeout timer started: %v", remaining)
+}
 
-	// Send START_SESSION command to child
-	startMsg := ipc.BuildStartSessionMessage(
-		config.TaskID,
-		config.AppID,
-		config.Channel,
-		config.BotUID,
-		config.BotToken,
-		config.PalabraUID,
-		config.AnamAPIKey,
-		config.AnamBaseURL,
-		config.AnamAvatarID,
-		config.AnamUID,
-		config.AnamToken,
-		config.TargetLanguage,
-	)
+// placeSession picks the bot_worker for a new session. A session joins the
+// worker already hosting its fan-out group. Otherwise registered remote nodes
+// are preferred, then the local placement policy applies, spawning a new
+// process when none has spare capacity. A group's first session needs room
+// for the whole group. spawn is set when the caller must start the returned
+// worker (see startWorker).
+// Must be called with m.mu held.
+func (m *BotProcessManager) placeSession(config StartSessionConfig) (worker *workerProcess, spawn bool) {
+	if worker := m.fanoutWorker(config.FanoutGroup); worker != nil {
+		return worker, false
+	}
 
-	if err := proc.stdinWriter.WriteMessage(startMsg); err != nil {
-		m.logger.Printf("Failed to send START_SESSION: %v", err)
-		proc.cmd.Process.Kill()
-		delete(m.processes, config.TaskID)
-		return nil, fmt.Errorf("failed to send start command: %w", err)
+	need := 1
+	if config.FanoutGroup != "" {
+		need = max(config.FanoutSize, 1)
 	}
 
-	// Wait for connection (with timeout)
-	timeout := time.After(30 * time.Second)
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
+	if node := m.pickNode(need); node != nil {
+		return node, false
+	}
 
-	for {
-		select {
-		case <-timeout:
-			m.logger.Printf("Timeout waiting for session %s to connect", config.TaskID)
-			m.StopSession(config.TaskID)
-			return nil, fmt.Errorf("timeout waiting for session to connect")
-		case <-ticker.C:
+	switch m.placement {
+	case PlacementPerChannel:
+		for _, worker := range m.workers {
+			if !worker.retiring && worker.channel == config.Channel && worker.load()+need <= worker.maxSessions {
+				return worker, false
+			}
+		}
+		return m.spawnWorker(config.Channel, max(m.sessionsPerProcess, need)), true
+
+	case PlacementShared:
+		var best *workerProcess
+		for _, worker := range m.workers {
+			if worker.retiring || worker.load()+need > worker.maxSessions {
+				continue
+			}
+			if best == nil || worker.load() < best.load() {
+				best = worker
+			}
+		}
+		if best != nil {
+			return best, false
+		}
+		return m.spawnWorker("", max(m.sessionsPerProcess, need)), true
+
+	default:
+		return m.spawnWorker("", need), true
+	}
+}
+
+// fanoutWorker returns the worker hosting a session of group with room for
+// another, nil if none or group is "".
+// Must be called with m.mu held.
+func (m *BotProcessManager) fanoutWorker(group string) *workerProcess {
+	if group == "" {
+		return nil
+	}
+
+	hosts := func(worker *workerProcess) bool {
+		if worker.retiring || worker.load() >= worker.maxSessions {
+			return false
+		}
+		for _, proc := range worker.sessions {
 			proc.mu.RLock()
-			status := proc.Status
+			same := proc.config.FanoutGroup == group
 			proc.mu.RUnlock()
-
-			if status == botipc.SessionStatusCONNECTED || status == botipc.SessionStatusSTREAMING {
-				m.logger.Printf("Session %s connected successfully", config.TaskID)
-				return proc, nil
+			if same {
+				return true
 			}
-			if status == botipc.SessionStatusFAILED {
-				m.logger.Printf("Session %s failed to connect", config.TaskID)
-				m.StopSession(config.TaskID)
-				return nil, fmt.Errorf("session failed to connect")
+		}
+		return false
+	}
+
+	for _, worker := range m.workers {
+		if hosts(worker) {
+			return worker
+		}
+	}
+	for _, node := range m.nodes {
+		if hosts(node) {
+			return node
+		}
+	}
+	return nil
+}
+
+// spawnWorker adds a bot_worker child process that has yet to be started by
+// startWorker, so sessions placed meanwhile can wait for it.
+// Must be called with m.mu held.
+func (m *BotProcessManager) spawnWorker(channel string, maxSessions int) *workerProcess {
+	worker := &workerProcess{
+		requests:    ipc.NewRequests(),
+		channel:     channel,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]*BotProcess),
+		exited:      make(chan struct{}),
+		logger:      m.logger,
+		starting:    make(chan struct{}),
+		stderrTail:  newLineRing(crashOutputLines),
+		logTail:     newLineRing(crashOutputLines),
+	}
+	m.workers = append(m.workers, worker)
+	return worker
+}
+
+// startWorker starts a worker added by spawnWorker, listening on a fresh
+// socket in the runtime directory, and closes its starting channel once it
+// is connected. A worker that fails to start is dropped.
+// Must be called without m.mu held.
+func (m *BotProcessManager) startWorker(worker *workerProcess) {
+	err := m.startChild(worker)
+
+	if err != nil {
+		m.mu.Lock()
+		for i, w := range m.workers {
+			if w == worker {
+				m.workers = append(m.workers[:i], m.workers[i+1:]...)
+				break
 			}
 		}
+		worker.retiring = true
+		worker.startErr = err
+		m.mu.Unlock()
+	}
+	close(worker.starting)
+
+	if err == nil {
+		// Start goroutines to handle child output
+		go m.handleChildMessages(worker)
+		go m.monitorChildProcess(worker)
+	}
+}
+
+// startChild starts the process of a worker and performs the handshake
+func (m *BotProcessManager) startChild(worker *workerProcess) error {
+	if err := os.MkdirAll(m.runtimeDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+	socketPath := filepath.Join(m.runtimeDir, fmt.Sprintf("worker-%d.sock", time.Now().UnixNano()))
+
+	// Create child process command
+	cmd := exec.Command(m.workerPath, "-max-sessions", strconv.Itoa(worker.maxSessions), "-socket", socketPath)
+
+	// Own process group, so signals aimed at the server (Ctrl-C, a supervisor
+	// stopping it) leave the worker and its sessions running
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	// Inherit environment variables (for Agora SDK libs)
+	cmd.Env = append(os.Environ(),
+		"LD_LIBRARY_PATH=/usr/local/lib:/go/agora_sdk",
+	)
+
+	// Start the child process
+	if err := cmd.Start(); err != nil {
+		stderr.Close()
+		return fmt.Errorf("failed to start child process: %w", err)
+	}
+
+	logger := m.logger.With().Int("pid", cmd.Process.Pid).Logger()
+	logger.Info().Int("maxSessions", worker.maxSessions).Str("socket", socketPath).Msg("Child process started")
+	botWorkerStarts.WithLabelValues(workerKindLocal).Inc()
+
+	worker.logger = logger
+	worker.pid = cmd.Process.Pid
+	worker.process = cmd.Process
+	worker.socketPath = socketPath
+	worker.cmd = cmd
+	worker.stderr = stderr
+
+	// Drain stderr during the handshake so a failing child cannot block on it
+	worker.readers.Add(2)
+	go m.handleChildStderr(worker)
+
+	err = m.connectWorker(worker)
+	if err == nil {
+		err = m.handshake(worker)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Refusing bot_worker")
+		botWorkerHandshakeFailures.WithLabelValues(workerKindLocal).Inc()
+		cmd.Process.Kill()
+		if worker.conn != nil {
+			worker.conn.Close()
+		}
+		worker.capture.Close()
+		worker.readers.Done() // handleChildMessages never runs
+		go func() {
+			worker.readers.Wait()
+			cmd.Wait()
+			os.Remove(socketPath)
+		}()
+		return fmt.Errorf("incompatible bot_worker %s: %w", m.workerPath, err)
+	}
+	return nil
+}
+
+// connectWorker dials the socket of a freshly spawned worker, retrying until
+// the worker listens or the handshake timeout passes
+func (m *BotProcessManager) connectWorker(worker *workerProcess) error {
+	deadline := time.Now().Add(ipc.HelloTimeout)
+	for {
+		conn, err := net.Dial(ipc.SchemeUnix, worker.socketPath)
+		if err == nil {
+			worker.conn = conn
+			worker.writer = ipc.NewMessageWriter(conn)
+			worker.reader = ipc.NewMessageReader(conn)
+			worker.capture = m.openCapture(fmt.Sprintf("pid%d", worker.pid), worker.reader, worker.writer, worker.logger)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("bot_worker did not listen on %s within %v: %w", worker.socketPath, ipc.HelloTimeout, err)
+		}
+		time.Sleep(workerDialInterval)
 	}
 }
 
+// handshake waits for the worker's HELLO, refuses incompatible workers and
+// answers with the server's HELLO
+func (m *BotProcessManager) handshake(worker *workerProcess) error {
+	worker.conn.SetReadDeadline(time.Now().Add(ipc.HelloTimeout))
+	hello, err := ipc.ReadHello(worker.reader)
+	if err != nil {
+		return err
+	}
+	worker.conn.SetReadDeadline(time.Time{})
+
+	worker.hello = hello
+	worker.logger = worker.logger.With().Str("build", hello.BuildID).Logger()
+	worker.logger.Info().
+		Uint32("protocol", hello.ProtocolVersion).
+		Strs("capabilities", hello.Capabilities).
+		Msg("bot_worker handshake complete")
+
+	return worker.writer.WriteMessage(ipc.BuildHelloMessage(ipc.LocalHello()))
+}
+
 // StopSession stops a running session
 func (m *BotProcessManager) StopSession(taskID string) error {
+	return m.stopSession(taskID, stopReasonRequested)
+}
+
+// stopSession stops a running session, recording why it was stopped
+func (m *BotProcessManager) stopSession(taskID, reason string) error {
 	m.mu.Lock()
 	proc, ok := m.processes[taskID]
 	if !ok {
@@ -238,6 +906,8 @@ func (m *BotProcessManager) StopSession(taskID string) error {
 		return fmt.Errorf("no session found for task %s", taskID)
 	}
 	delete(m.processes, taskID)
+	m.retainFinished(proc)
+	proc.stopping = true
 	m.mu.Unlock()
 
 	// Cancel timeout timer if running
@@ -245,40 +915,64 @@ func (m *BotProcessManager) StopSession(taskID string) error {
 		proc.timeoutTimer.Stop()
 	}
 
-	m.logger.Printf("Stopping session for task %s", taskID)
-
-	// Send STOP_SESSION command
-	stopMsg := ipc.BuildStopSessionMessage(taskID, "Requested by parent")
-	if err := proc.stdinWriter.WriteMessage(stopMsg); err != nil {
-		m.logger.Printf("Failed to send STOP_SESSION (will force kill): %v", err)
-	}
+	proc.logger.Info().Str("reason", reason).Msg("Stopping session")
+	botSessionsStopped.WithLabelValues(reason).Inc()
 
 	// Close shutdown channel to signal handlers
 	close(proc.shutdownChan)
 
-	// Give child time to cleanup gracefully
-	done := make(chan struct{})
-	go func() {
-		proc.cmd.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		m.logger.Printf("Child process for task %s exited gracefully", taskID)
-	case <-time.After(5 * time.Second):
-		m.logger.Printf("Child process for task %s did not exit, killing", taskID)
-		proc.cmd.Process.Kill()
+	// Give child time to cleanup gracefully. A worker left without sessions
+	// is retired (and killed if it lingers) by detachSession either way.
+	stopMsg := ipc.BuildStopSessionMessage(taskID, "Requested by parent: "+reason)
+	if _, err := proc.worker.requests.Do(proc.worker.writer, stopMsg, sessionStopTimeout); err != nil {
+		proc.logger.Warn().Err(err).Msg("bot_worker did not confirm the stop")
+	} else {
+		proc.logger.Info().Msg("Session stopped by bot_worker")
 	}
 
-	// Close pipes
-	proc.stdin.Close()
-	proc.stdout.Close()
-	proc.stderr.Close()
+	m.detachSession(proc)
 
 	return nil
 }
 
+// detachSession removes a finished session from its worker and retires local
+// workers once they no longer host any sessions. Remote nodes stay registered.
+func (m *BotProcessManager) detachSession(proc *BotProcess) {
+	worker := proc.worker
+
+	m.mu.Lock()
+	if m.processes[proc.TaskID] == proc {
+		delete(m.processes, proc.TaskID)
+		m.retainFinished(proc)
+	}
+	delete(worker.sessions, proc.TaskID)
+	retire := worker.nodeID == "" && worker.load() == 0 && !worker.retiring
+	if retire {
+		worker.retiring = true
+	}
+	m.mu.Unlock()
+	proc.logs.close()
+
+	if retire {
+		m.retireWorker(worker)
+	}
+}
+
+// retireWorker asks a local worker to exit with SIGTERM, killing it if it
+// lingers. Closing the connection would not do: workers keep their sessions
+// running when the server goes away.
+func (m *BotProcessManager) retireWorker(worker *workerProcess) {
+	worker.process.Signal(syscall.SIGTERM)
+
+	select {
+	case <-worker.exited:
+		worker.logger.Info().Msg("Child process exited gracefully")
+	case <-time.After(5 * time.Second):
+		worker.logger.Warn().Msg("Child process did not exit, killing")
+		worker.process.Kill()
+	}
+}
+
 // GetSession returns a session by task ID
 func (m *BotProcessManager) GetSession(taskID string) (*BotProcess, bool) {
 	m.mu.RLock()
@@ -299,121 +993,294 @@ func (m *BotProcessManager) GetAllSessions() map[string]*BotProcess {
 	return result
 }
 
+// lookupSession finds the session a child message refers to
+func (m *BotProcessManager) lookupSession(worker *workerProcess, taskID string) *BotProcess {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return worker.sessions[taskID]
+}
+
 // handleChildStderr reads and logs child stderr
-func (m *BotProcessManager) handleChildStderr(proc *BotProcess) {
-	scanner := bufio.NewScanner(proc.stderr)
+func (m *BotProcessManager) handleChildStderr(worker *workerProcess) {
+	defer worker.readers.Done()
+
+	scanner := bufio.NewScanner(worker.stderr)
 	for scanner.Scan() {
-		select {
-		case <-proc.shutdownChan:
-			return
-		default:
-			m.logger.Printf("[child:%s] %s", proc.TaskID, scanner.Text())
+		line := scanner.Text()
+		worker.stderrTail.add(line)
+
+		// stderr can only be attributed to a session when the child hosts one
+		m.mu.RLock()
+		var proc *BotProcess
+		if len(worker.sessions) == 1 {
+			for _, p := range worker.sessions {
+				proc = p
+			}
 		}
+		m.mu.RUnlock()
+
+		m.logChildOutput(worker, proc, zerolog.InfoLevel, logSourceStderr, line)
 	}
 	if err := scanner.Err(); err != nil && err != io.EOF {
-		m.logger.Printf("Error reading child stderr for task %s: %v", proc.TaskID, err)
+		worker.logger.Error().Err(err).Msg("Error reading child stderr")
 	}
 }
 
-// handleChildMessages reads IPC messages from child stdout
-func (m *BotProcessManager) handleChildMessages(proc *BotProcess) {
-	reader := ipc.NewMessageReader(proc.stdout)
+// handleChildMessages reads IPC messages from a worker and routes them to sessions by task ID.
+// A read error means the worker is gone; for spawned workers monitorChildProcess
+// reports the exit once the process has been reaped.
+func (m *BotProcessManager) handleChildMessages(worker *workerProcess) {
+	if worker.cmd != nil {
+		defer worker.readers.Done()
+	}
 
 	for {
-		select {
-		case <-proc.shutdownChan:
-			return
-		default:
-		}
-
-		msgBytes, err := reader.ReadMessage()
+		msgBytes, err := worker.reader.ReadMessage()
 		if err != nil {
 			if err == io.EOF {
-				m.logger.Printf("Child stdout closed for task %s", proc.TaskID)
+				worker.logger.Info().Msg("IPC stream closed")
 			} else {
-				m.logger.Printf("Error reading from child for task %s: %v", proc.TaskID, err)
+				worker.logger.Error().Err(err).Msg("Error reading IPC stream")
+			}
+			worker.requests.FailAll()
+			worker.conn.Close()
+			if worker.cmd == nil {
+				m.workerExited(worker, fmt.Errorf("connection lost: %w", err))
 			}
 			return
 		}
 
 		msgType, payloadBytes, err := ipc.ParseIPCMessage(msgBytes)
 		if err != nil {
-			m.logger.Printf("Error parsing IPC message for task %s: %v", proc.TaskID, err)
+			worker.logger.Error().Err(err).Msg("Error parsing IPC message")
 			continue
 		}
 
 		switch msgType {
 		case botipc.MessageTypeSTATUS_UPDATE:
 			payload := ipc.ParseStatusPayload(payloadBytes)
-			proc.mu.Lock()
-			proc.Status = payload.Status()
-			proc.AnamUID = payload.AnamUid()
-			proc.mu.Unlock()
-			m.logger.Printf("Task %s status: %s - %s (AnamUID: %d)",
-				proc.TaskID,
-				botipc.EnumNamesSessionStatus[payload.Status()],
-				string(payload.Message()),
-				payload.AnamUid())
+			taskID := string(payload.TaskId())
+
+			proc := m.lookupSession(worker, taskID)
+			if proc == nil {
+				worker.logger.Warn().
+					Str("task_id", taskID).
+					Str("status", botipc.EnumNamesSessionStatus[payload.Status()]).
+					Msg("Status update for unknown session")
+				continue
+			}
+			proc.logger.Info().
+				Str("status", botipc.EnumNamesSessionStatus[payload.Status()]).
+				Uint32("anamUID", payload.AnamUid()).
+				Msgf("Status: %s - %s", botipc.EnumNamesSessionStatus[payload.Status()], string(payload.Message()))
+
+			if from, ok := proc.transition(payload.Status(), string(payload.Message()), ""); ok {
+				proc.mu.Lock()
+				proc.AnamUID = payload.AnamUid()
+				proc.mu.Unlock()
+			} else {
+				proc.logger.Warn().Msgf("Ignoring illegal status transition %s -> %s",
+					botipc.EnumNamesSessionStatus[from],
+					botipc.EnumNamesSessionStatus[payload.Status()])
+			}
+
+			if payload.Status() == botipc.SessionStatusDISCONNECTED {
+				m.mu.RLock()
+				stopping := proc.stopping
+				m.mu.RUnlock()
+				if !stopping {
+					// Session ended inside the worker (idle timeout, target left, ...)
+					proc.logger.Info().Msg("Session ended by bot_worker")
+					botSessionsStopped.WithLabelValues(stopReasonEnded).Inc()
+					if proc.timeoutTimer != nil {
+						proc.timeoutTimer.Stop()
+					}
+					go m.detachSession(proc)
+				}
+			}
 
 		case botipc.MessageTypeLOG_MESSAGE:
 			payload := ipc.ParseLogPayload(payloadBytes)
-			levelName := botipc.EnumNamesLogLevel[payload.Level()]
-			m.logger.Printf("[child:%s][%s] %s", proc.TaskID, levelName, string(payload.Message()))
+			taskID := string(payload.TaskId())
+			message := string(payload.Message())
+			if worker.logTail != nil {
+				worker.logTail.add(fmt.Sprintf("[%s][%s] %s", taskID, botipc.EnumNamesLogLevel[payload.Level()], message))
+			}
+
+			proc := m.lookupSession(worker, taskID)
+			if proc == nil && taskID != "" {
+				message = "[" + taskID + "] " + message
+			}
+			m.logChildOutput(worker, proc, childLogLevel(payload.Level()), logSourceWorker, message)
 
 		case botipc.MessageTypeERROR_RESPONSE:
 			payload := ipc.ParseErrorPayload(payloadBytes)
-			m.logger.Printf("Task %s error [%s]: %s (fatal: %v)",
-				proc.TaskID,
-				string(payload.ErrorCode()),
-				string(payload.Message()),
-				payload.Fatal())
+			taskID := string(payload.TaskId())
+
+			logger := worker.logger.With().Str("task_id", taskID).Logger()
+			proc := m.lookupSession(worker, taskID)
+			if proc != nil {
+				logger = proc.logger
+			}
+			logger.Error().
+				Str("errorCode", string(payload.ErrorCode())).
+				Bool("fatal", payload.Fatal()).
+				Msg(string(payload.Message()))
 
 			if payload.Fatal() {
+				botSessionFailures.WithLabelValues(strings.ToLower(string(payload.ErrorCode()))).Inc()
+				if proc != nil {
+					proc.transition(botipc.SessionStatusFAILED, string(payload.Message()), string(payload.ErrorCode()))
+				}
+			}
+
+		case botipc.MessageTypeMETRICS:
+			taskID, metrics := ipc.ParseMetricsPayload(payloadBytes)
+			observeAnamRequests(metrics.AnamHTTP)
+
+			if proc := m.lookupSession(worker, taskID); proc != nil {
+				metrics.AnamHTTP = nil
 				proc.mu.Lock()
-				proc.Status = botipc.SessionStatusFAILED
+				proc.metrics = metrics
+				proc.metricsAt = time.Now()
 				proc.mu.Unlock()
 			}
 
+		case botipc.MessageTypeAVATAR_VIDEO:
+			taskID, event := ipc.ParseAvatarVideoPayload(payloadBytes)
+			proc := m.lookupSession(worker, taskID)
+			if proc == nil {
+				worker.logger.Warn().Str("task_id", taskID).Str("event", event.Event).Msg("Avatar video event for unknown session")
+				continue
+			}
+			proc.logger.Info().
+				Str("event", event.Event).
+				Dur("waited", event.Waited).
+				Msgf("Avatar video: %s %s", event.Event, event.Message)
+			m.avatarVideo(proc, event)
+
+		case botipc.MessageTypeCAPTION:
+			taskID, caption := ipc.ParseCaptionPayload(payloadBytes)
+			proc := m.lookupSession(worker, taskID)
+			if proc == nil {
+				continue
+			}
+			proc.logger.Debug().
+				Bool("final", caption.Final).
+				Str("language", caption.Language).
+				Msgf("Caption: %s", caption.Text)
+			// Remote nodes have clocks of their own; the captions are timed
+			// against the server's, like the task start they are relative to
+			caption.ReceivedAt = time.Now()
+			m.caption(proc, caption)
+
+		case botipc.MessageTypeACK, botipc.MessageTypeNACK:
+			resp := ipc.ParseResponse(msgType, ipc.ParseRequestID(msgBytes), payloadBytes)
+			if !worker.requests.Resolve(resp) {
+				worker.logger.Warn().
+					Str("task_id", resp.TaskID).
+					Str("command", resp.Command.String()).
+					Msgf("Late or unexpected %s", msgType)
+			}
+
+		case botipc.MessageTypeNODE_HEARTBEAT:
+			payload := ipc.ParseNodeHeartbeatPayload(payloadBytes)
+			m.mu.Lock()
+			worker.lastHeartbeat = time.Now()
+			worker.reportedSessions = int(payload.ActiveSessions())
+			m.mu.Unlock()
+
 		default:
-			m.logger.Printf("Unknown message type from child for task %s: %d", proc.TaskID, msgType)
+			worker.logger.Warn().Msgf("Unknown message type: %d", msgType)
 		}
 	}
 }
 
-// monitorChildProcess watches for child process exit
-func (m *BotProcessManager) monitorChildProcess(proc *BotProcess) {
+// monitorChildProcess watches for child process exit and writes a crash
+// bundle when it dies unexpectedly
+func (m *BotProcessManager) monitorChildProcess(worker *workerProcess) {
+	// Drain the socket and stderr first: Wait closes the stderr pipe, which
+	// would drop the last lines written before a crash
+	worker.readers.Wait()
+
 	// Wait for process to exit
-	err := proc.cmd.Wait()
+	err := worker.cmd.Wait()
 
-	select {
-	case <-proc.shutdownChan:
-		// Normal shutdown, ignore
-		return
-	default:
+	m.mu.RLock()
+	crashed := err != nil && !worker.retiring
+	sessions := make([]*BotProcess, 0, len(worker.sessions))
+	for _, proc := range worker.sessions {
+		sessions = append(sessions, proc)
 	}
+	m.mu.RUnlock()
 
-	// Unexpected exit (crash)
-	m.logger.Printf("Child process for task %s exited unexpectedly: %v", proc.TaskID, err)
+	m.workerExited(worker, err)
+	if crashed {
+		m.writeCrashBundle(worker, sessions, err)
+	}
+
+	worker.stderr.Close()
+
+	// A crashed worker leaves its socket behind
+	os.Remove(worker.socketPath)
+}
 
-	// Update status
-	proc.mu.Lock()
-	proc.Status = botipc.SessionStatusFAILED
-	proc.mu.Unlock()
+// workerExited drops a worker whose process exited or whose node was lost.
+// Sessions the parent did not ask to stop are marked FAILED.
+func (m *BotProcessManager) workerExited(worker *workerProcess, err error) {
+	close(worker.exited)
+	worker.capture.Close()
 
-	// Remove from active processes
 	m.mu.Lock()
-	delete(m.processes, proc.TaskID)
+	if !worker.retiring {
+		botWorkerCrashes.WithLabelValues(worker.kind()).Inc()
+	}
+	if worker.nodeID != "" {
+		if m.nodes[worker.nodeID] == worker {
+			delete(m.nodes, worker.nodeID)
+		}
+	} else {
+		for i, w := range m.workers {
+			if w == worker {
+				m.workers = append(m.workers[:i], m.workers[i+1:]...)
+				break
+			}
+		}
+	}
+	orphaned := make([]*BotProcess, 0, len(worker.sessions))
+	for taskID, proc := range worker.sessions {
+		delete(worker.sessions, taskID)
+		if proc.stopping {
+			// Normal shutdown, ignore
+			continue
+		}
+		if m.processes[taskID] == proc {
+			delete(m.processes, taskID)
+			m.retainFinished(proc)
+		}
+		orphaned = append(orphaned, proc)
+	}
+	worker.retiring = true
 	m.mu.Unlock()
 
-	// Close pipes
-	proc.stdin.Close()
-	proc.stdout.Close()
-	proc.stderr.Close()
+	// Unexpected exit (crash or lost node) takes down every session hosted by the worker
+	for _, proc := range orphaned {
+		proc.logger.Error().Err(err).Msgf("bot_worker %s exited unexpectedly", worker.label())
+		botSessionsStopped.WithLabelValues(stopReasonWorkerLost).Inc()
+		botSessionFailures.WithLabelValues("worker_lost").Inc()
+
+		if proc.timeoutTimer != nil {
+			proc.timeoutTimer.Stop()
+		}
+
+		proc.transition(botipc.SessionStatusFAILED, fmt.Sprintf("bot_worker %s exited: %v", worker.label(), err), "WORKER_LOST")
+		proc.logs.close()
+	}
 }
 
 // Shutdown stops all sessions and cleans up
 func (m *BotProcessManager) Shutdown() {
-	m.logger.Println("Shutting down all bot processes")
+	m.logger.Info().Msg("Shutting down all bot processes")
 
 	m.mu.Lock()
 	taskIDs := make([]string, 0, len(m.processes))
@@ -427,4 +1294,15 @@ func (m *BotProcessManager) Shutdown() {
 	}
 
 	close(m.shutdownChan)
+
+	// Disconnect remote nodes; they keep retrying until the server is back
+	if m.nodeListener != nil {
+		m.nodeListener.Close()
+	}
+	m.mu.Lock()
+	for _, node := range m.nodes {
+		node.retiring = true
+		node.conn.Close()
+	}
+	m.mu.Unlock()
 }
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Default directory local bot_workers listen in
+const DefaultRuntimeDir = "./bot_runtime"
+
+// How often a freshly spawned worker's socket is dialed until it listens
+const workerDialInterval = 50 * time.Millisecond
+
+// Time a reattached worker has to answer the handshake and LIST_SESSIONS
+const reattachTimeout = 5 * time.Second
+
+// reattachWorkers adopts the bot_workers a previous server run left behind.
+// Every local worker listens on a socket in the runtime directory and keeps
+// its sessions running while no server is connected, so a restart (or deploy)
+// of the server does not interrupt the meetings they serve.
+func (m *BotProcessManager) reattachWorkers() {
+	paths, err := filepath.Glob(filepath.Join(m.runtimeDir, ipc.WorkerSocketPattern))
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to scan runtime directory")
+		return
+	}
+
+	for _, path := range paths {
+		if err := m.reattachWorker(path); err != nil {
+			m.logger.Warn().Err(err).Str("socket", path).Msg("Not reattaching bot_worker")
+		}
+	}
+}
+
+// adoptHandshake performs the handshake with a reattached worker and asks
+// for its sessions
+func (m *BotProcessManager) adoptHandshake(conn net.Conn, reader *ipc.MessageReader, writer *ipc.MessageWriter) (ipc.SessionList, ipc.Hello, error) {
+	conn.SetReadDeadline(time.Now().Add(reattachTimeout))
+	hello, err := ipc.ReadHello(reader)
+	if err != nil {
+		botWorkerHandshakeFailures.WithLabelValues(workerKindLocal).Inc()
+		return ipc.SessionList{}, hello, fmt.Errorf("incompatible bot_worker: %w", err)
+	}
+	if !hello.Supports(botipc.MessageTypeLIST_SESSIONS) {
+		return ipc.SessionList{}, hello, fmt.Errorf("bot_worker build %s cannot report its sessions", hello.BuildID)
+	}
+	if err := writer.WriteMessage(ipc.BuildHelloMessage(ipc.LocalHello())); err != nil {
+		return ipc.SessionList{}, hello, fmt.Errorf("failed to send HELLO: %w", err)
+	}
+	list, err := ipc.ListSessions(reader, writer)
+	if err != nil {
+		return ipc.SessionList{}, hello, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	return list, hello, nil
+}
+
+// reattachWorker connects to a worker's socket, performs the handshake and
+// rebuilds the worker's sessions from its SESSION_LIST. A socket nobody
+// listens on belongs to a worker that died and is removed.
+func (m *BotProcessManager) reattachWorker(path string) error {
+	conn, err := net.DialTimeout(ipc.SchemeUnix, path, reattachTimeout)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("removed stale socket: %w", err)
+	}
+
+	reader := ipc.NewMessageReader(conn)
+	writer := ipc.NewMessageWriter(conn)
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + "-reattached"
+	capture := m.openCapture(name, reader, writer, m.logger.With().Str("socket", path).Logger())
+
+	list, hello, err := m.adoptHandshake(conn, reader, writer)
+	if err != nil {
+		conn.Close()
+		capture.Close()
+		return err
+	}
+
+	// Never fails on Unix; the process is alive as long as its socket answers
+	process, _ := os.FindProcess(list.Pid)
+
+	worker := &workerProcess{
+		conn:        conn,
+		writer:      writer,
+		reader:      reader,
+		requests:    ipc.NewRequests(),
+		maxSessions: list.MaxSessions,
+		sessions:    make(map[string]*BotProcess),
+		exited:      make(chan struct{}),
+		logger:      m.logger.With().Int("pid", list.Pid).Str("build", hello.BuildID).Logger(),
+		hello:       hello,
+		capture:     capture,
+		pid:         list.Pid,
+		process:     process,
+		socketPath:  path,
+	}
+
+	m.mu.Lock()
+	adopted := make([]*BotProcess, 0, len(list.Sessions))
+	for _, info := range list.Sessions {
+		if _, exists := m.processes[info.TaskID]; exists {
+			worker.logger.Warn().Str("task_id", info.TaskID).Msg("Session already hosted by another bot_worker, not adopting it")
+			continue
+		}
+		config := StartSessionConfig{
+			TaskID:         info.TaskID,
+			Channel:        info.Channel,
+			BotUID:         info.BotUID,
+			PalabraUID:     info.PalabraUID,
+			AnamUID:        info.AnamUID,
+			TargetLanguage: info.TargetLanguage,
+			TaskState:      info.TaskState,
+		}
+		proc := m.addSession(worker, config, info.Status, info.StartedAt, "Reattached to bot_worker "+worker.label()+" after server restart")
+		adopted = append(adopted, proc)
+		reserveAnamUID(info.Channel, info.AnamUID)
+	}
+	if m.placement == PlacementPerChannel && len(adopted) > 0 {
+		worker.channel = adopted[0].Channel
+	}
+	m.workers = append(m.workers, worker)
+	retire := len(worker.sessions) == 0
+	if retire {
+		worker.retiring = true
+	}
+	m.mu.Unlock()
+
+	worker.logger.Info().
+		Str("socket", path).
+		Int("sessions", len(adopted)).
+		Int("maxSessions", worker.maxSessions).
+		Msg("Reattached to bot_worker")
+
+	go m.handleChildMessages(worker)
+
+	for _, proc := range adopted {
+		botSessionsReattached.Inc()
+		proc.logger.Info().Msgf("Adopted session running since %s", proc.StartTime.Format(time.RFC3339))
+	}
+
+	if retire {
+		go m.retireWorker(worker)
+	}
+	return nil
+}
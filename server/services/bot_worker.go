@@ -7,21 +7,36 @@ import (
 	"sync"
 	"time"
 
+	"github.com/samyak-jain/agora_backend/services/audio"
+	"github.com/samyak-jain/agora_backend/services/ipc"
 	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
 )
 
 // Default idle timeout in seconds (stop session if no audio for this long)
 const DefaultIdleTimeoutSeconds = 60
 
+// How often session metrics are reported to the parent
+const MetricsReportInterval = 5 * time.Second
+
 // StatusCallback is called when session status changes
 type StatusCallback func(taskID string, status botipc.SessionStatus, message string, anamUID uint32)
 
 // LogCallback is called to send log messages to parent
 type LogCallback func(taskID string, level botipc.LogLevel, message string)
 
+// LogFunc writes a formatted log line for a session. AgoraBot and AnamClient
+// use it so their output reaches the parent tagged with the session's task ID.
+type LogFunc func(level botipc.LogLevel, format string, args ...interface{})
+
 // ErrorCallback is called when an error occurs
 type ErrorCallback func(taskID, errorCode, message string, fatal bool)
 
+// MetricsCallback is called periodically with the session's counters
+type MetricsCallback func(taskID string, metrics ipc.SessionMetrics)
+
+// CaptionCallback is called with each caption of the session's target
+type CaptionCallback func(taskID string, caption ipc.Caption)
+
 // BotWorkerConfig contains all configuration needed to start a bot session
 type BotWorkerConfig struct {
 	TaskID         string
@@ -36,28 +51,49 @@ type BotWorkerConfig struct {
 	AnamUID        uint32
 	AnamToken      string
 	TargetLanguage string
+	VADMode        string                // Voice detector of the bot (see VADMode*), "" for the default
+	AudioCapture   *ipc.AudioCapture     // Record the session's audio, nil to disable
+	ChunkDuration  time.Duration         // Audio per message sent to Anam, 0 for the default
+	Loudness       *ipc.Loudness         // Normalize the audio sent to Anam, nil to send it as received
+	Placeholder    *ipc.PlaceholderVideo // Publish a placeholder video until the avatar's first frames, nil to disable
+	FanoutGroup    string                // Share the Agora connection with the group's other sessions, "" for its own
+	Captions       bool                  // Relay the captions of the target's data stream
+	TaskState      string                // Server's task metadata, reported back by Info
 
 	// Callbacks for IPC
-	StatusCallback StatusCallback
-	LogCallback    LogCallback
-	ErrorCallback  ErrorCallback
+	StatusCallback      StatusCallback
+	LogCallback         LogCallback
+	ErrorCallback       ErrorCallback
+	MetricsCallback     MetricsCallback
+	AvatarVideoCallback AvatarVideoCallback
+	CaptionCallback     CaptionCallback
 }
 
 // BotWorker orchestrates AgoraBot and AnamClient in the child process
 type BotWorker struct {
-	config     BotWorkerConfig
-	agoraBot   *AgoraBot
-	anamClient *AnamClient
-	stopChan   chan struct{}
-	mu         sync.Mutex
-	isRunning  bool
+	config      BotWorkerConfig
+	agoraBot    *AgoraBot
+	anamClient  *AnamClient
+	placeholder *placeholderVideo // Nil unless publishing the placeholder video
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	doneChan    chan struct{} // Closed when Run returns
+	mu          sync.Mutex
+	isRunning   bool
+	streaming   bool                 // Agora bot connected; runtime control commands allowed
+	status      botipc.SessionStatus // Last status reported (guarded by mu)
+	palabraUID  uint32               // Current target UID, changed by SwitchTarget (guarded by mu)
+	startedAt   time.Time
 }
 
 // NewBotWorker creates a new BotWorker instance
 func NewBotWorker(config BotWorkerConfig) *BotWorker {
 	return &BotWorker{
-		config:   config,
-		stopChan: make(chan struct{}),
+		config:     config,
+		stopChan:   make(chan struct{}),
+		doneChan:   make(chan struct{}),
+		palabraUID: config.PalabraUID,
+		startedAt:  time.Now(),
 	}
 }
 
@@ -75,10 +111,39 @@ func (w *BotWorker) Run() error {
 		w.mu.Lock()
 		w.isRunning = false
 		w.mu.Unlock()
+		close(w.doneChan)
 	}()
 
+	select {
+	case <-w.stopChan:
+		w.log(botipc.LogLevelINFO, "Stopped before starting")
+		return nil
+	default:
+	}
+
 	w.log(botipc.LogLevelINFO, "Starting bot worker for task %s", w.config.TaskID)
 
+	detector, err := NewVoiceDetector(w.config.VADMode)
+	if err != nil {
+		w.log(botipc.LogLevelERROR, "Invalid session config: %v", err)
+		w.sendError("INVALID_VAD_MODE", err.Error(), true)
+		return err
+	}
+	if err := audio.ValidChunkDuration(w.config.ChunkDuration); err != nil {
+		w.log(botipc.LogLevelERROR, "Invalid session config: %v", err)
+		w.sendError("INVALID_CHUNK_DURATION", err.Error(), true)
+		return err
+	}
+	err = ValidateLoudness(w.config.Loudness)
+	if err == nil {
+		err = checkLoudnessVADMode(w.config.Loudness, w.config.VADMode)
+	}
+	if err != nil {
+		w.log(botipc.LogLevelERROR, "Invalid session config: %v", err)
+		w.sendError("INVALID_LOUDNESS", err.Error(), true)
+		return err
+	}
+
 	// Step 1: Create and connect Anam client
 	w.sendStatus(botipc.SessionStatusCONNECTING_ANAM, "Connecting to Anam API", 0)
 
@@ -91,12 +156,14 @@ func (w *BotWorker) Run() error {
 		w.config.AnamBaseURL,
 		w.config.AnamAPIKey,
 	)
+	w.anamClient.logFunc = w.log
 
 	// Start Anam session (this connects to Anam API and WebSocket)
 	if err := w.anamClient.StartSession(); err != nil {
 		errMsg := fmt.Sprintf("Failed to start Anam session: %v", err)
 		w.log(botipc.LogLevelERROR, errMsg)
 		w.sendError("ANAM_CONNECT_FAILED", errMsg, true)
+		w.reportMetrics()
 		return fmt.Errorf(errMsg)
 	}
 
@@ -113,11 +180,31 @@ func (w *BotWorker) Run() error {
 		fmt.Sprintf("%d", w.config.PalabraUID),
 		w.anamClient, // Pass AnamClient reference
 	)
+	w.agoraBot.logFunc = w.log
+	w.agoraBot.SetVoiceDetector(detector)
+	w.agoraBot.SetChunkDuration(w.config.ChunkDuration)
+	w.agoraBot.SetFanoutGroup(w.config.FanoutGroup)
+	if w.config.Captions {
+		w.agoraBot.SetCaptions(w.config.TargetLanguage, w.sendCaption)
+	}
+	if w.config.Loudness != nil {
+		w.agoraBot.SetLoudness(w.config.Loudness.Engine, loudnessConfig(w.config.Loudness))
+	}
+
+	if w.config.AudioCapture != nil {
+		capture, err := newAudioCapture(w.config.AudioCapture, w.config.TaskID, inputSampleRate, w.anamClient.SampleRate(), w.log)
+		if err != nil {
+			w.log(botipc.LogLevelWARN, "Audio capture disabled: %v", err)
+		}
+		defer capture.Close()
+		w.agoraBot.SetAudioCapture(capture)
+	}
 
 	if err := w.agoraBot.Start(); err != nil {
 		errMsg := fmt.Sprintf("Failed to start Agora bot: %v", err)
 		w.log(botipc.LogLevelERROR, errMsg)
 		w.sendError("AGORA_CONNECT_FAILED", errMsg, true)
+		w.reportMetrics()
 		// Cleanup Anam
 		w.anamClient.Close()
 		return fmt.Errorf(errMsg)
@@ -125,10 +212,19 @@ func (w *BotWorker) Run() error {
 
 	w.log(botipc.LogLevelINFO, "Agora bot connected and subscribed to UID %d", w.config.PalabraUID)
 
+	// Cover the avatar's tile until Anam publishes video
+	if w.config.Placeholder != nil {
+		w.startPlaceholder()
+	}
+
 	// Step 3: Send connected status with Anam UID
 	w.sendStatus(botipc.SessionStatusCONNECTED, "Session connected", w.config.AnamUID)
 	w.sendStatus(botipc.SessionStatusSTREAMING, "Audio streaming active", w.config.AnamUID)
 
+	w.mu.Lock()
+	w.streaming = true
+	w.mu.Unlock()
+
 	// Get idle timeout from environment (default 60 seconds)
 	idleTimeoutSeconds := DefaultIdleTimeoutSeconds
 	if envTimeout := os.Getenv("PALABRA_IDLE_TIMEOUT_SECONDS"); envTimeout != "" {
@@ -142,6 +238,8 @@ func (w *BotWorker) Run() error {
 	// Step 4: Wait for stop signal, target left, or idle timeout
 	idleCheckTicker := time.NewTicker(10 * time.Second) // Check every 10 seconds
 	defer idleCheckTicker.Stop()
+	metricsTicker := time.NewTicker(MetricsReportInterval)
+	defer metricsTicker.Stop()
 
 	for {
 		select {
@@ -163,6 +261,8 @@ func (w *BotWorker) Run() error {
 					goto cleanup
 				}
 			}
+		case <-metricsTicker.C:
+			w.reportMetrics()
 		}
 	}
 
@@ -174,20 +274,33 @@ cleanup:
 	return nil
 }
 
-// Stop signals the worker to stop
+// Stop signals the worker to stop. A worker stopped before Run starts does
+// not connect at all.
 func (w *BotWorker) Stop() {
-	w.mu.Lock()
-	if !w.isRunning {
-		w.mu.Unlock()
-		return
-	}
-	w.mu.Unlock()
+	w.stopOnce.Do(func() {
+		close(w.stopChan)
+	})
+}
 
-	close(w.stopChan)
+// Done returns a channel that is closed once Run has returned
+func (w *BotWorker) Done() <-chan struct{} {
+	return w.doneChan
 }
 
 // cleanup stops all components
 func (w *BotWorker) cleanup() {
+	// Final report so counters from the last interval are not lost
+	w.reportMetrics()
+
+	w.mu.Lock()
+	w.streaming = false
+	w.mu.Unlock()
+
+	if w.placeholder != nil {
+		w.placeholder.Stop()
+		w.placeholder = nil
+	}
+
 	if w.agoraBot != nil {
 		w.log(botipc.LogLevelINFO, "Stopping Agora bot")
 		w.agoraBot.Stop()
@@ -201,8 +314,133 @@ func (w *BotWorker) cleanup() {
 	}
 }
 
+// startPlaceholder publishes the placeholder video. The session goes on
+// without it if it fails.
+func (w *BotWorker) startPlaceholder() {
+	placeholder, err := startPlaceholderVideo(w.config.AppID, w.config.Channel, w.config.AnamUID,
+		w.config.Placeholder, w.log, w.sendAvatarVideo)
+	if err != nil {
+		w.log(botipc.LogLevelWARN, "Placeholder video disabled: %v", err)
+		w.sendAvatarVideo(ipc.AvatarVideoEvent{Event: ipc.AvatarVideoPlaceholderFailed, Message: err.Error()})
+		return
+	}
+	w.placeholder = placeholder
+}
+
+// withStreamingBot runs fn on the Agora bot if the session is streaming
+func (w *BotWorker) withStreamingBot(fn func(bot *AgoraBot) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.streaming {
+		return fmt.Errorf("session is not streaming")
+	}
+	return fn(w.agoraBot)
+}
+
+// UpdateVAD changes the voice detection settings of the running session.
+// Zero values leave a setting unchanged.
+func (w *BotWorker) UpdateVAD(rmsThreshold int64, hangover, preroll time.Duration) error {
+	return w.withStreamingBot(func(bot *AgoraBot) error {
+		return bot.UpdateVAD(rmsThreshold, hangover, preroll)
+	})
+}
+
+// SetForwarding pauses or resumes forwarding Palabra audio to Anam
+func (w *BotWorker) SetForwarding(enabled bool) error {
+	return w.withStreamingBot(func(bot *AgoraBot) error {
+		bot.SetForwarding(enabled)
+		return nil
+	})
+}
+
+// SwitchTarget forwards the audio of another Palabra UID
+func (w *BotWorker) SwitchTarget(palabraUID uint32) error {
+	return w.withStreamingBot(func(bot *AgoraBot) error {
+		if err := bot.SwitchTarget(fmt.Sprintf("%d", palabraUID)); err != nil {
+			return err
+		}
+		w.palabraUID = palabraUID
+		return nil
+	})
+}
+
+// Info describes the session for a reattaching parent
+func (w *BotWorker) Info() ipc.SessionInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return ipc.SessionInfo{
+		TaskID:         w.config.TaskID,
+		Channel:        w.config.Channel,
+		TargetLanguage: w.config.TargetLanguage,
+		Status:         w.status,
+		BotUID:         w.config.BotUID,
+		PalabraUID:     w.palabraUID,
+		AnamUID:        w.config.AnamUID,
+		StartedAt:      w.startedAt,
+		TaskState:      w.config.TaskState,
+	}
+}
+
+// reportMetrics sends the session's counters and live readings via callback
+func (w *BotWorker) reportMetrics() {
+	if w.config.MetricsCallback == nil {
+		return
+	}
+
+	var metrics ipc.SessionMetrics
+	if w.agoraBot != nil {
+		metrics.FramesForwarded, metrics.VoiceSegments, metrics.VoiceEndCount = w.agoraBot.AudioStats()
+		metrics.FramesReceived = w.agoraBot.FramesReceived()
+		metrics.RMSAvg, metrics.RMSPeak = w.agoraBot.DrainLevels()
+		metrics.SinceLastAudio = w.agoraBot.GetIdleDuration()
+		if gate, ok := w.agoraBot.GateDiagnostics(); ok {
+			metrics.Gate = ipcGateDiagnostics(gate)
+		}
+	}
+	if w.anamClient != nil {
+		metrics.AnamHTTP = w.anamClient.DrainHTTPTimings()
+		metrics.AnamSendErrors = w.anamClient.SendErrors()
+		metrics.WSRoundTrip = w.anamClient.RoundTrip()
+
+		depth, peak, dropped := w.anamClient.SendQueueStats()
+		metrics.AnamQueueDepth, metrics.AnamQueuePeak, metrics.AnamDropped = uint32(depth), uint32(peak), dropped
+	}
+	w.config.MetricsCallback(w.config.TaskID, metrics)
+}
+
+// ipcGateDiagnostics converts the adaptive gate's diagnostics for METRICS
+func ipcGateDiagnostics(d audio.GateDiagnostics) *ipc.GateDiagnostics {
+	gate := &ipc.GateDiagnostics{
+		NoiseFloorDBFS:  float32(d.NoiseFloor),
+		SpeechLevelDBFS: float32(d.SpeechLevel),
+		ThresholdDBFS:   float32(d.Threshold),
+		Hangover:        d.Hangover,
+		Preroll:         d.Preroll,
+		Gap:             d.Gap,
+	}
+	for _, end := range d.Ends {
+		gate.Ends = append(gate.Ends, ipc.GateEnd{
+			At:             end.At,
+			Reason:         end.Reason,
+			Segment:        end.Segment,
+			Silence:        end.Silence,
+			Hangover:       end.Hangover,
+			LevelDBFS:      float32(end.Level),
+			ThresholdDBFS:  float32(end.Threshold),
+			NoiseFloorDBFS: float32(end.NoiseFloor),
+		})
+	}
+	return gate
+}
+
 // sendStatus sends a status update via callback
 func (w *BotWorker) sendStatus(status botipc.SessionStatus, message string, anamUID uint32) {
+	w.mu.Lock()
+	w.status = status
+	w.mu.Unlock()
+
 	if w.config.StatusCallback != nil {
 		w.config.StatusCallback(w.config.TaskID, status, message, anamUID)
 	}
@@ -215,6 +453,20 @@ func (w *BotWorker) sendError(errorCode, message string, fatal bool) {
 	}
 }
 
+// sendAvatarVideo reports a placeholder video event via callback
+func (w *BotWorker) sendAvatarVideo(event ipc.AvatarVideoEvent) {
+	if w.config.AvatarVideoCallback != nil {
+		w.config.AvatarVideoCallback(w.config.TaskID, event)
+	}
+}
+
+// sendCaption relays a caption of the target via callback
+func (w *BotWorker) sendCaption(caption ipc.Caption) {
+	if w.config.CaptionCallback != nil {
+		w.config.CaptionCallback(w.config.TaskID, caption)
+	}
+}
+
 // log sends a log message via callback
 func (w *BotWorker) log(level botipc.LogLevel, format string, args ...interface{}) {
 	if w.config.LogCallback != nil {
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Palabra message types carrying transcriptions. Translation streams send
+// their translated text; source transcriptions only count when they are in
+// the stream's language.
+const (
+	palabraPartialTranslation = "partial_translated_transcription"
+	palabraTranslation        = "translated_transcription"
+	palabraPartialTranscript  = "partial_transcription"
+	palabraTranscript         = "validated_transcription"
+)
+
+// Captions queued for a slow client. Further captions are dropped for it
+// until it catches up.
+const captionBuffer = 64
+
+// palabraMessage is a message Palabra sends on a translation stream's data
+// stream
+type palabraMessage struct {
+	MessageType string `json:"message_type"`
+	Data        struct {
+		Transcription struct {
+			TranscriptionID string `json:"transcription_id"`
+			Language        string `json:"language"`
+			Text            string `json:"text"`
+			Segments        []struct {
+				Start float64 `json:"start"` // Seconds
+				End   float64 `json:"end"`
+			} `json:"segments"`
+		} `json:"transcription"`
+	} `json:"data"`
+}
+
+// parseCaption reads a caption in language from a data stream message,
+// returning false for messages that carry none
+func parseCaption(data []byte, language string) (ipc.Caption, bool) {
+	var msg palabraMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return ipc.Caption{}, false
+	}
+	transcription := msg.Data.Transcription
+
+	caption := ipc.Caption{
+		Language:   transcription.Language,
+		SegmentID:  transcription.TranscriptionID,
+		Text:       strings.TrimSpace(transcription.Text),
+		ReceivedAt: time.Now(),
+	}
+	switch msg.MessageType {
+	case palabraTranslation, palabraTranscript:
+		caption.Final = true
+	case palabraPartialTranslation, palabraPartialTranscript:
+	default:
+		return ipc.Caption{}, false
+	}
+	if caption.Language == "" {
+		caption.Language = language
+	}
+	translated := msg.MessageType == palabraTranslation || msg.MessageType == palabraPartialTranslation
+	if !translated && !strings.EqualFold(caption.Language, language) {
+		return ipc.Caption{}, false
+	}
+	if caption.Text == "" && !caption.Final {
+		return ipc.Caption{}, false
+	}
+
+	if segments := transcription.Segments; len(segments) > 0 {
+		caption.Start = time.Duration(segments[0].Start * float64(time.Second))
+		caption.End = time.Duration(segments[len(segments)-1].End * float64(time.Second))
+	}
+	return caption, true
+}
+
+// CaptionEvent is a caption pushed to the clients of a channel
+type CaptionEvent struct {
+	TaskID     string `json:"taskId"`
+	Channel    string `json:"channel"`
+	Language   string `json:"language"`
+	UID        string `json:"uid"`        // Palabra UID of the translation stream
+	SpeakerUID string `json:"speakerUid"` // UID of the translated speaker
+	SegmentID  string `json:"segmentId,omitempty"`
+	Text       string `json:"text"`
+	Final      bool   `json:"final"` // Partials of a segment are replaced by its final
+	StartMs    int64  `json:"startMs"`
+	EndMs      int64  `json:"endMs"`
+	Timestamp  int64  `json:"timestamp"` // Unix ms the server received the caption
+}
+
+// captionSession is the translation stream a bot session relays captions of
+type captionSession struct {
+	taskID      string // Palabra task
+	channel     string
+	speakerUID  string
+	speakerName string    // SourceName of the task, labels the speaker in transcripts
+	startedAt   time.Time // Task start, which transcript timestamps are relative to
+}
+
+// captionRelayRegistry pushes the captions bot sessions read from Palabra's
+// data streams to the clients of their channel, and keeps the final ones in
+// the channel's transcript (see transcripts.go)
+type captionRelayRegistry struct {
+	mu          sync.Mutex
+	sessions    map[string]captionSession    // Bot session task ID -> stream
+	subscribers map[chan CaptionEvent]string // Client -> channel
+}
+
+var captionRelay = &captionRelayRegistry{
+	sessions:    make(map[string]captionSession),
+	subscribers: make(map[chan CaptionEvent]string),
+}
+
+// track starts relaying the captions of a bot session translating speakerUID
+// in a task started at startedAt
+func (r *captionRelayRegistry) track(sessionID, taskID, channel, speakerUID, speakerName string, startedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[sessionID] = captionSession{
+		taskID:      taskID,
+		channel:     channel,
+		speakerUID:  speakerUID,
+		speakerName: speakerName,
+		startedAt:   startedAt,
+	}
+}
+
+// forgetTask stops relaying the captions of a task's sessions
+func (r *captionRelayRegistry) forgetTask(taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sessionID, session := range r.sessions {
+		if session.taskID == taskID {
+			delete(r.sessions, sessionID)
+		}
+	}
+}
+
+// sessionTransition stops relaying the captions of a bot session once it
+// failed or ended, whether stopped with its task or not
+func (r *captionRelayRegistry) sessionTransition(proc *BotProcess, to botipc.SessionStatus, errorCode string) {
+	if to != botipc.SessionStatusFAILED && to != botipc.SessionStatusDISCONNECTED {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, proc.TaskID)
+}
+
+// caption pushes a caption of a bot session to the clients of its channel
+// and stores it in the channel's transcript once final
+func (r *captionRelayRegistry) caption(proc *BotProcess, caption ipc.Caption) {
+	session, ok := r.push(proc.TaskID, caption)
+	if !ok || !caption.Final || caption.Text == "" {
+		return
+	}
+
+	// The final arrives as the segment ends
+	end := caption.ReceivedAt.Sub(session.startedAt)
+	duration := caption.End - caption.Start
+	if duration <= 0 {
+		duration = defaultSegmentDuration
+	}
+	segment := TranscriptSegment{
+		TaskID:        session.taskID,
+		SpeakerUID:    session.speakerUID,
+		Speaker:       session.speakerName,
+		Language:      caption.Language,
+		Text:          caption.Text,
+		StartMs:       max(end-duration, 0).Milliseconds(),
+		EndMs:         max(end, 0).Milliseconds(),
+		TaskStartedAt: session.startedAt.UnixMilli(),
+	}
+	if err := getTranscripts().append(session.channel, segment); err != nil {
+		proc.logger.Error().Err(err).Msg("Failed to store caption in transcript")
+	}
+}
+
+// push sends a caption of a bot session to the clients of its channel,
+// returning the session's stream or false if its captions are not relayed
+func (r *captionRelayRegistry) push(sessionID string, caption ipc.Caption) (captionSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return session, false
+	}
+	event := CaptionEvent{
+		TaskID:     session.taskID,
+		Channel:    session.channel,
+		Language:   caption.Language,
+		UID:        caption.UID,
+		SpeakerUID: session.speakerUID,
+		SegmentID:  caption.SegmentID,
+		Text:       caption.Text,
+		Final:      caption.Final,
+		StartMs:    caption.Start.Milliseconds(),
+		EndMs:      caption.End.Milliseconds(),
+		Timestamp:  caption.ReceivedAt.UnixMilli(),
+	}
+	for ch, channel := range r.subscribers {
+		if channel != session.channel {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			// Too far behind: the client misses this caption
+		}
+	}
+	return session, true
+}
+
+// subscribe registers a client for the captions of a channel
+func (r *captionRelayRegistry) subscribe(channel string) chan CaptionEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	captions := make(chan CaptionEvent, captionBuffer)
+	r.subscribers[captions] = channel
+	return captions
+}
+
+// unsubscribe removes a client registered with subscribe
+func (r *captionRelayRegistry) unsubscribe(captions chan CaptionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscribers, captions)
+}
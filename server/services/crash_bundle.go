@@ -0,0 +1,355 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+)
+
+// Crash bundle defaults, overridable through config
+const (
+	DefaultCrashDir          = "./crash_bundles"
+	DefaultCrashBundlesMax   = 20
+	DefaultCrashBundleMaxAge = 7 * 24 * time.Hour
+	crashOutputLines         = 200               // stderr / IPC log lines kept per worker
+	crashSDKLogDir           = "./agora_rtc_log" // Written by every local bot_worker (see AgoraBot)
+	crashSDKLogMaxBytes      = 2 << 20           // Tail copied from each SDK log file
+	crashReportFile          = "crash.json"
+	crashBundleTimeFormat    = "20060102T150405Z"
+	redactedValue            = "[REDACTED]"
+)
+
+// ErrCrashBundleNotFound is returned for unknown crash bundle names
+var ErrCrashBundleNotFound = errors.New("crash bundle not found")
+
+// lineRing keeps the last N lines written to it
+type lineRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+func newLineRing(size int) *lineRing {
+	return &lineRing{lines: make([]string, size)}
+}
+
+// add appends a timestamped line, overwriting the oldest once full
+func (r *lineRing) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.next] = time.Now().Format("15:04:05.000 ") + line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the buffered lines, oldest first
+func (r *lineRing) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	return append(append([]string(nil), r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// CrashBundleInfo describes a crash bundle
+type CrashBundleInfo struct {
+	Name       string    `json:"name"`
+	Time       time.Time `json:"time"`
+	Pid        int       `json:"pid"`
+	ExitCode   int       `json:"exitCode"` // -1 when killed by a signal
+	Signal     string    `json:"signal,omitempty"`
+	CoreDumped bool      `json:"coreDumped,omitempty"`
+	Error      string    `json:"error"`
+	Sessions   []string  `json:"sessions"`
+	SizeBytes  int64     `json:"sizeBytes,omitempty"` // Filled in when listing
+}
+
+// crashReport is the content of crash.json
+type crashReport struct {
+	CrashBundleInfo
+	SessionDetails []crashSession `json:"sessionDetails"`
+}
+
+// crashSession is a session hosted by the crashed worker
+type crashSession struct {
+	Config  StartSessionConfig `json:"config"` // Secrets redacted
+	History SessionHistory     `json:"history"`
+	Metrics ipc.SessionMetrics `json:"metrics"`
+}
+
+// redacted returns a copy of the config safe to write to disk
+func (c StartSessionConfig) redacted() StartSessionConfig {
+	// Nested settings are shared with the session; copy the ones holding secrets
+	if c.Placeholder != nil {
+		placeholder := *c.Placeholder
+		c.Placeholder = &placeholder
+	}
+	for _, secret := range c.secrets() {
+		if *secret != "" {
+			*secret = redactedValue
+		}
+	}
+	return c
+}
+
+// writeCrashBundle collects the forensics of a crashed local bot_worker into
+// a new bundle directory and applies the retention limits
+func (m *BotProcessManager) writeCrashBundle(worker *workerProcess, sessions []*BotProcess, exitErr error) {
+	pid := worker.pid
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-pid%d", now.Format(crashBundleTimeFormat), pid)
+	dir := filepath.Join(m.crashDir, name)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		worker.logger.Error().Err(err).Str("bundle", dir).Msg("Failed to create crash bundle")
+		return
+	}
+
+	report := crashReport{
+		CrashBundleInfo: CrashBundleInfo{
+			Name:     name,
+			Time:     now,
+			Pid:      pid,
+			ExitCode: -1,
+			Error:    exitErr.Error(),
+			Sessions: make([]string, 0, len(sessions)),
+		},
+		SessionDetails: make([]crashSession, 0, len(sessions)),
+	}
+	if state := worker.cmd.ProcessState; state != nil {
+		report.ExitCode = state.ExitCode()
+		if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			report.Signal = status.Signal().String()
+			report.CoreDumped = status.CoreDump()
+		}
+	}
+	for _, proc := range sessions {
+		proc.mu.RLock()
+		metrics := proc.metrics
+		config := proc.config
+		proc.mu.RUnlock()
+
+		report.Sessions = append(report.Sessions, proc.TaskID)
+		report.SessionDetails = append(report.SessionDetails, crashSession{
+			Config:  config.redacted(),
+			History: proc.History(),
+			Metrics: metrics,
+		})
+	}
+
+	reportJSON, _ := json.MarshalIndent(report, "", "  ")
+	files := map[string][]byte{
+		crashReportFile: reportJSON,
+		"stderr.log":    []byte(strings.Join(worker.stderrTail.snapshot(), "\n") + "\n"),
+		"ipc.log":       []byte(strings.Join(worker.logTail.snapshot(), "\n") + "\n"),
+	}
+	for file, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+			worker.logger.Error().Err(err).Str("bundle", name).Msgf("Failed to write %s to crash bundle", file)
+		}
+	}
+
+	if err := copySDKLogs(filepath.Join(dir, filepath.Base(crashSDKLogDir))); err != nil {
+		worker.logger.Error().Err(err).Str("bundle", name).Msg("Failed to copy SDK logs to crash bundle")
+	}
+
+	worker.logger.Info().Str("bundle", dir).Msg("Crash bundle written")
+	m.pruneCrashBundles()
+}
+
+// copySDKLogs copies the tail of every Agora SDK log file into dst
+func copySDKLogs(dst string) error {
+	entries, err := os.ReadDir(crashSDKLogDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if err := copyFileTail(filepath.Join(crashSDKLogDir, entry.Name()), filepath.Join(dst, entry.Name()), crashSDKLogMaxBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFileTail copies at most the last maxBytes of src to dst
+func copyFileTail(src, dst string, maxBytes int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxBytes {
+		if _, err := in.Seek(info.Size()-maxBytes, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// crashBundleNames returns the names of all crash bundles, oldest first
+func (m *BotProcessManager) crashBundleNames() ([]string, error) {
+	entries, err := os.ReadDir(m.crashDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(m.crashDir, entry.Name(), crashReportFile)); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	// Names start with a UTC timestamp
+	sort.Strings(names)
+	return names, nil
+}
+
+// pruneCrashBundles removes bundles beyond the count limit or older than the max age
+func (m *BotProcessManager) pruneCrashBundles() {
+	names, err := m.crashBundleNames()
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to list crash bundles")
+		return
+	}
+
+	for i, name := range names {
+		expired := len(names)-i > m.crashBundlesMax
+		if created, err := time.Parse(crashBundleTimeFormat, strings.SplitN(name, "-", 2)[0]); err == nil && time.Since(created) > m.crashBundleMaxAge {
+			expired = true
+		}
+		if !expired {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(m.crashDir, name)); err != nil {
+			m.logger.Error().Err(err).Str("bundle", name).Msg("Failed to remove crash bundle")
+		}
+	}
+}
+
+// ListCrashBundles returns the stored crash bundles, newest first
+func (m *BotProcessManager) ListCrashBundles() ([]CrashBundleInfo, error) {
+	names, err := m.crashBundleNames()
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := make([]CrashBundleInfo, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		dir := filepath.Join(m.crashDir, names[i])
+
+		var info CrashBundleInfo
+		data, err := os.ReadFile(filepath.Join(dir, crashReportFile))
+		if err != nil || json.Unmarshal(data, &info) != nil {
+			continue
+		}
+		info.Name = names[i]
+
+		filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+			if err == nil && fi.Mode().IsRegular() {
+				info.SizeBytes += fi.Size()
+			}
+			return nil
+		})
+		bundles = append(bundles, info)
+	}
+	return bundles, nil
+}
+
+// WriteCrashBundleArchive writes a crash bundle to w as a .tar.gz archive
+func (m *BotProcessManager) WriteCrashBundleArchive(name string, w io.Writer) error {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return ErrCrashBundleNotFound
+	}
+	dir := filepath.Join(m.crashDir, name)
+	if _, err := os.Stat(filepath.Join(dir, crashReportFile)); err != nil {
+		return ErrCrashBundleNotFound
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.crashDir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -79,8 +79,20 @@ func (rcv *IPCMessage) MutatePayload(j int, n byte) bool {
 	return false
 }
 
+func (rcv *IPCMessage) RequestId() uint64 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(8))
+	if o != 0 {
+		return rcv._tab.GetUint64(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *IPCMessage) MutateRequestId(n uint64) bool {
+	return rcv._tab.MutateUint64Slot(8, n)
+}
+
 func IPCMessageStart(builder *flatbuffers.Builder) {
-	builder.StartObject(2)
+	builder.StartObject(3)
 }
 func IPCMessageAddMessageType(builder *flatbuffers.Builder, messageType MessageType) {
 	builder.PrependInt8Slot(0, int8(messageType), 0)
@@ -91,6 +103,9 @@ func IPCMessageAddPayload(builder *flatbuffers.Builder, payload flatbuffers.UOff
 func IPCMessageStartPayloadVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
 	return builder.StartVector(1, numElems, 1)
 }
+func IPCMessageAddRequestId(builder *flatbuffers.Builder, requestId uint64) {
+	builder.PrependUint64Slot(2, requestId, 0)
+}
 func IPCMessageEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
@@ -7,27 +7,69 @@ import "strconv"
 type MessageType int8
 
 const (
-	MessageTypeSTART_SESSION  MessageType = 0
-	MessageTypeSTOP_SESSION   MessageType = 1
-	MessageTypeSTATUS_UPDATE  MessageType = 10
-	MessageTypeLOG_MESSAGE    MessageType = 11
-	MessageTypeERROR_RESPONSE MessageType = 12
+	MessageTypeSTART_SESSION     MessageType = 0
+	MessageTypeSTOP_SESSION      MessageType = 1
+	MessageTypeUPDATE_CONFIG     MessageType = 2
+	MessageTypePAUSE_FORWARDING  MessageType = 3
+	MessageTypeRESUME_FORWARDING MessageType = 4
+	MessageTypeSWITCH_TARGET     MessageType = 5
+	MessageTypeLIST_SESSIONS     MessageType = 6
+	MessageTypeSTATUS_UPDATE     MessageType = 10
+	MessageTypeLOG_MESSAGE       MessageType = 11
+	MessageTypeERROR_RESPONSE    MessageType = 12
+	MessageTypeMETRICS           MessageType = 13
+	MessageTypeSESSION_LIST      MessageType = 14
+	MessageTypeACK               MessageType = 15
+	MessageTypeNACK              MessageType = 16
+	MessageTypeAVATAR_VIDEO      MessageType = 17
+	MessageTypeCAPTION           MessageType = 18
+	MessageTypeREGISTER_NODE     MessageType = 20
+	MessageTypeNODE_HEARTBEAT    MessageType = 21
+	MessageTypeHELLO             MessageType = 30
 )
 
 var EnumNamesMessageType = map[MessageType]string{
-	MessageTypeSTART_SESSION:  "START_SESSION",
-	MessageTypeSTOP_SESSION:   "STOP_SESSION",
-	MessageTypeSTATUS_UPDATE:  "STATUS_UPDATE",
-	MessageTypeLOG_MESSAGE:    "LOG_MESSAGE",
-	MessageTypeERROR_RESPONSE: "ERROR_RESPONSE",
+	MessageTypeSTART_SESSION:     "START_SESSION",
+	MessageTypeSTOP_SESSION:      "STOP_SESSION",
+	MessageTypeUPDATE_CONFIG:     "UPDATE_CONFIG",
+	MessageTypePAUSE_FORWARDING:  "PAUSE_FORWARDING",
+	MessageTypeRESUME_FORWARDING: "RESUME_FORWARDING",
+	MessageTypeSWITCH_TARGET:     "SWITCH_TARGET",
+	MessageTypeLIST_SESSIONS:     "LIST_SESSIONS",
+	MessageTypeSTATUS_UPDATE:     "STATUS_UPDATE",
+	MessageTypeLOG_MESSAGE:       "LOG_MESSAGE",
+	MessageTypeERROR_RESPONSE:    "ERROR_RESPONSE",
+	MessageTypeMETRICS:           "METRICS",
+	MessageTypeSESSION_LIST:      "SESSION_LIST",
+	MessageTypeACK:               "ACK",
+	MessageTypeNACK:              "NACK",
+	MessageTypeAVATAR_VIDEO:      "AVATAR_VIDEO",
+	MessageTypeCAPTION:           "CAPTION",
+	MessageTypeREGISTER_NODE:     "REGISTER_NODE",
+	MessageTypeNODE_HEARTBEAT:    "NODE_HEARTBEAT",
+	MessageTypeHELLO:             "HELLO",
 }
 
 var EnumValuesMessageType = map[string]MessageType{
-	"START_SESSION":  MessageTypeSTART_SESSION,
-	"STOP_SESSION":   MessageTypeSTOP_SESSION,
-	"STATUS_UPDATE":  MessageTypeSTATUS_UPDATE,
-	"LOG_MESSAGE":    MessageTypeLOG_MESSAGE,
-	"ERROR_RESPONSE": MessageTypeERROR_RESPONSE,
+	"START_SESSION":     MessageTypeSTART_SESSION,
+	"STOP_SESSION":      MessageTypeSTOP_SESSION,
+	"UPDATE_CONFIG":     MessageTypeUPDATE_CONFIG,
+	"PAUSE_FORWARDING":  MessageTypePAUSE_FORWARDING,
+	"RESUME_FORWARDING": MessageTypeRESUME_FORWARDING,
+	"SWITCH_TARGET":     MessageTypeSWITCH_TARGET,
+	"LIST_SESSIONS":     MessageTypeLIST_SESSIONS,
+	"STATUS_UPDATE":     MessageTypeSTATUS_UPDATE,
+	"LOG_MESSAGE":       MessageTypeLOG_MESSAGE,
+	"ERROR_RESPONSE":    MessageTypeERROR_RESPONSE,
+	"METRICS":           MessageTypeMETRICS,
+	"SESSION_LIST":      MessageTypeSESSION_LIST,
+	"ACK":               MessageTypeACK,
+	"NACK":              MessageTypeNACK,
+	"AVATAR_VIDEO":      MessageTypeAVATAR_VIDEO,
+	"CAPTION":           MessageTypeCAPTION,
+	"REGISTER_NODE":     MessageTypeREGISTER_NODE,
+	"NODE_HEARTBEAT":    MessageTypeNODE_HEARTBEAT,
+	"HELLO":             MessageTypeHELLO,
 }
 
 func (v MessageType) String() string {
@@ -141,8 +141,95 @@ func (rcv *StartSessionPayload) TargetLanguage() []byte {
 	return nil
 }
 
+func (rcv *StartSessionPayload) VadMode() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(28))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *StartSessionPayload) AudioCapture(obj *AudioCaptureConfig) *AudioCaptureConfig {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(30))
+	if o != 0 {
+		x := rcv._tab.Indirect(o + rcv._tab.Pos)
+		if obj == nil {
+			obj = new(AudioCaptureConfig)
+		}
+		obj.Init(rcv._tab.Bytes, x)
+		return obj
+	}
+	return nil
+}
+
+func (rcv *StartSessionPayload) ChunkMs() uint32 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(32))
+	if o != 0 {
+		return rcv._tab.GetUint32(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *StartSessionPayload) MutateChunkMs(n uint32) bool {
+	return rcv._tab.MutateUint32Slot(32, n)
+}
+
+func (rcv *StartSessionPayload) Loudness(obj *LoudnessConfig) *LoudnessConfig {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(34))
+	if o != 0 {
+		x := rcv._tab.Indirect(o + rcv._tab.Pos)
+		if obj == nil {
+			obj = new(LoudnessConfig)
+		}
+		obj.Init(rcv._tab.Bytes, x)
+		return obj
+	}
+	return nil
+}
+
+func (rcv *StartSessionPayload) PlaceholderVideo(obj *PlaceholderVideoConfig) *PlaceholderVideoConfig {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(36))
+	if o != 0 {
+		x := rcv._tab.Indirect(o + rcv._tab.Pos)
+		if obj == nil {
+			obj = new(PlaceholderVideoConfig)
+		}
+		obj.Init(rcv._tab.Bytes, x)
+		return obj
+	}
+	return nil
+}
+
+func (rcv *StartSessionPayload) FanoutGroup() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(38))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *StartSessionPayload) Captions() bool {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(40))
+	if o != 0 {
+		return rcv._tab.GetBool(o + rcv._tab.Pos)
+	}
+	return false
+}
+
+func (rcv *StartSessionPayload) MutateCaptions(n bool) bool {
+	return rcv._tab.MutateBoolSlot(40, n)
+}
+
+func (rcv *StartSessionPayload) TaskState() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(42))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
 func StartSessionPayloadStart(builder *flatbuffers.Builder) {
-	builder.StartObject(12)
+	builder.StartObject(20)
 }
 func StartSessionPayloadAddTaskId(builder *flatbuffers.Builder, taskId flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(taskId), 0)
@@ -180,6 +267,30 @@ func StartSessionPayloadAddAnamToken(builder *flatbuffers.Builder, anamToken fla
 func StartSessionPayloadAddTargetLanguage(builder *flatbuffers.Builder, targetLanguage flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(11, flatbuffers.UOffsetT(targetLanguage), 0)
 }
+func StartSessionPayloadAddVadMode(builder *flatbuffers.Builder, vadMode flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(12, flatbuffers.UOffsetT(vadMode), 0)
+}
+func StartSessionPayloadAddAudioCapture(builder *flatbuffers.Builder, audioCapture flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(13, flatbuffers.UOffsetT(audioCapture), 0)
+}
+func StartSessionPayloadAddChunkMs(builder *flatbuffers.Builder, chunkMs uint32) {
+	builder.PrependUint32Slot(14, chunkMs, 0)
+}
+func StartSessionPayloadAddLoudness(builder *flatbuffers.Builder, loudness flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(15, flatbuffers.UOffsetT(loudness), 0)
+}
+func StartSessionPayloadAddPlaceholderVideo(builder *flatbuffers.Builder, placeholderVideo flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(16, flatbuffers.UOffsetT(placeholderVideo), 0)
+}
+func StartSessionPayloadAddFanoutGroup(builder *flatbuffers.Builder, fanoutGroup flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(17, flatbuffers.UOffsetT(fanoutGroup), 0)
+}
+func StartSessionPayloadAddCaptions(builder *flatbuffers.Builder, captions bool) {
+	builder.PrependBoolSlot(18, captions, false)
+}
+func StartSessionPayloadAddTaskState(builder *flatbuffers.Builder, taskState flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(19, flatbuffers.UOffsetT(taskState), 0)
+}
 func StartSessionPayloadEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
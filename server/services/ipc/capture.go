@@ -0,0 +1,153 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Capture files start with captureMagic, followed by one record per message:
+// [8 bytes big-endian Unix nanoseconds][1 byte Direction][4 bytes big-endian length][message]
+const captureMagic = "BOTIPCAP1\n"
+
+// CaptureExtension is the file extension of IPC captures
+const CaptureExtension = ".ipccap"
+
+// Direction tells which side of a connection sent a captured message
+type Direction byte
+
+const (
+	ToWorker   Direction = '>' // Server -> bot_worker
+	FromWorker Direction = '<' // bot_worker -> server
+)
+
+func (d Direction) String() string {
+	switch d {
+	case ToWorker:
+		return "to_worker"
+	case FromWorker:
+		return "from_worker"
+	default:
+		return fmt.Sprintf("Direction(%d)", byte(d))
+	}
+}
+
+// CaptureRecord is one message read back from a capture
+type CaptureRecord struct {
+	Time      time.Time
+	Direction Direction
+	Message   []byte // Framed IPCMessage without its length prefix
+}
+
+// CaptureWriter records the messages of one connection. A nil CaptureWriter
+// records nothing, so callers need not check whether capturing is enabled.
+type CaptureWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+	err  error // First write error; recording stops after it
+}
+
+// CreateCapture creates a capture file
+func CreateCapture(path string) (*CaptureWriter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CaptureWriter{file: file, w: bufio.NewWriter(file)}
+	if _, err := c.w.WriteString(captureMagic); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Record appends a message. Every record is flushed, so a capture is
+// complete up to the last message even if the server crashes.
+func (c *CaptureWriter) Record(direction Direction, msg []byte) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+
+	var header [13]byte
+	binary.BigEndian.PutUint64(header[0:8], uint64(time.Now().UnixNano()))
+	header[8] = byte(direction)
+	binary.BigEndian.PutUint32(header[9:13], uint32(len(msg)))
+
+	if _, err := c.w.Write(header[:]); err != nil {
+		c.err = err
+		return err
+	}
+	if _, err := c.w.Write(msg); err != nil {
+		c.err = err
+		return err
+	}
+	c.err = c.w.Flush()
+	return c.err
+}
+
+// Close closes the capture file
+func (c *CaptureWriter) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.w.Flush()
+	return c.file.Close()
+}
+
+// CaptureReader reads the records of a capture
+type CaptureReader struct {
+	r *bufio.Reader
+}
+
+// NewCaptureReader checks the capture header and returns a reader for its records
+func NewCaptureReader(r io.Reader) (*CaptureReader, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(captureMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != captureMagic {
+		return nil, fmt.Errorf("not an IPC capture")
+	}
+	return &CaptureReader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one. A record cut
+// short by a crash is reported as io.ErrUnexpectedEOF.
+func (c *CaptureReader) Next() (CaptureRecord, error) {
+	var header [13]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return CaptureRecord{}, err
+	}
+
+	length := binary.BigEndian.Uint32(header[9:13])
+	if length > MaxMessageSize {
+		return CaptureRecord{}, fmt.Errorf("record too large: %d bytes", length)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(c.r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return CaptureRecord{}, err
+	}
+
+	return CaptureRecord{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(header[0:8]))),
+		Direction: Direction(header[8]),
+		Message:   msg,
+	}, nil
+}
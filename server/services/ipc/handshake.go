@@ -0,0 +1,194 @@
+package ipc
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// ProtocolVersion identifies the wire format of bot_ipc.fbs. Bump it for
+// changes an older peer would misparse (renumbered fields, removed tables,
+// changed types); additions are advertised through HELLO instead.
+const ProtocolVersion = 3
+
+// HelloTimeout is how long a peer has to send HELLO after connecting
+const HelloTimeout = 5 * time.Second
+
+// BuildID identifies the binary in HELLO. Set it at link time with
+// -ldflags "-X github.com/samyak-jain/agora_backend/services/ipc.BuildID=<id>".
+var BuildID = "dev"
+
+// Capabilities gate optional payload fields added without a protocol bump
+const (
+	CapabilitySessionTelemetry = "session_telemetry" // METRICS carries RMS, RTT, send errors and idle time
+	CapabilityVADMode          = "vad_mode"          // START_SESSION chooses the voice detector
+	CapabilityAudioCapture     = "audio_capture"     // START_SESSION can record the session's audio
+	CapabilityAudioChunking    = "audio_chunking"    // START_SESSION can set the duration of audio per Anam message
+	CapabilityAnamSendQueue    = "anam_send_queue"   // METRICS carries the Anam send queue's depth and drops
+	CapabilityLoudness         = "loudness"          // START_SESSION can normalize the loudness of the audio sent to Anam
+	CapabilityAdaptiveGate     = "adaptive_gate"     // START_SESSION can choose the adaptive gate; METRICS carries its diagnostics
+	CapabilityPlaceholderVideo = "placeholder_video" // START_SESSION can publish a placeholder video until the avatar's first frames
+	CapabilityBotFanout        = "bot_fanout"        // START_SESSION can share one Agora connection between the sessions of a group
+	CapabilityCaptions         = "captions"          // START_SESSION can relay the target's captions as CAPTION messages
+)
+
+// capabilities lists the capabilities of this build
+var capabilities = []string{
+	CapabilitySessionTelemetry,
+	CapabilityVADMode,
+	CapabilityAudioCapture,
+	CapabilityAudioChunking,
+	CapabilityAnamSendQueue,
+	CapabilityLoudness,
+	CapabilityAdaptiveGate,
+	CapabilityPlaceholderVideo,
+	CapabilityBotFanout,
+	CapabilityCaptions,
+}
+
+// requiredMessageTypes must be understood by every peer, whatever its version
+var requiredMessageTypes = []botipc.MessageType{
+	botipc.MessageTypeSTART_SESSION,
+	botipc.MessageTypeSTOP_SESSION,
+	botipc.MessageTypeSTATUS_UPDATE,
+	botipc.MessageTypeLOG_MESSAGE,
+	botipc.MessageTypeERROR_RESPONSE,
+	botipc.MessageTypeACK,
+	botipc.MessageTypeNACK,
+	botipc.MessageTypeHELLO,
+}
+
+// Hello describes a peer: its protocol version, build and what it supports
+type Hello struct {
+	ProtocolVersion uint32
+	BuildID         string
+	MessageTypes    []botipc.MessageType
+	Capabilities    []string
+}
+
+// LocalHello returns the HELLO of this build. Parent and bot_worker are built
+// from the same schema, so every message type it defines is supported.
+func LocalHello() Hello {
+	types := make([]botipc.MessageType, 0, len(botipc.EnumNamesMessageType))
+	for msgType := range botipc.EnumNamesMessageType {
+		types = append(types, msgType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return Hello{
+		ProtocolVersion: ProtocolVersion,
+		BuildID:         BuildID,
+		MessageTypes:    types,
+		Capabilities:    append([]string(nil), capabilities...),
+	}
+}
+
+// Supports reports whether the peer understands a message type
+func (h Hello) Supports(msgType botipc.MessageType) bool {
+	for _, t := range h.MessageTypes {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCapability reports whether the peer understands an optional feature
+func (h Hello) HasCapability(capability string) bool {
+	for _, c := range h.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCompatible returns an error describing why a peer cannot be used
+func (h Hello) CheckCompatible() error {
+	if h.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("peer build %s speaks IPC protocol version %d, this build (%s) speaks version %d",
+			h.BuildID, h.ProtocolVersion, BuildID, ProtocolVersion)
+	}
+	for _, msgType := range requiredMessageTypes {
+		if !h.Supports(msgType) {
+			return fmt.Errorf("peer build %s does not support required message type %s", h.BuildID, msgType)
+		}
+	}
+	return nil
+}
+
+// BuildHelloMessage creates a HELLO message
+func BuildHelloMessage(hello Hello) []byte {
+	innerBuilder := flatbuffers.NewBuilder(256)
+
+	buildIDOffset := innerBuilder.CreateString(hello.BuildID)
+
+	capabilityOffsets := make([]flatbuffers.UOffsetT, len(hello.Capabilities))
+	for i, capability := range hello.Capabilities {
+		capabilityOffsets[i] = innerBuilder.CreateString(capability)
+	}
+	botipc.HelloPayloadStartCapabilitiesVector(innerBuilder, len(capabilityOffsets))
+	for i := len(capabilityOffsets) - 1; i >= 0; i-- {
+		innerBuilder.PrependUOffsetT(capabilityOffsets[i])
+	}
+	capabilitiesOffset := innerBuilder.EndVector(len(capabilityOffsets))
+
+	botipc.HelloPayloadStartMessageTypesVector(innerBuilder, len(hello.MessageTypes))
+	for i := len(hello.MessageTypes) - 1; i >= 0; i-- {
+		innerBuilder.PrependInt8(int8(hello.MessageTypes[i]))
+	}
+	messageTypesOffset := innerBuilder.EndVector(len(hello.MessageTypes))
+
+	botipc.HelloPayloadStart(innerBuilder)
+	botipc.HelloPayloadAddProtocolVersion(innerBuilder, hello.ProtocolVersion)
+	botipc.HelloPayloadAddBuildId(innerBuilder, buildIDOffset)
+	botipc.HelloPayloadAddMessageTypes(innerBuilder, messageTypesOffset)
+	botipc.HelloPayloadAddCapabilities(innerBuilder, capabilitiesOffset)
+	payloadOffset := botipc.HelloPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeHELLO, payloadBytes)
+}
+
+// ParseHelloPayload parses a HelloPayload
+func ParseHelloPayload(data []byte) Hello {
+	payload := botipc.GetRootAsHelloPayload(data, 0)
+
+	hello := Hello{
+		ProtocolVersion: payload.ProtocolVersion(),
+		BuildID:         string(payload.BuildId()),
+		MessageTypes:    make([]botipc.MessageType, payload.MessageTypesLength()),
+		Capabilities:    make([]string, payload.CapabilitiesLength()),
+	}
+	for i := range hello.MessageTypes {
+		hello.MessageTypes[i] = payload.MessageTypes(i)
+	}
+	for i := range hello.Capabilities {
+		hello.Capabilities[i] = string(payload.Capabilities(i))
+	}
+	return hello
+}
+
+// ReadHello reads the first message of a connection and checks that it is a
+// compatible HELLO. The caller bounds the wait (read deadline or timer).
+func ReadHello(reader *MessageReader) (Hello, error) {
+	msgBytes, err := reader.ReadMessage()
+	if err != nil {
+		return Hello{}, fmt.Errorf("no HELLO received: %w", err)
+	}
+
+	msgType, payloadBytes, err := ParseIPCMessage(msgBytes)
+	if err != nil {
+		return Hello{}, fmt.Errorf("invalid HELLO: %w", err)
+	}
+	if msgType != botipc.MessageTypeHELLO {
+		return Hello{}, fmt.Errorf("expected HELLO, got %s (peer predates IPC protocol version %d)", msgType, ProtocolVersion)
+	}
+
+	hello := ParseHelloPayload(payloadBytes)
+	return hello, hello.CheckCompatible()
+}
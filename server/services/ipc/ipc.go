@@ -8,9 +8,10 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
-	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
 	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
 )
 
 // MaxMessageSize is the maximum allowed message size (10MB)
@@ -18,8 +19,10 @@ const MaxMessageSize = 10 * 1024 * 1024
 
 // MessageWriter handles writing length-prefixed FlatBuffer messages
 type MessageWriter struct {
-	writer *bufio.Writer
-	mu     sync.Mutex
+	writer    *bufio.Writer
+	mu        sync.Mutex
+	capture   *CaptureWriter // Records written messages (nil if not teed)
+	direction Direction
 }
 
 // NewMessageWriter creates a new MessageWriter
@@ -29,6 +32,13 @@ func NewMessageWriter(w io.Writer) *MessageWriter {
 	}
 }
 
+// Tee records every message written from now on in a capture
+func (mw *MessageWriter) Tee(capture *CaptureWriter, direction Direction) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	mw.capture, mw.direction = capture, direction
+}
+
 // WriteMessage writes a length-prefixed FlatBuffer message
 // Format: [4 bytes big-endian length][payload bytes]
 func (mw *MessageWriter) WriteMessage(data []byte) error {
@@ -51,12 +61,16 @@ func (mw *MessageWriter) WriteMessage(data []byte) error {
 		return fmt.Errorf("failed to flush message: %w", err)
 	}
 
+	// Recorded under the lock so the capture keeps the wire order
+	mw.capture.Record(mw.direction, data)
 	return nil
 }
 
 // MessageReader handles reading length-prefixed FlatBuffer messages
 type MessageReader struct {
-	reader *bufio.Reader
+	reader    *bufio.Reader
+	capture   *CaptureWriter // Records read messages (nil if not teed)
+	direction Direction
 }
 
 // NewMessageReader creates a new MessageReader
@@ -66,6 +80,12 @@ func NewMessageReader(r io.Reader) *MessageReader {
 	}
 }
 
+// Tee records every message read from now on in a capture. Call it before
+// the reader is handed to another goroutine.
+func (mr *MessageReader) Tee(capture *CaptureWriter, direction Direction) {
+	mr.capture, mr.direction = capture, direction
+}
+
 // ReadMessage reads a length-prefixed FlatBuffer message
 // Returns the raw bytes which can be parsed with GetRootAsIPCMessage
 func (mr *MessageReader) ReadMessage() ([]byte, error) {
@@ -92,46 +112,183 @@ func (mr *MessageReader) ReadMessage() ([]byte, error) {
 		return nil, fmt.Errorf("failed to read message payload: %w", err)
 	}
 
+	mr.capture.Record(mr.direction, msgBuf)
 	return msgBuf, nil
 }
 
 // Helper functions to build common messages
 
+// AudioCapture asks a worker to record a session's audio to WAV files
+type AudioCapture struct {
+	Dir         string
+	MaxBytes    uint64        // Per file
+	MaxDuration time.Duration // Per file
+}
+
+// ParseAudioCapture returns the audio capture settings of a START_SESSION
+// payload, nil when capturing is disabled
+func ParseAudioCapture(payload *botipc.StartSessionPayload) *AudioCapture {
+	config := payload.AudioCapture(nil)
+	if config == nil {
+		return nil
+	}
+	return &AudioCapture{
+		Dir:         string(config.Dir()),
+		MaxBytes:    config.MaxBytes(),
+		MaxDuration: time.Duration(config.MaxSeconds()) * time.Second,
+	}
+}
+
+// Loudness asks a worker to normalize the loudness of a session's audio.
+// Zero levels select the worker's defaults.
+type Loudness struct {
+	Engine      string // "go" or "apm", "" for the worker's default
+	TargetDBFS  float32
+	MaxGainDB   float32
+	CeilingDBFS float32
+}
+
+// ParseLoudness returns the loudness settings of a START_SESSION payload, nil
+// when normalization is disabled
+func ParseLoudness(payload *botipc.StartSessionPayload) *Loudness {
+	config := payload.Loudness(nil)
+	if config == nil {
+		return nil
+	}
+	return &Loudness{
+		Engine:      string(config.Engine()),
+		TargetDBFS:  config.TargetDbfs(),
+		MaxGainDB:   config.MaxGainDb(),
+		CeilingDBFS: config.CeilingDbfs(),
+	}
+}
+
+// PlaceholderVideo asks a worker to publish a placeholder video under a
+// companion UID until the avatar's first frames arrive
+type PlaceholderVideo struct {
+	UID     uint32
+	Token   string
+	Source  string        // Image or GIF on the worker's host
+	Timeout time.Duration // Stop publishing if the avatar shows no video by then
+}
+
+// ParsePlaceholderVideo returns the placeholder video settings of a
+// START_SESSION payload, nil when the placeholder is disabled
+func ParsePlaceholderVideo(payload *botipc.StartSessionPayload) *PlaceholderVideo {
+	config := payload.PlaceholderVideo(nil)
+	if config == nil {
+		return nil
+	}
+	return &PlaceholderVideo{
+		UID:     config.Uid(),
+		Token:   string(config.Token()),
+		Source:  string(config.Source()),
+		Timeout: time.Duration(config.TimeoutMs()) * time.Millisecond,
+	}
+}
+
+// StartSession is the configuration a START_SESSION message carries
+type StartSession struct {
+	TaskID         string
+	AppID          string
+	Channel        string
+	BotUID         uint32
+	BotToken       string
+	PalabraUID     uint32
+	AnamAPIKey     string
+	AnamBaseURL    string
+	AnamAvatarID   string
+	AnamUID        uint32
+	AnamToken      string
+	TargetLanguage string
+	VADMode        string            // "" for the worker's default detector
+	FanoutGroup    string            // Sessions of a group share the Agora connection of their worker
+	Captions       bool              // Relay the captions of the target's data stream
+	TaskState      string            // Opaque, handed back in SESSION_LIST
+	ChunkMs        uint32            // 0 for the worker's default chunk duration
+	Loudness       *Loudness         // Nil disables loudness normalization
+	Placeholder    *PlaceholderVideo // Nil disables the placeholder video
+	Capture        *AudioCapture     // Nil disables audio capture
+}
+
 // BuildStartSessionMessage creates a START_SESSION message
-func BuildStartSessionMessage(
-	taskID, appID, channel string,
-	botUID uint32, botToken string,
-	palabraUID uint32,
-	anamAPIKey, anamBaseURL, anamAvatarID string,
-	anamUID uint32, anamToken string,
-	targetLanguage string,
-) []byte {
+func BuildStartSessionMessage(start StartSession) []byte {
 	// Build the StartSessionPayload
 	innerBuilder := flatbuffers.NewBuilder(1024)
 
-	taskIDOffset := innerBuilder.CreateString(taskID)
-	appIDOffset := innerBuilder.CreateString(appID)
-	channelOffset := innerBuilder.CreateString(channel)
-	botTokenOffset := innerBuilder.CreateString(botToken)
-	anamAPIKeyOffset := innerBuilder.CreateString(anamAPIKey)
-	anamBaseURLOffset := innerBuilder.CreateString(anamBaseURL)
-	anamAvatarIDOffset := innerBuilder.CreateString(anamAvatarID)
-	anamTokenOffset := innerBuilder.CreateString(anamToken)
-	targetLangOffset := innerBuilder.CreateString(targetLanguage)
+	taskIDOffset := innerBuilder.CreateString(start.TaskID)
+	appIDOffset := innerBuilder.CreateString(start.AppID)
+	channelOffset := innerBuilder.CreateString(start.Channel)
+	botTokenOffset := innerBuilder.CreateString(start.BotToken)
+	anamAPIKeyOffset := innerBuilder.CreateString(start.AnamAPIKey)
+	anamBaseURLOffset := innerBuilder.CreateString(start.AnamBaseURL)
+	anamAvatarIDOffset := innerBuilder.CreateString(start.AnamAvatarID)
+	anamTokenOffset := innerBuilder.CreateString(start.AnamToken)
+	targetLangOffset := innerBuilder.CreateString(start.TargetLanguage)
+	vadModeOffset := innerBuilder.CreateString(start.VADMode)
+	fanoutGroupOffset := innerBuilder.CreateString(start.FanoutGroup)
+	taskStateOffset := innerBuilder.CreateString(start.TaskState)
+
+	var captureOffset flatbuffers.UOffsetT
+	if start.Capture != nil {
+		dirOffset := innerBuilder.CreateString(start.Capture.Dir)
+		botipc.AudioCaptureConfigStart(innerBuilder)
+		botipc.AudioCaptureConfigAddDir(innerBuilder, dirOffset)
+		botipc.AudioCaptureConfigAddMaxBytes(innerBuilder, start.Capture.MaxBytes)
+		botipc.AudioCaptureConfigAddMaxSeconds(innerBuilder, uint32(start.Capture.MaxDuration/time.Second))
+		captureOffset = botipc.AudioCaptureConfigEnd(innerBuilder)
+	}
+
+	var loudnessOffset flatbuffers.UOffsetT
+	if start.Loudness != nil {
+		engineOffset := innerBuilder.CreateString(start.Loudness.Engine)
+		botipc.LoudnessConfigStart(innerBuilder)
+		botipc.LoudnessConfigAddEngine(innerBuilder, engineOffset)
+		botipc.LoudnessConfigAddTargetDbfs(innerBuilder, start.Loudness.TargetDBFS)
+		botipc.LoudnessConfigAddMaxGainDb(innerBuilder, start.Loudness.MaxGainDB)
+		botipc.LoudnessConfigAddCeilingDbfs(innerBuilder, start.Loudness.CeilingDBFS)
+		loudnessOffset = botipc.LoudnessConfigEnd(innerBuilder)
+	}
+
+	var placeholderOffset flatbuffers.UOffsetT
+	if start.Placeholder != nil {
+		tokenOffset := innerBuilder.CreateString(start.Placeholder.Token)
+		sourceOffset := innerBuilder.CreateString(start.Placeholder.Source)
+		botipc.PlaceholderVideoConfigStart(innerBuilder)
+		botipc.PlaceholderVideoConfigAddUid(innerBuilder, start.Placeholder.UID)
+		botipc.PlaceholderVideoConfigAddToken(innerBuilder, tokenOffset)
+		botipc.PlaceholderVideoConfigAddSource(innerBuilder, sourceOffset)
+		botipc.PlaceholderVideoConfigAddTimeoutMs(innerBuilder, uint32(start.Placeholder.Timeout/time.Millisecond))
+		placeholderOffset = botipc.PlaceholderVideoConfigEnd(innerBuilder)
+	}
 
 	botipc.StartSessionPayloadStart(innerBuilder)
 	botipc.StartSessionPayloadAddTaskId(innerBuilder, taskIDOffset)
 	botipc.StartSessionPayloadAddAppId(innerBuilder, appIDOffset)
 	botipc.StartSessionPayloadAddChannel(innerBuilder, channelOffset)
-	botipc.StartSessionPayloadAddBotUid(innerBuilder, botUID)
+	botipc.StartSessionPayloadAddBotUid(innerBuilder, start.BotUID)
 	botipc.StartSessionPayloadAddBotToken(innerBuilder, botTokenOffset)
-	botipc.StartSessionPayloadAddPalabraUid(innerBuilder, palabraUID)
+	botipc.StartSessionPayloadAddPalabraUid(innerBuilder, start.PalabraUID)
 	botipc.StartSessionPayloadAddAnamApiKey(innerBuilder, anamAPIKeyOffset)
 	botipc.StartSessionPayloadAddAnamBaseUrl(innerBuilder, anamBaseURLOffset)
 	botipc.StartSessionPayloadAddAnamAvatarId(innerBuilder, anamAvatarIDOffset)
-	botipc.StartSessionPayloadAddAnamUid(innerBuilder, anamUID)
+	botipc.StartSessionPayloadAddAnamUid(innerBuilder, start.AnamUID)
 	botipc.StartSessionPayloadAddAnamToken(innerBuilder, anamTokenOffset)
 	botipc.StartSessionPayloadAddTargetLanguage(innerBuilder, targetLangOffset)
+	botipc.StartSessionPayloadAddVadMode(innerBuilder, vadModeOffset)
+	if start.Capture != nil {
+		botipc.StartSessionPayloadAddAudioCapture(innerBuilder, captureOffset)
+	}
+	botipc.StartSessionPayloadAddChunkMs(innerBuilder, start.ChunkMs)
+	if start.Loudness != nil {
+		botipc.StartSessionPayloadAddLoudness(innerBuilder, loudnessOffset)
+	}
+	if start.Placeholder != nil {
+		botipc.StartSessionPayloadAddPlaceholderVideo(innerBuilder, placeholderOffset)
+	}
+	botipc.StartSessionPayloadAddFanoutGroup(innerBuilder, fanoutGroupOffset)
+	botipc.StartSessionPayloadAddCaptions(innerBuilder, start.Captions)
+	botipc.StartSessionPayloadAddTaskState(innerBuilder, taskStateOffset)
 	payloadOffset := botipc.StartSessionPayloadEnd(innerBuilder)
 	innerBuilder.Finish(payloadOffset)
 	payloadBytes := innerBuilder.FinishedBytes()
@@ -157,6 +314,57 @@ func BuildStopSessionMessage(taskID, reason string) []byte {
 	return buildIPCMessage(botipc.MessageTypeSTOP_SESSION, payloadBytes)
 }
 
+// BuildUpdateConfigMessage creates an UPDATE_CONFIG message. Zero values leave
+// the corresponding setting unchanged.
+func BuildUpdateConfigMessage(taskID string, vadThreshold, hangoverMs, prerollMs uint32) []byte {
+	innerBuilder := flatbuffers.NewBuilder(128)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+
+	botipc.UpdateConfigPayloadStart(innerBuilder)
+	botipc.UpdateConfigPayloadAddTaskId(innerBuilder, taskIDOffset)
+	botipc.UpdateConfigPayloadAddVadThreshold(innerBuilder, vadThreshold)
+	botipc.UpdateConfigPayloadAddHangoverMs(innerBuilder, hangoverMs)
+	botipc.UpdateConfigPayloadAddPrerollMs(innerBuilder, prerollMs)
+	payloadOffset := botipc.UpdateConfigPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeUPDATE_CONFIG, payloadBytes)
+}
+
+// BuildSessionCommandMessage creates a command message that only names the
+// session (PAUSE_FORWARDING, RESUME_FORWARDING)
+func BuildSessionCommandMessage(msgType botipc.MessageType, taskID string) []byte {
+	innerBuilder := flatbuffers.NewBuilder(128)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+
+	botipc.SessionCommandPayloadStart(innerBuilder)
+	botipc.SessionCommandPayloadAddTaskId(innerBuilder, taskIDOffset)
+	payloadOffset := botipc.SessionCommandPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(msgType, payloadBytes)
+}
+
+// BuildSwitchTargetMessage creates a SWITCH_TARGET message
+func BuildSwitchTargetMessage(taskID string, palabraUID uint32) []byte {
+	innerBuilder := flatbuffers.NewBuilder(128)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+
+	botipc.SwitchTargetPayloadStart(innerBuilder)
+	botipc.SwitchTargetPayloadAddTaskId(innerBuilder, taskIDOffset)
+	botipc.SwitchTargetPayloadAddPalabraUid(innerBuilder, palabraUID)
+	payloadOffset := botipc.SwitchTargetPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeSWITCH_TARGET, payloadBytes)
+}
+
 // BuildStatusMessage creates a STATUS_UPDATE message
 func BuildStatusMessage(taskID string, status botipc.SessionStatus, message string, anamUID uint32) []byte {
 	innerBuilder := flatbuffers.NewBuilder(256)
@@ -214,8 +422,251 @@ func BuildErrorMessage(taskID, errorCode, message string, fatal bool) []byte {
 	return buildIPCMessage(botipc.MessageTypeERROR_RESPONSE, payloadBytes)
 }
 
+// Events of AVATAR_VIDEO
+const (
+	AvatarVideoPlaceholderStarted = "placeholder_started" // The placeholder is published
+	AvatarVideoPlaceholderFailed  = "placeholder_failed"  // The placeholder could not be published
+	AvatarVideoReady              = "avatar_ready"        // The avatar's first frames arrived; the placeholder stopped
+	AvatarVideoTimeout            = "placeholder_timeout" // The avatar showed no video in time; the placeholder stopped
+)
+
+// AvatarVideoEvent reports a change of a session's placeholder video
+type AvatarVideoEvent struct {
+	Event   string        // See AvatarVideo*
+	Waited  time.Duration // Since the placeholder started
+	Message string        // AvatarVideoPlaceholderFailed only: error details
+}
+
+// BuildAvatarVideoMessage creates an AVATAR_VIDEO message
+func BuildAvatarVideoMessage(taskID string, event AvatarVideoEvent) []byte {
+	innerBuilder := flatbuffers.NewBuilder(256)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+	eventOffset := innerBuilder.CreateString(event.Event)
+	messageOffset := innerBuilder.CreateString(event.Message)
+
+	botipc.AvatarVideoPayloadStart(innerBuilder)
+	botipc.AvatarVideoPayloadAddTaskId(innerBuilder, taskIDOffset)
+	botipc.AvatarVideoPayloadAddEvent(innerBuilder, eventOffset)
+	botipc.AvatarVideoPayloadAddWaitedMs(innerBuilder, uint32(event.Waited/time.Millisecond))
+	botipc.AvatarVideoPayloadAddMessage(innerBuilder, messageOffset)
+	payloadOffset := botipc.AvatarVideoPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeAVATAR_VIDEO, payloadBytes)
+}
+
+// Caption is a caption of a translation stream, read by the bot from its
+// data stream
+type Caption struct {
+	UID        string // UID that sent the caption
+	Language   string
+	SegmentID  string // Partials and the final of a segment share it
+	Text       string
+	Final      bool          // false while the segment may still change
+	Start      time.Duration // Segment start in the stream's timeline
+	End        time.Duration
+	ReceivedAt time.Time
+}
+
+// BuildCaptionMessage creates a CAPTION message
+func BuildCaptionMessage(taskID string, caption Caption) []byte {
+	innerBuilder := flatbuffers.NewBuilder(512)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+	uidOffset := innerBuilder.CreateString(caption.UID)
+	languageOffset := innerBuilder.CreateString(caption.Language)
+	segmentIDOffset := innerBuilder.CreateString(caption.SegmentID)
+	textOffset := innerBuilder.CreateString(caption.Text)
+
+	botipc.CaptionPayloadStart(innerBuilder)
+	botipc.CaptionPayloadAddTaskId(innerBuilder, taskIDOffset)
+	botipc.CaptionPayloadAddUid(innerBuilder, uidOffset)
+	botipc.CaptionPayloadAddLanguage(innerBuilder, languageOffset)
+	botipc.CaptionPayloadAddSegmentId(innerBuilder, segmentIDOffset)
+	botipc.CaptionPayloadAddText(innerBuilder, textOffset)
+	botipc.CaptionPayloadAddFinal(innerBuilder, caption.Final)
+	botipc.CaptionPayloadAddStartMs(innerBuilder, uint32(caption.Start/time.Millisecond))
+	botipc.CaptionPayloadAddEndMs(innerBuilder, uint32(caption.End/time.Millisecond))
+	botipc.CaptionPayloadAddReceivedAtMs(innerBuilder, caption.ReceivedAt.UnixMilli())
+	payloadOffset := botipc.CaptionPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeCAPTION, payloadBytes)
+}
+
+// HTTPTiming describes an HTTP request made by a child process
+type HTTPTiming struct {
+	Endpoint   string
+	StatusCode int // 0 if the request failed without a response
+	Duration   time.Duration
+}
+
+// SessionMetrics holds the counters and live readings a child reports for one session
+type SessionMetrics struct {
+	FramesForwarded uint64           // Audio frames sent to Anam
+	VoiceSegments   uint64           // Speech segments started
+	VoiceEndCount   uint64           // voice_end signals sent to Anam
+	AnamHTTP        []HTTPTiming     // Anam API requests completed since the last report
+	FramesReceived  uint64           // Audio frames received from the Palabra UID
+	RMSAvg          uint32           // Mean frame RMS since the last report
+	RMSPeak         uint32           // Highest frame RMS since the last report
+	AnamSendErrors  uint64           // Failed WebSocket sends to Anam
+	WSRoundTrip     time.Duration    // Last Anam WebSocket ping round-trip time (0 if unknown)
+	SinceLastAudio  time.Duration    // Time since audio was last forwarded to Anam
+	AnamDropped     uint64           // Audio frames dropped because the Anam send queue was full
+	AnamQueueDepth  uint32           // Messages waiting in the Anam send queue
+	AnamQueuePeak   uint32           // Deepest the Anam send queue got since the last report
+	Gate            *GateDiagnostics // Estimates of the adaptive gate, nil for other detectors
+}
+
+// GateDiagnostics holds the adaptive gate's estimates a child reports
+type GateDiagnostics struct {
+	NoiseFloorDBFS  float32
+	SpeechLevelDBFS float32
+	ThresholdDBFS   float32 // Open threshold
+	Hangover        time.Duration
+	Preroll         time.Duration
+	Gap             time.Duration // Estimated gap between words
+	Ends            []GateEnd     // Latest segment ends, oldest first
+}
+
+// GateEnd records why the adaptive gate ended a speech segment
+type GateEnd struct {
+	At             time.Duration // Stream time of the end
+	Reason         string
+	Segment        time.Duration
+	Silence        time.Duration // Audio under the close threshold before the end
+	Hangover       time.Duration // In force
+	LevelDBFS      float32       // Of the last frame
+	ThresholdDBFS  float32       // Close threshold
+	NoiseFloorDBFS float32
+}
+
+// BuildMetricsMessage creates a METRICS message
+func BuildMetricsMessage(taskID string, metrics SessionMetrics) []byte {
+	innerBuilder := flatbuffers.NewBuilder(256)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+
+	timingOffsets := make([]flatbuffers.UOffsetT, len(metrics.AnamHTTP))
+	for i, timing := range metrics.AnamHTTP {
+		endpointOffset := innerBuilder.CreateString(timing.Endpoint)
+		botipc.HttpTimingStart(innerBuilder)
+		botipc.HttpTimingAddEndpoint(innerBuilder, endpointOffset)
+		botipc.HttpTimingAddStatusCode(innerBuilder, uint32(timing.StatusCode))
+		botipc.HttpTimingAddDurationMs(innerBuilder, uint32(timing.Duration.Milliseconds()))
+		timingOffsets[i] = botipc.HttpTimingEnd(innerBuilder)
+	}
+	botipc.MetricsPayloadStartAnamHttpVector(innerBuilder, len(timingOffsets))
+	for i := len(timingOffsets) - 1; i >= 0; i-- {
+		innerBuilder.PrependUOffsetT(timingOffsets[i])
+	}
+	anamHTTPOffset := innerBuilder.EndVector(len(timingOffsets))
+
+	var gateOffset flatbuffers.UOffsetT
+	if gate := metrics.Gate; gate != nil {
+		endOffsets := make([]flatbuffers.UOffsetT, len(gate.Ends))
+		for i, end := range gate.Ends {
+			reasonOffset := innerBuilder.CreateString(end.Reason)
+			botipc.GateEndStart(innerBuilder)
+			botipc.GateEndAddAtMs(innerBuilder, uint64(end.At.Milliseconds()))
+			botipc.GateEndAddReason(innerBuilder, reasonOffset)
+			botipc.GateEndAddSegmentMs(innerBuilder, uint32(end.Segment.Milliseconds()))
+			botipc.GateEndAddSilenceMs(innerBuilder, uint32(end.Silence.Milliseconds()))
+			botipc.GateEndAddHangoverMs(innerBuilder, uint32(end.Hangover.Milliseconds()))
+			botipc.GateEndAddLevelDbfs(innerBuilder, end.LevelDBFS)
+			botipc.GateEndAddThresholdDbfs(innerBuilder, end.ThresholdDBFS)
+			botipc.GateEndAddNoiseFloorDbfs(innerBuilder, end.NoiseFloorDBFS)
+			endOffsets[i] = botipc.GateEndEnd(innerBuilder)
+		}
+		botipc.GateDiagnosticsStartEndsVector(innerBuilder, len(endOffsets))
+		for i := len(endOffsets) - 1; i >= 0; i-- {
+			innerBuilder.PrependUOffsetT(endOffsets[i])
+		}
+		endsOffset := innerBuilder.EndVector(len(endOffsets))
+
+		botipc.GateDiagnosticsStart(innerBuilder)
+		botipc.GateDiagnosticsAddNoiseFloorDbfs(innerBuilder, gate.NoiseFloorDBFS)
+		botipc.GateDiagnosticsAddSpeechLevelDbfs(innerBuilder, gate.SpeechLevelDBFS)
+		botipc.GateDiagnosticsAddThresholdDbfs(innerBuilder, gate.ThresholdDBFS)
+		botipc.GateDiagnosticsAddHangoverMs(innerBuilder, uint32(gate.Hangover.Milliseconds()))
+		botipc.GateDiagnosticsAddPrerollMs(innerBuilder, uint32(gate.Preroll.Milliseconds()))
+		botipc.GateDiagnosticsAddGapMs(innerBuilder, uint32(gate.Gap.Milliseconds()))
+		botipc.GateDiagnosticsAddEnds(innerBuilder, endsOffset)
+		gateOffset = botipc.GateDiagnosticsEnd(innerBuilder)
+	}
+
+	botipc.MetricsPayloadStart(innerBuilder)
+	botipc.MetricsPayloadAddTaskId(innerBuilder, taskIDOffset)
+	botipc.MetricsPayloadAddFramesForwarded(innerBuilder, metrics.FramesForwarded)
+	botipc.MetricsPayloadAddVoiceSegments(innerBuilder, metrics.VoiceSegments)
+	botipc.MetricsPayloadAddVoiceEndCount(innerBuilder, metrics.VoiceEndCount)
+	botipc.MetricsPayloadAddAnamHttp(innerBuilder, anamHTTPOffset)
+	botipc.MetricsPayloadAddFramesReceived(innerBuilder, metrics.FramesReceived)
+	botipc.MetricsPayloadAddRmsAvg(innerBuilder, metrics.RMSAvg)
+	botipc.MetricsPayloadAddRmsPeak(innerBuilder, metrics.RMSPeak)
+	botipc.MetricsPayloadAddAnamSendErrors(innerBuilder, metrics.AnamSendErrors)
+	botipc.MetricsPayloadAddWsRttMs(innerBuilder, uint32(metrics.WSRoundTrip.Milliseconds()))
+	botipc.MetricsPayloadAddMsSinceAudio(innerBuilder, uint32(metrics.SinceLastAudio.Milliseconds()))
+	botipc.MetricsPayloadAddAnamDroppedFrames(innerBuilder, metrics.AnamDropped)
+	botipc.MetricsPayloadAddAnamQueueDepth(innerBuilder, metrics.AnamQueueDepth)
+	botipc.MetricsPayloadAddAnamQueuePeak(innerBuilder, metrics.AnamQueuePeak)
+	if metrics.Gate != nil {
+		botipc.MetricsPayloadAddGate(innerBuilder, gateOffset)
+	}
+	payloadOffset := botipc.MetricsPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeMETRICS, payloadBytes)
+}
+
+// BuildNodeRegisterMessage creates a REGISTER_NODE message
+func BuildNodeRegisterMessage(nodeID, hostname string, capacity uint32, token string) []byte {
+	innerBuilder := flatbuffers.NewBuilder(256)
+
+	nodeIDOffset := innerBuilder.CreateString(nodeID)
+	hostnameOffset := innerBuilder.CreateString(hostname)
+	tokenOffset := innerBuilder.CreateString(token)
+
+	botipc.NodeRegisterPayloadStart(innerBuilder)
+	botipc.NodeRegisterPayloadAddNodeId(innerBuilder, nodeIDOffset)
+	botipc.NodeRegisterPayloadAddHostname(innerBuilder, hostnameOffset)
+	botipc.NodeRegisterPayloadAddCapacity(innerBuilder, capacity)
+	botipc.NodeRegisterPayloadAddToken(innerBuilder, tokenOffset)
+	payloadOffset := botipc.NodeRegisterPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeREGISTER_NODE, payloadBytes)
+}
+
+// BuildNodeHeartbeatMessage creates a NODE_HEARTBEAT message
+func BuildNodeHeartbeatMessage(nodeID string, activeSessions uint32) []byte {
+	innerBuilder := flatbuffers.NewBuilder(128)
+
+	nodeIDOffset := innerBuilder.CreateString(nodeID)
+
+	botipc.NodeHeartbeatPayloadStart(innerBuilder)
+	botipc.NodeHeartbeatPayloadAddNodeId(innerBuilder, nodeIDOffset)
+	botipc.NodeHeartbeatPayloadAddActiveSessions(innerBuilder, activeSessions)
+	payloadOffset := botipc.NodeHeartbeatPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessage(botipc.MessageTypeNODE_HEARTBEAT, payloadBytes)
+}
+
 // buildIPCMessage wraps a payload in an IPCMessage
 func buildIPCMessage(msgType botipc.MessageType, payloadBytes []byte) []byte {
+	return buildIPCMessageWithID(msgType, payloadBytes, 0)
+}
+
+// buildIPCMessageWithID wraps a payload in an IPCMessage carrying a request ID
+func buildIPCMessageWithID(msgType botipc.MessageType, payloadBytes []byte, requestID uint64) []byte {
 	builder := flatbuffers.NewBuilder(len(payloadBytes) + 64)
 
 	// Create payload vector
@@ -229,6 +680,9 @@ func buildIPCMessage(msgType botipc.MessageType, payloadBytes []byte) []byte {
 	botipc.IPCMessageStart(builder)
 	botipc.IPCMessageAddMessageType(builder, msgType)
 	botipc.IPCMessageAddPayload(builder, payloadOffset)
+	if requestID != 0 {
+		botipc.IPCMessageAddRequestId(builder, requestID)
+	}
 	msg := botipc.IPCMessageEnd(builder)
 	builder.Finish(msg)
 
@@ -258,6 +712,21 @@ func ParseStopSessionPayload(data []byte) *botipc.StopSessionPayload {
 	return botipc.GetRootAsStopSessionPayload(data, 0)
 }
 
+// ParseUpdateConfigPayload parses an UpdateConfigPayload from bytes
+func ParseUpdateConfigPayload(data []byte) *botipc.UpdateConfigPayload {
+	return botipc.GetRootAsUpdateConfigPayload(data, 0)
+}
+
+// ParseSessionCommandPayload parses a SessionCommandPayload from bytes
+func ParseSessionCommandPayload(data []byte) *botipc.SessionCommandPayload {
+	return botipc.GetRootAsSessionCommandPayload(data, 0)
+}
+
+// ParseSwitchTargetPayload parses a SwitchTargetPayload from bytes
+func ParseSwitchTargetPayload(data []byte) *botipc.SwitchTargetPayload {
+	return botipc.GetRootAsSwitchTargetPayload(data, 0)
+}
+
 // ParseStatusPayload parses a StatusPayload from bytes
 func ParseStatusPayload(data []byte) *botipc.StatusPayload {
 	return botipc.GetRootAsStatusPayload(data, 0)
@@ -272,3 +741,97 @@ func ParseLogPayload(data []byte) *botipc.LogPayload {
 func ParseErrorPayload(data []byte) *botipc.ErrorPayload {
 	return botipc.GetRootAsErrorPayload(data, 0)
 }
+
+// ParseAvatarVideoPayload parses an AvatarVideoPayload, returning the task ID
+func ParseAvatarVideoPayload(data []byte) (string, AvatarVideoEvent) {
+	payload := botipc.GetRootAsAvatarVideoPayload(data, 0)
+	return string(payload.TaskId()), AvatarVideoEvent{
+		Event:   string(payload.Event()),
+		Waited:  time.Duration(payload.WaitedMs()) * time.Millisecond,
+		Message: string(payload.Message()),
+	}
+}
+
+// ParseCaptionPayload parses a CaptionPayload, returning the task ID
+func ParseCaptionPayload(data []byte) (string, Caption) {
+	payload := botipc.GetRootAsCaptionPayload(data, 0)
+	return string(payload.TaskId()), Caption{
+		UID:        string(payload.Uid()),
+		Language:   string(payload.Language()),
+		SegmentID:  string(payload.SegmentId()),
+		Text:       string(payload.Text()),
+		Final:      payload.Final(),
+		Start:      time.Duration(payload.StartMs()) * time.Millisecond,
+		End:        time.Duration(payload.EndMs()) * time.Millisecond,
+		ReceivedAt: time.UnixMilli(payload.ReceivedAtMs()),
+	}
+}
+
+// ParseMetricsPayload parses a MetricsPayload into SessionMetrics, returning the task ID
+func ParseMetricsPayload(data []byte) (string, SessionMetrics) {
+	payload := botipc.GetRootAsMetricsPayload(data, 0)
+
+	metrics := SessionMetrics{
+		FramesForwarded: payload.FramesForwarded(),
+		VoiceSegments:   payload.VoiceSegments(),
+		VoiceEndCount:   payload.VoiceEndCount(),
+		FramesReceived:  payload.FramesReceived(),
+		RMSAvg:          payload.RmsAvg(),
+		RMSPeak:         payload.RmsPeak(),
+		AnamSendErrors:  payload.AnamSendErrors(),
+		WSRoundTrip:     time.Duration(payload.WsRttMs()) * time.Millisecond,
+		SinceLastAudio:  time.Duration(payload.MsSinceAudio()) * time.Millisecond,
+		AnamDropped:     payload.AnamDroppedFrames(),
+		AnamQueueDepth:  payload.AnamQueueDepth(),
+		AnamQueuePeak:   payload.AnamQueuePeak(),
+	}
+
+	timing := new(botipc.HttpTiming)
+	for i := 0; i < payload.AnamHttpLength(); i++ {
+		if payload.AnamHttp(timing, i) {
+			metrics.AnamHTTP = append(metrics.AnamHTTP, HTTPTiming{
+				Endpoint:   string(timing.Endpoint()),
+				StatusCode: int(timing.StatusCode()),
+				Duration:   time.Duration(timing.DurationMs()) * time.Millisecond,
+			})
+		}
+	}
+
+	if gate := payload.Gate(nil); gate != nil {
+		metrics.Gate = &GateDiagnostics{
+			NoiseFloorDBFS:  gate.NoiseFloorDbfs(),
+			SpeechLevelDBFS: gate.SpeechLevelDbfs(),
+			ThresholdDBFS:   gate.ThresholdDbfs(),
+			Hangover:        time.Duration(gate.HangoverMs()) * time.Millisecond,
+			Preroll:         time.Duration(gate.PrerollMs()) * time.Millisecond,
+			Gap:             time.Duration(gate.GapMs()) * time.Millisecond,
+		}
+		end := new(botipc.GateEnd)
+		for i := 0; i < gate.EndsLength(); i++ {
+			if gate.Ends(end, i) {
+				metrics.Gate.Ends = append(metrics.Gate.Ends, GateEnd{
+					At:             time.Duration(end.AtMs()) * time.Millisecond,
+					Reason:         string(end.Reason()),
+					Segment:        time.Duration(end.SegmentMs()) * time.Millisecond,
+					Silence:        time.Duration(end.SilenceMs()) * time.Millisecond,
+					Hangover:       time.Duration(end.HangoverMs()) * time.Millisecond,
+					LevelDBFS:      end.LevelDbfs(),
+					ThresholdDBFS:  end.ThresholdDbfs(),
+					NoiseFloorDBFS: end.NoiseFloorDbfs(),
+				})
+			}
+		}
+	}
+
+	return string(payload.TaskId()), metrics
+}
+
+// ParseNodeRegisterPayload parses a NodeRegisterPayload from bytes
+func ParseNodeRegisterPayload(data []byte) *botipc.NodeRegisterPayload {
+	return botipc.GetRootAsNodeRegisterPayload(data, 0)
+}
+
+// ParseNodeHeartbeatPayload parses a NodeHeartbeatPayload from bytes
+func ParseNodeHeartbeatPayload(data []byte) *botipc.NodeHeartbeatPayload {
+	return botipc.GetRootAsNodeHeartbeatPayload(data, 0)
+}
@@ -0,0 +1,138 @@
+package ipc
+
+import (
+	"fmt"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// WorkerSocketPattern matches the Unix sockets local bot_workers listen on
+// inside the runtime directory
+const WorkerSocketPattern = "worker-*.sock"
+
+// SessionInfo describes a session hosted by a worker, as reported in SESSION_LIST
+type SessionInfo struct {
+	TaskID         string
+	Channel        string
+	TargetLanguage string
+	Status         botipc.SessionStatus
+	BotUID         uint32
+	PalabraUID     uint32
+	AnamUID        uint32
+	StartedAt      time.Time
+	TaskState      string // Server's task metadata, as received in START_SESSION
+}
+
+// SessionList is a worker's answer to LIST_SESSIONS
+type SessionList struct {
+	Pid         int
+	MaxSessions int
+	Sessions    []SessionInfo
+}
+
+// BuildListSessionsMessage creates a LIST_SESSIONS message
+func BuildListSessionsMessage() []byte {
+	return BuildSessionCommandMessage(botipc.MessageTypeLIST_SESSIONS, "")
+}
+
+// BuildSessionListMessage creates the SESSION_LIST answering a LIST_SESSIONS
+func BuildSessionListMessage(requestID uint64, list SessionList) []byte {
+	innerBuilder := flatbuffers.NewBuilder(512)
+
+	sessionOffsets := make([]flatbuffers.UOffsetT, len(list.Sessions))
+	for i, session := range list.Sessions {
+		taskIDOffset := innerBuilder.CreateString(session.TaskID)
+		channelOffset := innerBuilder.CreateString(session.Channel)
+		languageOffset := innerBuilder.CreateString(session.TargetLanguage)
+		taskStateOffset := innerBuilder.CreateString(session.TaskState)
+
+		botipc.SessionInfoStart(innerBuilder)
+		botipc.SessionInfoAddTaskId(innerBuilder, taskIDOffset)
+		botipc.SessionInfoAddChannel(innerBuilder, channelOffset)
+		botipc.SessionInfoAddTargetLanguage(innerBuilder, languageOffset)
+		botipc.SessionInfoAddStatus(innerBuilder, session.Status)
+		botipc.SessionInfoAddBotUid(innerBuilder, session.BotUID)
+		botipc.SessionInfoAddPalabraUid(innerBuilder, session.PalabraUID)
+		botipc.SessionInfoAddAnamUid(innerBuilder, session.AnamUID)
+		botipc.SessionInfoAddStartedAtMs(innerBuilder, session.StartedAt.UnixMilli())
+		botipc.SessionInfoAddTaskState(innerBuilder, taskStateOffset)
+		sessionOffsets[i] = botipc.SessionInfoEnd(innerBuilder)
+	}
+	botipc.SessionListPayloadStartSessionsVector(innerBuilder, len(sessionOffsets))
+	for i := len(sessionOffsets) - 1; i >= 0; i-- {
+		innerBuilder.PrependUOffsetT(sessionOffsets[i])
+	}
+	sessionsOffset := innerBuilder.EndVector(len(sessionOffsets))
+
+	botipc.SessionListPayloadStart(innerBuilder)
+	botipc.SessionListPayloadAddPid(innerBuilder, uint32(list.Pid))
+	botipc.SessionListPayloadAddMaxSessions(innerBuilder, uint32(list.MaxSessions))
+	botipc.SessionListPayloadAddSessions(innerBuilder, sessionsOffset)
+	payloadOffset := botipc.SessionListPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessageWithID(botipc.MessageTypeSESSION_LIST, payloadBytes, requestID)
+}
+
+// ParseSessionListPayload parses a SessionListPayload
+func ParseSessionListPayload(data []byte) SessionList {
+	payload := botipc.GetRootAsSessionListPayload(data, 0)
+
+	list := SessionList{
+		Pid:         int(payload.Pid()),
+		MaxSessions: int(payload.MaxSessions()),
+		Sessions:    make([]SessionInfo, payload.SessionsLength()),
+	}
+	var info botipc.SessionInfo
+	for i := range list.Sessions {
+		payload.Sessions(&info, i)
+		list.Sessions[i] = SessionInfo{
+			TaskID:         string(info.TaskId()),
+			Channel:        string(info.Channel()),
+			TargetLanguage: string(info.TargetLanguage()),
+			Status:         info.Status(),
+			BotUID:         info.BotUid(),
+			PalabraUID:     info.PalabraUid(),
+			AnamUID:        info.AnamUid(),
+			StartedAt:      time.UnixMilli(info.StartedAtMs()),
+			TaskState:      string(info.TaskState()),
+		}
+	}
+	return list
+}
+
+// ListSessions asks a worker that just completed the handshake for its
+// sessions. Messages the worker sent before answering (status, logs, metrics)
+// predate the list and are dropped. The caller bounds the wait (read deadline).
+func ListSessions(reader *MessageReader, writer *MessageWriter) (SessionList, error) {
+	const requestID = 1 // Nothing else is in flight on a fresh connection
+
+	request, err := WithRequestID(BuildListSessionsMessage(), requestID)
+	if err != nil {
+		return SessionList{}, err
+	}
+	if err := writer.WriteMessage(request); err != nil {
+		return SessionList{}, fmt.Errorf("failed to send LIST_SESSIONS: %w", err)
+	}
+
+	for {
+		msgBytes, err := reader.ReadMessage()
+		if err != nil {
+			return SessionList{}, fmt.Errorf("no SESSION_LIST received: %w", err)
+		}
+		msgType, payloadBytes, err := ParseIPCMessage(msgBytes)
+		if err != nil {
+			return SessionList{}, fmt.Errorf("invalid message: %w", err)
+		}
+
+		switch {
+		case msgType == botipc.MessageTypeSESSION_LIST && ParseRequestID(msgBytes) == requestID:
+			return ParseSessionListPayload(payloadBytes), nil
+		case msgType == botipc.MessageTypeNACK:
+			return SessionList{}, ParseResponse(msgType, ParseRequestID(msgBytes), payloadBytes).Err()
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// NACK error codes not taken from a session's own errors
+const (
+	NackUnknownSession = "UNKNOWN_SESSION"    // No session with the command's task ID
+	NackSessionExists  = "SESSION_EXISTS"     // START_SESSION for a task already running
+	NackAtCapacity     = "WORKER_AT_CAPACITY" // START_SESSION beyond -max-sessions
+	NackSessionEnded   = "SESSION_ENDED"      // Session ended before it connected
+	NackCommandFailed  = "COMMAND_FAILED"     // The command was applied and failed
+	NackUnsupported    = "UNSUPPORTED"        // Message type unknown to the worker
+)
+
+// Errors returned by Requests.Do
+var (
+	ErrRequestTimeout = errors.New("timed out waiting for response")
+	ErrConnectionLost = errors.New("connection lost before response")
+)
+
+// CommandError is the error of a NACKed command
+type CommandError struct {
+	Command botipc.MessageType
+	TaskID  string
+	Code    string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s rejected (%s): %s", e.Command, e.Code, e.Message)
+}
+
+// Response is an ACK or NACK received for a command
+type Response struct {
+	RequestID uint64
+	TaskID    string
+	Command   botipc.MessageType
+	OK        bool   // ACK
+	ErrorCode string // NACK only
+	Message   string // NACK only
+}
+
+// Err returns nil for an ACK and a *CommandError for a NACK
+func (r Response) Err() error {
+	if r.OK {
+		return nil
+	}
+	return &CommandError{Command: r.Command, TaskID: r.TaskID, Code: r.ErrorCode, Message: r.Message}
+}
+
+// Requests correlates commands sent over one connection with their ACK/NACK.
+// The connection's reader hands responses to Resolve.
+type Requests struct {
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan Response
+	lost    error // Set by FailAll; later requests fail immediately
+}
+
+// NewRequests creates an empty request table
+func NewRequests() *Requests {
+	return &Requests{pending: make(map[uint64]chan Response)}
+}
+
+// Do sends a command built by one of the Build*Message functions with a fresh
+// request ID and waits for its response. A NACK is returned as a *CommandError.
+func (r *Requests) Do(w *MessageWriter, msg []byte, timeout time.Duration) (Response, error) {
+	r.mu.Lock()
+	if r.lost != nil {
+		r.mu.Unlock()
+		return Response{}, r.lost
+	}
+	r.nextID++
+	id := r.nextID
+	ch := make(chan Response, 1)
+	r.pending[id] = ch
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+
+	request, err := WithRequestID(msg, id)
+	if err != nil {
+		return Response{}, err
+	}
+	if err := w.WriteMessage(request); err != nil {
+		return Response{}, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return Response{}, ErrConnectionLost
+		}
+		return resp, resp.Err()
+	case <-timer.C:
+		return Response{}, ErrRequestTimeout
+	}
+}
+
+// Resolve hands a response to the request waiting for it. It returns false if
+// nobody waits, e.g. because the request already timed out.
+func (r *Requests) Resolve(resp Response) bool {
+	r.mu.Lock()
+	ch, ok := r.pending[resp.RequestID]
+	delete(r.pending, resp.RequestID)
+	r.mu.Unlock()
+
+	if ok {
+		ch <- resp
+	}
+	return ok
+}
+
+// FailAll fails every pending and future request, once the connection is gone
+func (r *Requests) FailAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lost = ErrConnectionLost
+	for id, ch := range r.pending {
+		close(ch)
+		delete(r.pending, id)
+	}
+}
+
+// WithRequestID returns a copy of an IPC message carrying a request ID
+func WithRequestID(msg []byte, requestID uint64) ([]byte, error) {
+	msgType, payloadBytes, err := ParseIPCMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	return buildIPCMessageWithID(msgType, payloadBytes, requestID), nil
+}
+
+// ParseRequestID returns the request ID of an IPC message, 0 if it has none
+func ParseRequestID(data []byte) uint64 {
+	return botipc.GetRootAsIPCMessage(data, 0).RequestId()
+}
+
+// BuildAckMessage creates the ACK of a command
+func BuildAckMessage(requestID uint64, taskID string, command botipc.MessageType) []byte {
+	return buildResponseMessage(botipc.MessageTypeACK, requestID, taskID, command, "", "")
+}
+
+// BuildNackMessage creates the NACK of a command with its error details
+func BuildNackMessage(requestID uint64, taskID string, command botipc.MessageType, errorCode, message string) []byte {
+	return buildResponseMessage(botipc.MessageTypeNACK, requestID, taskID, command, errorCode, message)
+}
+
+func buildResponseMessage(msgType botipc.MessageType, requestID uint64, taskID string, command botipc.MessageType, errorCode, message string) []byte {
+	innerBuilder := flatbuffers.NewBuilder(256)
+
+	taskIDOffset := innerBuilder.CreateString(taskID)
+	errorCodeOffset := innerBuilder.CreateString(errorCode)
+	messageOffset := innerBuilder.CreateString(message)
+
+	botipc.AckPayloadStart(innerBuilder)
+	botipc.AckPayloadAddTaskId(innerBuilder, taskIDOffset)
+	botipc.AckPayloadAddCommand(innerBuilder, command)
+	botipc.AckPayloadAddErrorCode(innerBuilder, errorCodeOffset)
+	botipc.AckPayloadAddMessage(innerBuilder, messageOffset)
+	payloadOffset := botipc.AckPayloadEnd(innerBuilder)
+	innerBuilder.Finish(payloadOffset)
+	payloadBytes := innerBuilder.FinishedBytes()
+
+	return buildIPCMessageWithID(msgType, payloadBytes, requestID)
+}
+
+// ParseResponse parses an ACK or NACK message
+func ParseResponse(msgType botipc.MessageType, requestID uint64, data []byte) Response {
+	payload := botipc.GetRootAsAckPayload(data, 0)
+	return Response{
+		RequestID: requestID,
+		TaskID:    string(payload.TaskId()),
+		Command:   payload.Command(),
+		OK:        msgType == botipc.MessageTypeACK,
+		ErrorCode: string(payload.ErrorCode()),
+		Message:   string(payload.Message()),
+	}
+}
@@ -0,0 +1,198 @@
+package ipc
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// command is a command the fake worker read
+type command struct {
+	requestID uint64
+	taskID    string
+	msgType   botipc.MessageType
+}
+
+// fakeWorker returns a writer whose STOP_SESSION commands arrive on the
+// returned channel, standing in for a worker at the other end
+func fakeWorker(t *testing.T) (*MessageWriter, <-chan command) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	commands := make(chan command, 8)
+	reader := NewMessageReader(client)
+	go func() {
+		defer close(commands)
+		for {
+			msg, err := reader.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgType, payload, _ := ParseIPCMessage(msg)
+			commands <- command{
+				requestID: ParseRequestID(msg),
+				taskID:    string(ParseStopSessionPayload(payload).TaskId()),
+				msgType:   msgType,
+			}
+		}
+	}()
+	return NewMessageWriter(server), commands
+}
+
+// answer hands a response message to requests, as the connection's reader
+// does
+func answer(requests *Requests, msg []byte) bool {
+	msgType, payload, _ := ParseIPCMessage(msg)
+	return requests.Resolve(ParseResponse(msgType, ParseRequestID(msg), payload))
+}
+
+// result is what Requests.Do returned
+type result struct {
+	resp Response
+	err  error
+}
+
+// stop sends a STOP_SESSION for taskID in the background
+func stop(requests *Requests, w *MessageWriter, taskID string, timeout time.Duration) <-chan result {
+	done := make(chan result, 1)
+	go func() {
+		resp, err := requests.Do(w, BuildStopSessionMessage(taskID, "test"), timeout)
+		done <- result{resp, err}
+	}()
+	return done
+}
+
+func TestRequestsAck(t *testing.T) {
+	requests := NewRequests()
+	w, commands := fakeWorker(t)
+	done := stop(requests, w, "task-1", time.Second)
+
+	cmd := <-commands
+	if cmd.requestID == 0 || cmd.msgType != botipc.MessageTypeSTOP_SESSION || cmd.taskID != "task-1" {
+		t.Fatalf("worker read %+v, want a STOP_SESSION for task-1 with a request ID", cmd)
+	}
+	if !answer(requests, BuildAckMessage(cmd.requestID, cmd.taskID, cmd.msgType)) {
+		t.Fatal("nobody waited for the ACK")
+	}
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("Do: %v", r.err)
+	}
+	if !r.resp.OK || r.resp.TaskID != "task-1" || r.resp.Command != botipc.MessageTypeSTOP_SESSION {
+		t.Errorf("got %+v, want the ACK of the STOP_SESSION for task-1", r.resp)
+	}
+}
+
+func TestRequestsNack(t *testing.T) {
+	requests := NewRequests()
+	w, commands := fakeWorker(t)
+	done := stop(requests, w, "task-1", time.Second)
+
+	cmd := <-commands
+	answer(requests, BuildNackMessage(cmd.requestID, cmd.taskID, cmd.msgType, NackUnknownSession, "no such session"))
+
+	r := <-done
+	var nack *CommandError
+	if !errors.As(r.err, &nack) {
+		t.Fatalf("Do returned %v, want a *CommandError", r.err)
+	}
+	if nack.Code != NackUnknownSession || nack.Message != "no such session" || nack.TaskID != "task-1" {
+		t.Errorf("got %+v, want the NACK's code, message and task ID", nack)
+	}
+	if r.resp.OK {
+		t.Error("NACKed response reported OK")
+	}
+}
+
+func TestRequestsCorrelation(t *testing.T) {
+	requests := NewRequests()
+	w, commands := fakeWorker(t)
+	first := stop(requests, w, "task-1", time.Second)
+	cmd1 := <-commands
+	second := stop(requests, w, "task-2", time.Second)
+	cmd2 := <-commands
+
+	if cmd1.requestID == cmd2.requestID {
+		t.Fatalf("both commands carry request ID %d", cmd1.requestID)
+	}
+
+	// Answered out of order, each response reaches its own request
+	answer(requests, BuildNackMessage(cmd2.requestID, cmd2.taskID, cmd2.msgType, NackCommandFailed, "failed"))
+	answer(requests, BuildAckMessage(cmd1.requestID, cmd1.taskID, cmd1.msgType))
+
+	if r := <-first; r.err != nil || r.resp.TaskID != "task-1" {
+		t.Errorf("first request got %+v, %v, want the ACK for task-1", r.resp, r.err)
+	}
+	if r := <-second; r.err == nil || r.resp.TaskID != "task-2" {
+		t.Errorf("second request got %+v, %v, want the NACK for task-2", r.resp, r.err)
+	}
+}
+
+func TestRequestsTimeout(t *testing.T) {
+	requests := NewRequests()
+	w, commands := fakeWorker(t)
+	done := stop(requests, w, "task-1", 20*time.Millisecond)
+
+	cmd := <-commands
+	if r := <-done; !errors.Is(r.err, ErrRequestTimeout) {
+		t.Fatalf("Do returned %v, want %v", r.err, ErrRequestTimeout)
+	}
+	if answer(requests, BuildAckMessage(cmd.requestID, cmd.taskID, cmd.msgType)) {
+		t.Error("a late ACK was handed to the timed out request")
+	}
+}
+
+func TestRequestsResolveUnknown(t *testing.T) {
+	requests := NewRequests()
+	if answer(requests, BuildAckMessage(42, "task-1", botipc.MessageTypeSTOP_SESSION)) {
+		t.Error("Resolve accepted a response nobody waits for")
+	}
+}
+
+func TestRequestsFailAll(t *testing.T) {
+	requests := NewRequests()
+	w, commands := fakeWorker(t)
+	done := stop(requests, w, "task-1", time.Second)
+	<-commands
+
+	requests.FailAll()
+	if r := <-done; !errors.Is(r.err, ErrConnectionLost) {
+		t.Fatalf("pending request returned %v, want %v", r.err, ErrConnectionLost)
+	}
+
+	// Later requests fail without being sent
+	if _, err := requests.Do(w, BuildStopSessionMessage("task-2", "test"), time.Second); !errors.Is(err, ErrConnectionLost) {
+		t.Errorf("request after FailAll returned %v, want %v", err, ErrConnectionLost)
+	}
+	select {
+	case cmd := <-commands:
+		t.Errorf("worker read %+v after FailAll", cmd)
+	default:
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	msg := BuildStopSessionMessage("task-1", "test")
+	if id := ParseRequestID(msg); id != 0 {
+		t.Fatalf("message without request ID reports %d", id)
+	}
+
+	tagged, err := WithRequestID(msg, 7)
+	if err != nil {
+		t.Fatalf("WithRequestID: %v", err)
+	}
+	if id := ParseRequestID(tagged); id != 7 {
+		t.Errorf("got request ID %d, want 7", id)
+	}
+	msgType, payload, _ := ParseIPCMessage(tagged)
+	if msgType != botipc.MessageTypeSTOP_SESSION || string(ParseStopSessionPayload(payload).TaskId()) != "task-1" {
+		t.Errorf("tagged message lost its payload: %v for %q", msgType, ParseStopSessionPayload(payload).TaskId())
+	}
+}
@@ -0,0 +1,120 @@
+package ipc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// NodeHeartbeatInterval is how often a daemon node reports its load. The server
+// treats a node as lost after missing NodeHeartbeatTimeout worth of heartbeats.
+const (
+	NodeHeartbeatInterval = 5 * time.Second
+	NodeHeartbeatTimeout  = 3 * NodeHeartbeatInterval
+)
+
+// Address schemes accepted by ParseAddress
+const (
+	SchemeTCP  = "tcp"
+	SchemeUnix = "unix"
+	SchemeTLS  = "tls" // TCP with TLS, for nodes on untrusted networks
+)
+
+// ParseAddress splits a transport address of the form "tcp://host:port",
+// "tls://host:port" or "unix:///path/to.sock" into a network and address.
+// A bare "host:port" is treated as TCP.
+func ParseAddress(addr string) (network, address string, err error) {
+	if addr == "" {
+		return "", "", fmt.Errorf("empty transport address")
+	}
+
+	scheme, rest, found := strings.Cut(addr, "://")
+	if !found {
+		return SchemeTCP, addr, nil
+	}
+
+	switch scheme {
+	case SchemeTCP, SchemeUnix, SchemeTLS:
+		if rest == "" {
+			return "", "", fmt.Errorf("missing address in %q", addr)
+		}
+		return scheme, rest, nil
+	default:
+		return "", "", fmt.Errorf("unsupported transport scheme %q", scheme)
+	}
+}
+
+// Dial connects to a transport address (see ParseAddress). A tls:// server
+// is verified against config, nil for the system roots.
+func Dial(addr string, config *tls.Config) (net.Conn, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == SchemeTLS {
+		return tls.Dial(SchemeTCP, address, config)
+	}
+	return net.Dial(network, address)
+}
+
+// Listen opens a listener on a transport address (see ParseAddress). A
+// tls:// listener serves the certificate of config, which it requires.
+// A stale Unix socket file left behind by a previous run is removed first.
+func Listen(addr string, config *tls.Config) (net.Listener, error) {
+	network, address, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if network == SchemeTLS {
+		if config == nil || len(config.Certificates) == 0 {
+			return nil, fmt.Errorf("%s needs a TLS certificate", addr)
+		}
+		return tls.Listen(SchemeTCP, address, config)
+	}
+
+	if network == SchemeUnix {
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", address, err)
+		}
+	}
+
+	return net.Listen(network, address)
+}
+
+// ServerTLSConfig loads the certificate a tls:// listener serves, nil when
+// both files are unset
+func ServerTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// ClientTLSConfig trusts the CA certificates in caFile for tls:// servers,
+// in addition to the system roots. It returns nil when caFile is unset.
+func ClientTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS CA: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	return &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}, nil
+}
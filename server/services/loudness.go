@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	agoraservice "github.com/AgoraIO-Extensions/Agora-Golang-Server-SDK/v2/go_sdk/rtc"
+	"github.com/samyak-jain/agora_backend/services/audio"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Loudness engines a session can choose through StartSessionConfig.Loudness
+const (
+	LoudnessEngineGo  = "go"  // Pure-Go AGC and limiter in the bot's pipeline (default)
+	LoudnessEngineAPM = "apm" // The SDK's APM AGC ahead of the pipeline, then the pure-Go limiter
+)
+
+// DefaultLoudnessEngine is used when a session does not choose an engine
+const DefaultLoudnessEngine = LoudnessEngineGo
+
+// ValidLoudnessEngine reports whether engine names a loudness engine ("" selects the default)
+func ValidLoudnessEngine(engine string) bool {
+	switch engine {
+	case "", LoudnessEngineGo, LoudnessEngineAPM:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateLoudness returns an error unless l holds loudness settings a session
+// can choose
+func ValidateLoudness(l *ipc.Loudness) error {
+	if l == nil {
+		return nil
+	}
+	if !ValidLoudnessEngine(l.Engine) {
+		return fmt.Errorf("unknown loudness engine %q (expected %s or %s)", l.Engine, LoudnessEngineGo, LoudnessEngineAPM)
+	}
+	return loudnessConfig(l).Validate()
+}
+
+// checkLoudnessVADMode returns an error if loudness settings are given for a
+// session whose voice detector bypasses them. The sdk detector forwards the
+// speech the SDK's audio frame observer buffered, which never passes through
+// the pipeline's Loudness and Limit stages.
+func checkLoudnessVADMode(l *ipc.Loudness, vadMode string) error {
+	if l != nil && vadMode == VADModeSDK {
+		return fmt.Errorf("loudness normalization cannot be used with the %s voice detector", VADModeSDK)
+	}
+	return nil
+}
+
+// loudnessConfig converts the loudness settings of a session for the audio package
+func loudnessConfig(l *ipc.Loudness) audio.LoudnessConfig {
+	return audio.LoudnessConfig{
+		TargetDBFS:  float64(l.TargetDBFS),
+		MaxGainDB:   float64(l.MaxGainDB),
+		CeilingDBFS: float64(l.CeilingDBFS),
+	}
+}
+
+// apmModel returns the APM model the Agora service starts with
+// (AGORA_APM_MODEL), 0 when the service runs without APM. Sessions can only
+// use the apm loudness engine when it is set.
+func apmModel() int {
+	model, err := strconv.Atoi(os.Getenv("AGORA_APM_MODEL"))
+	if err != nil || model < 0 {
+		return 0
+	}
+	return model
+}
+
+// passthroughAPMConfig returns an APM configuration with every filter off.
+// With APM enabled, the SDK applies the service's configuration to every
+// remote track, so the target's audio must reach the observer untouched.
+func passthroughAPMConfig() *agoraservice.APMConfig {
+	config := agoraservice.NewAPMConfig()
+	config.AiNsConfig.AiNSEnabled = false
+	config.AiNsConfig.NsEnabled = false
+	config.AiAecConfig.Enabled = false
+	config.BghvsCConfig.Enabled = false
+	config.AgcConfig.Enabled = false
+	return config
+}
+
+// loudnessStage returns the pipeline stage normalizing the target's audio.
+// With the apm engine the SDK's AGC runs ahead of the pipeline and the stage
+// only limits the peaks; without APM the bot falls back to the pure-Go AGC.
+// It must be called after the Agora service is acquired.
+func (b *AgoraBot) loudnessStage() audio.Stage {
+	if b.loudnessEngine == LoudnessEngineAPM {
+		err := b.startAPM()
+		if err == nil {
+			b.log(botipc.LogLevelINFO, "Loudness: APM AGC, limiter ceiling=%g dBFS", b.loudness.WithDefaults().CeilingDBFS)
+			return audio.Limit(b.loudness.CeilingDBFS)
+		}
+		b.log(botipc.LogLevelWARN, "APM unavailable (%v), using the pure-Go AGC", err)
+	}
+	b.log(botipc.LogLevelINFO, "Loudness: %s", b.loudness)
+	return audio.Loudness(*b.loudness)
+}
+
+// startAPM creates the SDK audio processor that runs the AGC on the target's
+// audio. Its processed frames feed the pipeline. It runs no VAD, since the
+// sdk detector is refused with loudness (see checkLoudnessVADMode).
+func (b *AgoraBot) startAPM() error {
+	if apmModel() == 0 {
+		return fmt.Errorf("AGORA_APM_MODEL not set")
+	}
+
+	config := passthroughAPMConfig()
+	config.AgcConfig.Enabled = true
+	observer := &agoraservice.ExternalAudioProcessorObserver{
+		OnProcessedAudioFrame: func(_ *agoraservice.ExternalAudioProcessor, frame *agoraservice.AudioFrame, vadResultState agoraservice.VadState, vadResultFrame *agoraservice.AudioFrame) {
+			b.feed(frame, vadResultState, vadResultFrame)
+		},
+	}
+
+	processor := agoraservice.NewExternalAudioProcessor()
+	if ret := processor.Initialize(config, inputSampleRate, 1, nil, observer); ret != 0 {
+		processor.Release()
+		return fmt.Errorf("initialize failed, ret=%d", ret)
+	}
+	b.apm = processor
+	return nil
+}
+
+// releaseAPM releases the SDK audio processor, if any
+func (b *AgoraBot) releaseAPM() {
+	if b.apm != nil {
+		b.apm.Release()
+		b.apm = nil
+	}
+}
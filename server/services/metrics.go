@@ -0,0 +1,356 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/samyak-jain/agora_backend/services/audio"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Reasons a bot session ended, used as the "reason" label of
+// palabra_bot_sessions_stopped_total
+const (
+	stopReasonRequested   = "requested"    // Stopped through the API
+	stopReasonTimeout     = "timeout"      // Max session duration reached
+	stopReasonStartFailed = "start_failed" // Cleaned up after a failed start
+	stopReasonEnded       = "ended"        // Ended inside the worker (idle, target left, ...)
+	stopReasonWorkerLost  = "worker_lost"  // bot_worker crashed or remote node lost
+)
+
+// Worker kinds, used as the "kind" label of worker metrics
+const (
+	workerKindLocal  = "local"
+	workerKindRemote = "remote"
+)
+
+var (
+	palabraTasksStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "palabra_tasks_started_total",
+		Help: "Palabra translation tasks started.",
+	})
+	palabraTasksStopped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "palabra_tasks_stopped_total",
+		Help: "Palabra translation tasks stopped.",
+	})
+	palabraTaskFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "palabra_task_failures_total",
+		Help: "Palabra translation tasks that could not be started or stopped, by reason.",
+	}, []string{"reason"})
+
+	botSessionsStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "palabra_bot_sessions_started_total",
+		Help: "Bot sessions (avatar forwarders) started.",
+	})
+	botSessionsStopped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "palabra_bot_sessions_stopped_total",
+		Help: "Bot sessions that ended, by reason.",
+	}, []string{"reason"})
+	botSessionFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "palabra_bot_session_failures_total",
+		Help: "Bot session failures, by reason (lower-cased worker error code or manager failure).",
+	}, []string{"reason"})
+
+	botWorkerStarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "palabra_bot_worker_starts_total",
+		Help: "bot_worker processes spawned (local) or nodes registered (remote), including restarts.",
+	}, []string{"kind"})
+	botWorkerCrashes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "palabra_bot_worker_crashes_total",
+		Help: "bot_worker processes that exited unexpectedly (local) or nodes that were lost (remote).",
+	}, []string{"kind"})
+	botWorkerHandshakeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "palabra_bot_worker_handshake_failures_total",
+		Help: "bot_worker processes or nodes refused because their HELLO was missing or incompatible.",
+	}, []string{"kind"})
+	botSessionsReattached = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "palabra_bot_sessions_reattached_total",
+		Help: "Bot sessions adopted from bot_worker processes that outlived a server restart.",
+	})
+
+	palabraAPILatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "palabra_api_request_duration_seconds",
+		Help:    "Latency of Palabra API requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "code"})
+	anamAPILatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "anam_api_request_duration_seconds",
+		Help:    "Latency of Anam API requests made by bot_worker sessions.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "code"})
+)
+
+// observePalabraRequest records the latency of a Palabra API request
+func observePalabraRequest(endpoint string, start time.Time, resp *http.Response, err error) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	palabraAPILatency.WithLabelValues(endpoint, code).Observe(time.Since(start).Seconds())
+}
+
+// observeAnamRequests records Anam API latencies reported by a bot_worker
+func observeAnamRequests(timings []ipc.HTTPTiming) {
+	for _, timing := range timings {
+		code := "error"
+		if timing.StatusCode != 0 {
+			code = strconv.Itoa(timing.StatusCode)
+		}
+		anamAPILatency.WithLabelValues(timing.Endpoint, code).Observe(timing.Duration.Seconds())
+	}
+}
+
+// SessionTelemetry is the latest METRICS snapshot a worker reported for a session
+type SessionTelemetry struct {
+	ReportedAt       time.Time `json:"reportedAt"`
+	FramesReceived   uint64    `json:"framesReceived"`
+	FramesForwarded  uint64    `json:"framesForwarded"`
+	VoiceSegments    uint64    `json:"voiceSegments"`
+	VoiceEnds        uint64    `json:"voiceEnds"`
+	RMSAvg           uint32    `json:"rmsAvg"`  // Since the previous report
+	RMSPeak          uint32    `json:"rmsPeak"` // Since the previous report
+	AnamSendErrors   uint64    `json:"anamSendErrors"`
+	WSRoundTripMs    int64     `json:"wsRoundTripMs"`    // 0 until the first pong
+	SinceLastAudioMs int64     `json:"sinceLastAudioMs"` // As of ReportedAt
+
+	SendQueue *SendQueueTelemetry `json:"sendQueue,omitempty"` // Workers without capability anam_send_queue omit it
+	Gate      *GateTelemetry      `json:"gate,omitempty"`      // Sessions with vadMode "adaptive" only
+}
+
+// SendQueueTelemetry describes a session's Anam send queue
+type SendQueueTelemetry struct {
+	Depth         uint32 `json:"depth"`
+	Peak          uint32 `json:"peak"` // Since the previous report
+	DroppedFrames uint64 `json:"droppedFrames"`
+}
+
+// GateTelemetry describes the adaptive gate of a session
+type GateTelemetry struct {
+	NoiseFloorDBFS  float32         `json:"noiseFloorDbfs"`
+	SpeechLevelDBFS float32         `json:"speechLevelDbfs"` // -120 until speech was heard
+	ThresholdDBFS   float32         `json:"thresholdDbfs"`   // Open threshold
+	HangoverMs      int64           `json:"hangoverMs"`
+	PrerollMs       int64           `json:"prerollMs"`
+	GapMs           int64           `json:"gapMs"` // Estimated gap between words
+	Ends            []GateEndRecord `json:"ends"`  // Latest voice_end causes, oldest first
+}
+
+// GateEndRecord explains why the adaptive gate sent a voice_end
+type GateEndRecord struct {
+	AtMs           int64   `json:"atMs"` // Into the session's audio
+	Reason         string  `json:"reason"`
+	Explanation    string  `json:"explanation"`
+	SegmentMs      int64   `json:"segmentMs"`
+	SilenceMs      int64   `json:"silenceMs"`
+	HangoverMs     int64   `json:"hangoverMs"`
+	LevelDBFS      float32 `json:"levelDbfs"`
+	ThresholdDBFS  float32 `json:"thresholdDbfs"` // Close threshold
+	NoiseFloorDBFS float32 `json:"noiseFloorDbfs"`
+}
+
+func newGateTelemetry(gate *ipc.GateDiagnostics) *GateTelemetry {
+	telemetry := &GateTelemetry{
+		NoiseFloorDBFS:  gate.NoiseFloorDBFS,
+		SpeechLevelDBFS: gate.SpeechLevelDBFS,
+		ThresholdDBFS:   gate.ThresholdDBFS,
+		HangoverMs:      gate.Hangover.Milliseconds(),
+		PrerollMs:       gate.Preroll.Milliseconds(),
+		GapMs:           gate.Gap.Milliseconds(),
+		Ends:            make([]GateEndRecord, 0, len(gate.Ends)),
+	}
+	for _, end := range gate.Ends {
+		explanation := audio.GateEnd{
+			Reason:     end.Reason,
+			Segment:    end.Segment,
+			Silence:    end.Silence,
+			Hangover:   end.Hangover,
+			Level:      float64(end.LevelDBFS),
+			Threshold:  float64(end.ThresholdDBFS),
+			NoiseFloor: float64(end.NoiseFloorDBFS),
+		}.String()
+		telemetry.Ends = append(telemetry.Ends, GateEndRecord{
+			AtMs:           end.At.Milliseconds(),
+			Reason:         end.Reason,
+			Explanation:    explanation,
+			SegmentMs:      end.Segment.Milliseconds(),
+			SilenceMs:      end.Silence.Milliseconds(),
+			HangoverMs:     end.Hangover.Milliseconds(),
+			LevelDBFS:      end.LevelDBFS,
+			ThresholdDBFS:  end.ThresholdDBFS,
+			NoiseFloorDBFS: end.NoiseFloorDBFS,
+		})
+	}
+	return telemetry
+}
+
+func newSessionTelemetry(metrics ipc.SessionMetrics, reportedAt time.Time, sendQueue bool) SessionTelemetry {
+	telemetry := SessionTelemetry{
+		ReportedAt:       reportedAt,
+		FramesReceived:   metrics.FramesReceived,
+		FramesForwarded:  metrics.FramesForwarded,
+		VoiceSegments:    metrics.VoiceSegments,
+		VoiceEnds:        metrics.VoiceEndCount,
+		RMSAvg:           metrics.RMSAvg,
+		RMSPeak:          metrics.RMSPeak,
+		AnamSendErrors:   metrics.AnamSendErrors,
+		WSRoundTripMs:    metrics.WSRoundTrip.Milliseconds(),
+		SinceLastAudioMs: metrics.SinceLastAudio.Milliseconds(),
+	}
+	if sendQueue {
+		telemetry.SendQueue = &SendQueueTelemetry{
+			Depth:         metrics.AnamQueueDepth,
+			Peak:          metrics.AnamQueuePeak,
+			DroppedFrames: metrics.AnamDropped,
+		}
+	}
+	if metrics.Gate != nil {
+		telemetry.Gate = newGateTelemetry(metrics.Gate)
+	}
+	return telemetry
+}
+
+// botSessionCollector exposes gauges and per-session counters read from the
+// BotProcessManager at scrape time, so ended sessions drop out automatically
+type botSessionCollector struct {
+	manager *BotProcessManager
+
+	sessions        *prometheus.Desc
+	workers         *prometheus.Desc
+	framesReceived  *prometheus.Desc
+	framesForwarded *prometheus.Desc
+	voiceSegments   *prometheus.Desc
+	voiceEnds       *prometheus.Desc
+	rmsAvg          *prometheus.Desc
+	rmsPeak         *prometheus.Desc
+	anamSendErrors  *prometheus.Desc
+	wsRoundTrip     *prometheus.Desc
+	sinceLastAudio  *prometheus.Desc
+	droppedFrames   *prometheus.Desc
+	queueDepth      *prometheus.Desc
+	queuePeak       *prometheus.Desc
+	gateNoiseFloor  *prometheus.Desc
+	gateThreshold   *prometheus.Desc
+	gateHangover    *prometheus.Desc
+}
+
+func newBotSessionCollector(manager *BotProcessManager) *botSessionCollector {
+	sessionLabels := []string{"task_id", "channel", "language"}
+	return &botSessionCollector{
+		manager: manager,
+		sessions: prometheus.NewDesc("palabra_bot_sessions",
+			"Live bot sessions by status.", []string{"status"}, nil),
+		workers: prometheus.NewDesc("palabra_bot_workers",
+			"Running bot_worker processes (local) and registered nodes (remote).", []string{"kind"}, nil),
+		framesReceived: prometheus.NewDesc("palabra_session_audio_frames_received_total",
+			"Audio frames received from the Palabra UID by a live session.", sessionLabels, nil),
+		framesForwarded: prometheus.NewDesc("palabra_session_audio_frames_forwarded_total",
+			"Audio frames forwarded to Anam by a live session.", sessionLabels, nil),
+		voiceSegments: prometheus.NewDesc("palabra_session_voice_segments_total",
+			"Speech segments started by a live session.", sessionLabels, nil),
+		voiceEnds: prometheus.NewDesc("palabra_session_voice_end_total",
+			"voice_end signals sent to Anam by a live session.", sessionLabels, nil),
+		rmsAvg: prometheus.NewDesc("palabra_session_audio_rms_avg",
+			"Mean frame RMS of a live session's input over the last report interval.", sessionLabels, nil),
+		rmsPeak: prometheus.NewDesc("palabra_session_audio_rms_peak",
+			"Highest frame RMS of a live session's input over the last report interval.", sessionLabels, nil),
+		anamSendErrors: prometheus.NewDesc("palabra_session_anam_send_errors_total",
+			"Failed WebSocket sends to Anam by a live session.", sessionLabels, nil),
+		wsRoundTrip: prometheus.NewDesc("palabra_session_anam_ws_rtt_seconds",
+			"Last Anam WebSocket ping round-trip time of a live session.", sessionLabels, nil),
+		sinceLastAudio: prometheus.NewDesc("palabra_session_seconds_since_audio",
+			"Time since a live session last forwarded audio to Anam.", sessionLabels, nil),
+		droppedFrames: prometheus.NewDesc("palabra_session_anam_dropped_frames_total",
+			"Audio frames a live session dropped because its Anam send queue was full.", sessionLabels, nil),
+		queueDepth: prometheus.NewDesc("palabra_session_anam_queue_depth",
+			"Messages waiting in a live session's Anam send queue.", sessionLabels, nil),
+		queuePeak: prometheus.NewDesc("palabra_session_anam_queue_peak",
+			"Deepest a live session's Anam send queue got over the last report interval.", sessionLabels, nil),
+		gateNoiseFloor: prometheus.NewDesc("palabra_session_gate_noise_floor_dbfs",
+			"Noise floor the adaptive gate of a live session measured.", sessionLabels, nil),
+		gateThreshold: prometheus.NewDesc("palabra_session_gate_threshold_dbfs",
+			"Level the adaptive gate of a live session opens a segment at.", sessionLabels, nil),
+		gateHangover: prometheus.NewDesc("palabra_session_gate_hangover_seconds",
+			"Hangover the adaptive gate of a live session adapted to.", sessionLabels, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *botSessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sessions
+	ch <- c.workers
+	ch <- c.framesReceived
+	ch <- c.framesForwarded
+	ch <- c.voiceSegments
+	ch <- c.voiceEnds
+	ch <- c.rmsAvg
+	ch <- c.rmsPeak
+	ch <- c.anamSendErrors
+	ch <- c.wsRoundTrip
+	ch <- c.sinceLastAudio
+	ch <- c.droppedFrames
+	ch <- c.queueDepth
+	ch <- c.queuePeak
+	ch <- c.gateNoiseFloor
+	ch <- c.gateThreshold
+	ch <- c.gateHangover
+}
+
+// Collect implements prometheus.Collector
+func (c *botSessionCollector) Collect(ch chan<- prometheus.Metric) {
+	m := c.manager
+
+	m.mu.RLock()
+	procs := make([]*BotProcess, 0, len(m.processes))
+	for _, proc := range m.processes {
+		procs = append(procs, proc)
+	}
+	localWorkers, remoteWorkers := len(m.workers), len(m.nodes)
+	m.mu.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(c.workers, prometheus.GaugeValue, float64(localWorkers), workerKindLocal)
+	ch <- prometheus.MustNewConstMetric(c.workers, prometheus.GaugeValue, float64(remoteWorkers), workerKindRemote)
+
+	byStatus := make(map[botipc.SessionStatus]int)
+	for _, proc := range procs {
+		proc.mu.RLock()
+		status, metrics, metricsAt := proc.Status, proc.metrics, proc.metricsAt
+		proc.mu.RUnlock()
+
+		byStatus[status]++
+
+		labels := []string{proc.TaskID, proc.Channel, proc.Language}
+		ch <- prometheus.MustNewConstMetric(c.framesForwarded, prometheus.CounterValue, float64(metrics.FramesForwarded), labels...)
+		ch <- prometheus.MustNewConstMetric(c.voiceSegments, prometheus.CounterValue, float64(metrics.VoiceSegments), labels...)
+		ch <- prometheus.MustNewConstMetric(c.voiceEnds, prometheus.CounterValue, float64(metrics.VoiceEndCount), labels...)
+		if metricsAt.IsZero() || !proc.hasTelemetry() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.framesReceived, prometheus.CounterValue, float64(metrics.FramesReceived), labels...)
+		ch <- prometheus.MustNewConstMetric(c.rmsAvg, prometheus.GaugeValue, float64(metrics.RMSAvg), labels...)
+		ch <- prometheus.MustNewConstMetric(c.rmsPeak, prometheus.GaugeValue, float64(metrics.RMSPeak), labels...)
+		ch <- prometheus.MustNewConstMetric(c.anamSendErrors, prometheus.CounterValue, float64(metrics.AnamSendErrors), labels...)
+		ch <- prometheus.MustNewConstMetric(c.wsRoundTrip, prometheus.GaugeValue, metrics.WSRoundTrip.Seconds(), labels...)
+		// Extrapolate from the last report so a stalled worker shows up
+		ch <- prometheus.MustNewConstMetric(c.sinceLastAudio, prometheus.GaugeValue, (metrics.SinceLastAudio + time.Since(metricsAt)).Seconds(), labels...)
+		if gate := metrics.Gate; gate != nil {
+			ch <- prometheus.MustNewConstMetric(c.gateNoiseFloor, prometheus.GaugeValue, float64(gate.NoiseFloorDBFS), labels...)
+			ch <- prometheus.MustNewConstMetric(c.gateThreshold, prometheus.GaugeValue, float64(gate.ThresholdDBFS), labels...)
+			ch <- prometheus.MustNewConstMetric(c.gateHangover, prometheus.GaugeValue, gate.Hangover.Seconds(), labels...)
+		}
+		if !proc.hasSendQueue() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.droppedFrames, prometheus.CounterValue, float64(metrics.AnamDropped), labels...)
+		ch <- prometheus.MustNewConstMetric(c.queueDepth, prometheus.GaugeValue, float64(metrics.AnamQueueDepth), labels...)
+		ch <- prometheus.MustNewConstMetric(c.queuePeak, prometheus.GaugeValue, float64(metrics.AnamQueuePeak), labels...)
+	}
+
+	// Report every status so dashboards see zeros rather than gaps
+	for status, name := range botipc.EnumNamesSessionStatus {
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(byStatus[status]), name)
+	}
+}
@@ -7,19 +7,91 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"github.com/samyak-jain/agora_backend/services/audio"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
 	"github.com/samyak-jain/agora_backend/utils/rtctoken"
 	"github.com/spf13/viper"
 )
 
 // PalabraStartRequest represents the request to start translation
 type PalabraStartRequest struct {
-	Channel          string   `json:"channel"`
-	SourceUID        string   `json:"sourceUid"`
-	SourceName       string   `json:"sourceName"`       // NEW: User's display name
-	SourceLanguage   string   `json:"sourceLanguage"`
-	TargetLanguages  []string `json:"targetLanguages"`
+	Channel         string   `json:"channel"`
+	SourceUID       string   `json:"sourceUid"`
+	SourceName      string   `json:"sourceName"` // NEW: User's display name
+	SourceLanguage  string   `json:"sourceLanguage"`
+	TargetLanguages []string `json:"targetLanguages"`
+	VADMode         string   `json:"vadMode,omitempty"`      // Voice detector of the avatar bots (default: PALABRA_VAD_MODE)
+	CaptureAudio    bool     `json:"captureAudio,omitempty"` // Record the bots' audio to WAV files (always on with PALABRA_AUDIO_CAPTURE)
+	ChunkMs         uint32   `json:"chunkMs,omitempty"`      // Audio per message sent to Anam (default: PALABRA_AUDIO_CHUNK_MS)
+
+	Loudness *PalabraLoudness `json:"loudness,omitempty"` // Normalize the loudness of the bots' audio (default: PALABRA_LOUDNESS)
+	Fanout   *bool            `json:"fanout,omitempty"`   // One Agora connection for all the bots of the task (default: PALABRA_BOT_FANOUT)
+	Captions *bool            `json:"captions,omitempty"` // Relay the streams' captions to the channel's event stream (default: PALABRA_CAPTIONS)
+}
+
+// PalabraLoudness sets the loudness normalization of the avatar bots. Unset
+// fields take the PALABRA_LOUDNESS_* defaults.
+type PalabraLoudness struct {
+	Enabled     *bool   `json:"enabled,omitempty"` // Defaults to true, false turns off PALABRA_LOUDNESS
+	Engine      string  `json:"engine,omitempty"`  // "go" or "apm"
+	TargetDBFS  float32 `json:"targetDbfs,omitempty"`
+	MaxGainDB   float32 `json:"maxGainDb,omitempty"`
+	CeilingDBFS float32 `json:"ceilingDbfs,omitempty"`
+}
+
+// sessionLoudness returns the loudness settings for the bots of a request,
+// nil when normalization is off
+func sessionLoudness(req *PalabraLoudness) (*ipc.Loudness, error) {
+	enabled := viper.GetBool("PALABRA_LOUDNESS")
+	if req != nil {
+		enabled = req.Enabled == nil || *req.Enabled
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	loudness := &ipc.Loudness{
+		Engine:      viper.GetString("PALABRA_LOUDNESS_ENGINE"),
+		TargetDBFS:  float32(viper.GetFloat64("PALABRA_LOUDNESS_TARGET_DBFS")),
+		MaxGainDB:   float32(viper.GetFloat64("PALABRA_LOUDNESS_MAX_GAIN_DB")),
+		CeilingDBFS: float32(viper.GetFloat64("PALABRA_LOUDNESS_CEILING_DBFS")),
+	}
+	if req != nil {
+		if req.Engine != "" {
+			loudness.Engine = req.Engine
+		}
+		if req.TargetDBFS != 0 {
+			loudness.TargetDBFS = req.TargetDBFS
+		}
+		if req.MaxGainDB != 0 {
+			loudness.MaxGainDB = req.MaxGainDB
+		}
+		if req.CeilingDBFS != 0 {
+			loudness.CeilingDBFS = req.CeilingDBFS
+		}
+	}
+	return loudness, ValidateLoudness(loudness)
+}
+
+// sessionPlaceholder returns the placeholder video shown under uid while
+// an avatar warms up, nil when PALABRA_PLACEHOLDER_VIDEO is unset
+func sessionPlaceholder(uid uint32, token string) *ipc.PlaceholderVideo {
+	source := viper.GetString("PALABRA_PLACEHOLDER_VIDEO")
+	if source == "" {
+		return nil
+	}
+	timeout := time.Duration(viper.GetInt("PALABRA_PLACEHOLDER_TIMEOUT_SECONDS")) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultPlaceholderTimeout
+	}
+	return &ipc.PlaceholderVideo{UID: uid, Token: token, Source: source, Timeout: timeout}
 }
 
 // PalabraStopRequest represents the request to stop translation
@@ -37,13 +109,13 @@ type PalabraTranslation struct {
 
 // PalabraAPIRequest represents the payload sent to Palabra API
 type PalabraAPIRequest struct {
-	AgoraAppID        string               `json:"agoraAppId"`
-	Channel           string               `json:"channel"`
-	RemoteUID         string               `json:"remote_uid"`
-	LocalUID          string               `json:"local_uid"`
-	Token             string               `json:"token"`
+	AgoraAppID        string                 `json:"agoraAppId"`
+	Channel           string                 `json:"channel"`
+	RemoteUID         string                 `json:"remote_uid"`
+	LocalUID          string                 `json:"local_uid"`
+	Token             string                 `json:"token"`
 	SpeechRecognition map[string]interface{} `json:"speech_recognition"`
-	Translations      []PalabraTranslation `json:"translations"`
+	Translations      []PalabraTranslation   `json:"translations"`
 }
 
 // PalabraAPIResponse represents the response from Palabra API
@@ -61,8 +133,13 @@ type PalabraResponseData struct {
 
 // PalabraStreamInfo represents info about a translation stream
 type PalabraStreamInfo struct {
-	UID      string `json:"uid"`
-	Language string `json:"language"`
+	UID         string `json:"uid"` // UID to subscribe to: the Anam UID while the avatar plays, else the Palabra UID
+	Language    string `json:"language"`
+	FallbackUID string `json:"fallbackUid,omitempty"` // Palabra UID to switch to if the avatar fails (avatar streams only)
+	Mode        string `json:"mode"`                  // StreamModeConnecting, StreamModeAvatar or StreamModeAudio
+
+	// UID publishing a placeholder video while the avatar connects (see placeholder_video.go)
+	PlaceholderUID string `json:"placeholderUid,omitempty"`
 }
 
 // PalabraStartResponse represents the response for start translation
@@ -88,11 +165,11 @@ const (
 
 // TaskInfo represents an active translation task
 type TaskInfo struct {
-	TaskID      string
-	Streams     []PalabraStreamInfo
-	SourceUID   string
-	Channel     string
-	Language    string
+	TaskID    string
+	Streams   []PalabraStreamInfo
+	SourceUID string
+	Channel   string
+	Language  string
 }
 
 var (
@@ -112,6 +189,132 @@ func getNextAnamUID(channel string) uint32 {
 	return uid
 }
 
+// reserveAnamUID keeps getNextAnamUID from handing out a UID still used by a
+// session adopted after a server restart
+func reserveAnamUID(channel string, uid uint32) {
+	if uid < anamUIDBase {
+		return
+	}
+	if next, exists := channelAnamCounters[channel]; !exists || uid >= next {
+		channelAnamCounters[channel] = uid + 1
+	}
+}
+
+// sessionTask is the task metadata a bot session carries in its config. The
+// worker hands it back in SESSION_LIST, so a restarted server can rebuild the
+// task from its adopted sessions (see restoreTasks).
+type sessionTask struct {
+	TaskID          string              `json:"taskId"`
+	SourceUID       string              `json:"sourceUid"`
+	SourceName      string              `json:"sourceName,omitempty"`
+	TargetLanguages []string            `json:"targetLanguages"`
+	Streams         []PalabraStreamInfo `json:"streams"`
+	Index           int                 `json:"index"` // Stream the session plays
+	Captions        bool                `json:"captions"`
+	StartedAt       int64               `json:"startedAt"` // Unix ms, transcript timestamps are relative to it
+}
+
+// restoredTask is a task rebuilt from the sessions adopted after a restart
+type restoredTask struct {
+	task      sessionTask
+	channel   string
+	streams   []PalabraStreamInfo // Shared by the task's sessions, as in PalabraStart
+	sessions  map[string]int      // Adopted session -> stream it plays
+	streaming map[string]bool     // Adopted sessions already streaming
+}
+
+// restoreTasks rebuilds deduplication, avatar streams and caption relays for
+// the sessions adopted after a server restart. Sessions started by a server
+// predating task state are left out. Streams whose session did not survive
+// fall back to Palabra's audio.
+func restoreTasks(sessions map[string]*BotProcess) {
+	tasks := make(map[string]*restoredTask)
+	for sessionID, proc := range sessions {
+		proc.mu.RLock()
+		state, status := proc.config.TaskState, proc.Status
+		proc.mu.RUnlock()
+
+		var task sessionTask
+		if state == "" || json.Unmarshal([]byte(state), &task) != nil ||
+			task.Index < 0 || task.Index >= len(task.Streams) {
+			continue
+		}
+
+		restored, ok := tasks[task.TaskID]
+		if !ok {
+			restored = &restoredTask{
+				task:      task,
+				channel:   proc.Channel,
+				streams:   make([]PalabraStreamInfo, len(task.Streams)),
+				sessions:  make(map[string]int),
+				streaming: make(map[string]bool),
+			}
+			// Streams no adopted session plays are cleared, then fall back below
+			copy(restored.streams, task.Streams)
+			for i := range restored.streams {
+				restored.streams[i].Mode = ""
+			}
+			tasks[task.TaskID] = restored
+		}
+		if task.Index >= len(restored.streams) {
+			continue
+		}
+
+		info := task.Streams[task.Index]
+		switch status {
+		case botipc.SessionStatusSTREAMING:
+			info.Mode = StreamModeAvatar
+			info.PlaceholderUID = ""
+		case botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED, botipc.SessionStatusFAILED:
+			info.Mode = ""
+		}
+		restored.streams[task.Index] = info
+		restored.sessions[sessionID] = task.Index
+		restored.streaming[sessionID] = status == botipc.SessionStatusSTREAMING
+	}
+
+	for _, restored := range tasks {
+		for i := range restored.streams {
+			info := &restored.streams[i]
+			if info.Mode != "" {
+				continue
+			}
+			// No live session plays the avatar
+			info.Mode = StreamModeAudio
+			info.PlaceholderUID = ""
+			if info.FallbackUID != "" {
+				info.UID = info.FallbackUID
+			}
+		}
+
+		task := restored.task
+		for sessionID, index := range restored.sessions {
+			avatarStreams.track(sessionID, task.TaskID, restored.channel, restored.streams, index)
+			if restored.streaming[sessionID] {
+				avatarStreams.streaming(sessionID)
+			}
+			if task.Captions {
+				captionRelay.track(sessionID, task.TaskID, restored.channel, task.SourceUID, task.SourceName, time.UnixMilli(task.StartedAt))
+			}
+		}
+
+		for _, targetLang := range task.TargetLanguages {
+			taskKey := fmt.Sprintf("%s:%s:%s", restored.channel, task.SourceUID, targetLang)
+			if _, exists := activeTasksByKey[taskKey]; exists {
+				continue
+			}
+			activeTasksByKey[taskKey] = &TaskInfo{
+				TaskID:    task.TaskID,
+				Streams:   restored.streams,
+				SourceUID: task.SourceUID,
+				Channel:   restored.channel,
+				Language:  targetLang,
+			}
+		}
+		log.Info().Str("taskId", task.TaskID).Int("sessions", len(restored.sessions)).Msg("Restored task of adopted bot sessions")
+	}
+}
+
 // PalabraStart handles starting a translation task
 func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 	s.Logger.Info().Msg("Palabra start translation request received")
@@ -139,6 +342,42 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.VADMode == "" {
+		req.VADMode = viper.GetString("PALABRA_VAD_MODE")
+	}
+	if !ValidVADMode(req.VADMode) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid vadMode %q (expected %s, %s, %s or %s)", req.VADMode, VADModeEnergy, VADModeV2, VADModeSDK, VADModeAdaptive))
+		return
+	}
+
+	if req.ChunkMs == 0 {
+		req.ChunkMs = viper.GetUint32("PALABRA_AUDIO_CHUNK_MS")
+	}
+	if err := audio.ValidChunkDuration(time.Duration(req.ChunkMs) * time.Millisecond); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid chunkMs %d: %v", req.ChunkMs, err))
+		return
+	}
+
+	fanout := viper.GetBool("PALABRA_BOT_FANOUT")
+	if req.Fanout != nil {
+		fanout = *req.Fanout
+	}
+
+	captions := viper.GetBool("PALABRA_CAPTIONS")
+	if req.Captions != nil {
+		captions = *req.Captions
+	}
+
+	loudness, err := sessionLoudness(req.Loudness)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid loudness: %v", err))
+		return
+	}
+	if err := checkLoudnessVADMode(loudness, req.VADMode); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid loudness: %v", err))
+		return
+	}
+
 	// OPTIMIZATION: Check if task already exists for this (channel, sourceUid, targetLanguage)
 	// Prevent duplicate Palabra tasks for the same translation
 	for _, targetLang := range req.TargetLanguages {
@@ -154,7 +393,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 				"ok": true,
 				"data": map[string]interface{}{
 					"taskId":  existingTask.TaskID,
-					"streams": existingTask.Streams,
+					"streams": avatarStreams.snapshot(existingTask.Streams),
 				},
 			})
 			return
@@ -193,6 +432,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 	)
 	if err != nil {
 		s.Logger.Error().Err(err).Msg("Failed to generate task token")
+		palabraTaskFailures.WithLabelValues("token_error").Inc()
 		respondWithError(w, http.StatusInternalServerError, "Failed to generate task token")
 		return
 	}
@@ -213,6 +453,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 		)
 		if err != nil {
 			s.Logger.Error().Err(err).Msgf("Failed to generate translation token for UID %d", uid)
+			palabraTaskFailures.WithLabelValues("token_error").Inc()
 			respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to generate translation token for UID %d", uid))
 			return
 		}
@@ -236,6 +477,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 		streams[i] = PalabraStreamInfo{
 			UID:      fmt.Sprintf("%d", uid),
 			Language: lang,
+			Mode:     StreamModeAudio,
 		}
 	}
 
@@ -282,9 +524,12 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		},
 	}
+	requestStart := time.Now()
 	resp, err := client.Do(httpReq)
+	observePalabraRequest("start", requestStart, resp, err)
 	if err != nil {
 		s.Logger.Error().Err(err).Msg("Failed to call Palabra API")
+		palabraTaskFailures.WithLabelValues("api_unreachable").Inc()
 		respondWithError(w, http.StatusInternalServerError, "Failed to call Palabra API")
 		return
 	}
@@ -303,6 +548,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 	// Check if successful
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		s.Logger.Error().Int("status", resp.StatusCode).Str("body", string(body)).Msg("Palabra API returned error")
+		palabraTaskFailures.WithLabelValues("api_error").Inc()
 		respondWithJSON(w, http.StatusOK, PalabraStartResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Palabra API error: %s", string(body)),
@@ -314,6 +560,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 	var palabraResp PalabraAPIResponse
 	if err := json.Unmarshal(body, &palabraResp); err != nil {
 		s.Logger.Error().Err(err).Msg("Failed to parse Palabra API response")
+		palabraTaskFailures.WithLabelValues("invalid_response").Inc()
 		respondWithError(w, http.StatusInternalServerError, "Failed to parse API response")
 		return
 	}
@@ -325,14 +572,17 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 			errorMsg = "Unknown error"
 		}
 		s.Logger.Error().Str("error", errorMsg).Msg("Palabra API returned error")
+		palabraTaskFailures.WithLabelValues("api_error").Inc()
 		respondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Palabra API error: %s", errorMsg))
 		return
 	}
 
-	// Get task ID from response
+	// Get task ID from response. Transcript timestamps are relative to now.
 	taskID := palabraResp.Data.TaskID
+	taskStartedAt := time.Now()
 
 	s.Logger.Info().Str("taskId", taskID).Msg("Translation task started successfully")
+	palabraTasksStarted.Inc()
 
 	// NEW: Check if Anam is enabled
 	enableAnam := viper.GetBool("ENABLE_ANAM")
@@ -368,9 +618,6 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 					Str("botUID", botUID).
 					Msg("UID assignment for Anam avatar")
 
-				// Update stream UID immediately - client should subscribe to Anam UID, not Palabra
-				streams[i].UID = anamUID
-
 				// Generate token for Anam UID (Anam joins as this UID via init message)
 				anamToken, err := rtctoken.BuildTokenWithUID(
 					appID,
@@ -399,9 +646,29 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 					continue
 				}
 
+				// Placeholder UID = Anam UID + 400 (4400+), publishing a placeholder
+				// video until the avatar shows up
+				var placeholder *ipc.PlaceholderVideo
+				if viper.GetString("PALABRA_PLACEHOLDER_VIDEO") != "" {
+					placeholderUIDNum := anamUIDNum + placeholderUIDOffset
+					placeholderToken, err := rtctoken.BuildTokenWithUID(
+						appID,
+						appCertificate,
+						req.Channel,
+						placeholderUIDNum,
+						rtctoken.RolePublisher,
+						expireTime,
+					)
+					if err != nil {
+						s.Logger.Error().Err(err).Uint32("placeholderUID", placeholderUIDNum).Msg("Failed to generate placeholder token, starting without placeholder")
+					} else {
+						placeholder = sessionPlaceholder(placeholderUIDNum, placeholderToken)
+					}
+				}
+
 				// Use BotProcessManager to spawn isolated child process
 				// This prevents Agora SDK crashes from bringing down the HTTP server
-				botManager := GetBotProcessManager()
+				botManager := GetBotProcessManager(s.Logger)
 
 				// Get Anam configuration
 				anamAPIKey := viper.GetString("ANAM_API_KEY")
@@ -414,8 +681,34 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 				var palabraUIDNum uint32
 				fmt.Sscanf(palabraUID, "%d", &palabraUIDNum)
 
+				// Client should subscribe to Anam UID, not Palabra, and fall back
+				// to the Palabra UID if the avatar fails (see avatar_streams.go)
+				sessionID := fmt.Sprintf("%s-%d", taskID, i)
+				streams[i].UID = anamUID
+				streams[i].FallbackUID = palabraUID
+				streams[i].Mode = StreamModeConnecting
+				if placeholder != nil {
+					streams[i].PlaceholderUID = fmt.Sprintf("%d", placeholder.UID)
+				}
+				avatarStreams.track(sessionID, taskID, req.Channel, streams, i)
+				if captions {
+					captionRelay.track(sessionID, taskID, req.Channel, req.SourceUID, req.SourceName, taskStartedAt)
+				}
+
+				// Handed back by the worker if the server restarts
+				taskState, _ := json.Marshal(sessionTask{
+					TaskID:          taskID,
+					SourceUID:       req.SourceUID,
+					SourceName:      req.SourceName,
+					TargetLanguages: req.TargetLanguages,
+					Streams:         streams,
+					Index:           i,
+					Captions:        captions,
+					StartedAt:       taskStartedAt.UnixMilli(),
+				})
+
 				config := StartSessionConfig{
-					TaskID:         fmt.Sprintf("%s-%d", taskID, i),
+					TaskID:         sessionID,
 					AppID:          appID,
 					Channel:        req.Channel,
 					BotUID:         botUIDNum,
@@ -427,6 +720,18 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 					AnamUID:        anamUIDNum,
 					AnamToken:      anamToken,
 					TargetLanguage: stream.Language,
+					VADMode:        req.VADMode,
+					CaptureAudio:   req.CaptureAudio || viper.GetBool("PALABRA_AUDIO_CAPTURE"),
+					ChunkMs:        req.ChunkMs,
+					Loudness:       loudness,
+					Placeholder:    placeholder,
+					Captions:       captions,
+					TaskState:      string(taskState),
+				}
+				// The task's bots share one connection, demultiplexing by UID
+				if fanout {
+					config.FanoutGroup = taskID
+					config.FanoutSize = len(streams)
 				}
 
 				s.Logger.Info().
@@ -437,7 +742,8 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 
 				proc, err := botManager.StartSession(config)
 				if err != nil {
-					s.Logger.Error().Err(err).Str("anamUID", anamUID).Msg("Failed to start bot process")
+					s.Logger.Error().Err(err).Str("anamUID", anamUID).Msg("Failed to start bot process, falling back to Palabra audio")
+					avatarStreams.setMode(sessionID, StreamModeAudio, fallbackStartFailed)
 					continue
 				}
 
@@ -445,7 +751,8 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 					Str("palabraUID", palabraUID).
 					Str("anamUID", anamUID).
 					Str("botUID", botUID).
-					Int("pid", proc.cmd.Process.Pid).
+					Int("pid", proc.Pid()).
+					Str("node", proc.Node()).
 					Msg("Bot process started - isolated process handles Agora bot and Anam client")
 			}
 		}
@@ -471,7 +778,7 @@ func (s *ServiceRouter) PalabraStart(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, PalabraStartResponse{
 		Success: true,
 		TaskID:  taskID,
-		Streams: streams,
+		Streams: avatarStreams.snapshot(streams),
 	})
 }
 
@@ -526,9 +833,12 @@ func (s *ServiceRouter) PalabraStop(w http.ResponseWriter, r *http.Request) {
 			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		},
 	}
+	requestStart := time.Now()
 	resp, err := client.Do(httpReq)
+	observePalabraRequest("stop", requestStart, resp, err)
 	if err != nil {
 		s.Logger.Error().Err(err).Msg("Failed to call Palabra API")
+		palabraTaskFailures.WithLabelValues("stop_api_unreachable").Inc()
 		respondWithError(w, http.StatusInternalServerError, "Failed to call Palabra API")
 		return
 	}
@@ -547,6 +857,7 @@ func (s *ServiceRouter) PalabraStop(w http.ResponseWriter, r *http.Request) {
 	// Check if successful (200 or 204 are both success)
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		s.Logger.Error().Int("status", resp.StatusCode).Str("body", string(body)).Msg("Palabra API returned error")
+		palabraTaskFailures.WithLabelValues("stop_api_error").Inc()
 		respondWithJSON(w, http.StatusOK, PalabraStopResponse{
 			Success: false,
 			Error:   fmt.Sprintf("Palabra API error: %s", string(body)),
@@ -555,11 +866,16 @@ func (s *ServiceRouter) PalabraStop(w http.ResponseWriter, r *http.Request) {
 	}
 
 	s.Logger.Info().Str("taskId", req.TaskID).Msg("Translation task stopped successfully")
+	palabraTasksStopped.Inc()
+
+	// Stopping the sessions must not tell clients to fall back
+	avatarStreams.forgetTask(req.TaskID)
+	captionRelay.forgetTask(req.TaskID)
 
 	// Clean up bot processes if Anam is enabled
 	enableAnam := viper.GetBool("ENABLE_ANAM")
 	if enableAnam {
-		botManager := GetBotProcessManager()
+		botManager := GetBotProcessManager(s.Logger)
 
 		// Stop all sessions associated with this task ID
 		// Sessions are keyed as "taskID-index"
@@ -609,7 +925,10 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 func (s *ServiceRouter) PalabraTasks(w http.ResponseWriter, r *http.Request) {
 	tasks := make([]TaskInfo, 0)
 	for _, task := range activeTasksByKey {
-		tasks = append(tasks, *task)
+		info := *task
+		// The stream modes change as bot sessions report
+		info.Streams = avatarStreams.snapshot(task.Streams)
+		tasks = append(tasks, info)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -617,3 +936,473 @@ func (s *ServiceRouter) PalabraTasks(w http.ResponseWriter, r *http.Request) {
 		"tasks":   tasks,
 	})
 }
+
+// PalabraTaskHistory returns the status transition history of the bot sessions
+// of a task, live or recently ended
+func (s *ServiceRouter) PalabraTaskHistory(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	sessions := GetBotProcessManager(s.Logger).GetSessionHistory(taskID)
+	if len(sessions) == 0 {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("No bot sessions found for task %s", taskID))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"taskId":   taskID,
+		"sessions": sessions,
+	})
+}
+
+// PalabraControlRequest is the body of a session control request. Action is
+// one of update_config, pause, resume or switch_target.
+type PalabraControlRequest struct {
+	Action       string `json:"action"`
+	VADThreshold uint32 `json:"vadThreshold,omitempty"` // update_config; 0 keeps the current value
+	HangoverMs   uint32 `json:"hangoverMs,omitempty"`   // update_config; 0 keeps the current value
+	PrerollMs    uint32 `json:"prerollMs,omitempty"`    // update_config; 0 keeps the current value
+	PalabraUID   uint32 `json:"palabraUid,omitempty"`   // switch_target
+}
+
+// sessionControlResult is the outcome of a control request for one session
+type sessionControlResult struct {
+	TaskID  string `json:"taskId"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// PalabraTaskControl applies a runtime control command to the live bot
+// sessions of a task and reports the acknowledgement of each
+func (s *ServiceRouter) PalabraTaskControl(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	var req PalabraControlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	manager := GetBotProcessManager(s.Logger)
+	var apply func(sessionID string) error
+	switch req.Action {
+	case "update_config":
+		if req.VADThreshold == 0 && req.HangoverMs == 0 && req.PrerollMs == 0 {
+			respondWithError(w, http.StatusBadRequest, "update_config needs vadThreshold, hangoverMs or prerollMs")
+			return
+		}
+		apply = func(sessionID string) error {
+			return manager.UpdateSessionConfig(sessionID, req.VADThreshold, req.HangoverMs, req.PrerollMs)
+		}
+	case "pause", "resume":
+		apply = func(sessionID string) error {
+			return manager.SetSessionForwarding(sessionID, req.Action == "resume")
+		}
+	case "switch_target":
+		if req.PalabraUID == 0 {
+			respondWithError(w, http.StatusBadRequest, "switch_target needs palabraUid")
+			return
+		}
+		apply = func(sessionID string) error {
+			return manager.SwitchSessionTarget(sessionID, req.PalabraUID)
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown action %q", req.Action))
+		return
+	}
+
+	results := make([]sessionControlResult, 0)
+	success := true
+	for _, proc := range manager.FindSessions(taskID) {
+		if !proc.Active() {
+			continue
+		}
+		result := sessionControlResult{TaskID: proc.TaskID, Success: true}
+		if err := apply(proc.TaskID); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			success = false
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("No live bot sessions found for task %s", taskID))
+		return
+	}
+
+	s.Logger.Info().
+		Str("taskID", taskID).
+		Str("action", req.Action).
+		Bool("success", success).
+		Msg("[PALABRA-CONTROL] Applied control command")
+
+	code := http.StatusOK
+	if !success {
+		code = http.StatusBadGateway
+	}
+	respondWithJSON(w, code, map[string]interface{}{
+		"success":  success,
+		"taskId":   taskID,
+		"action":   req.Action,
+		"sessions": results,
+	})
+}
+
+// PalabraTaskStatus returns the status and latest worker telemetry of the bot
+// sessions of a task, live or recently ended
+func (s *ServiceRouter) PalabraTaskStatus(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	sessions := GetBotProcessManager(s.Logger).GetSessionStatus(taskID)
+	if len(sessions) == 0 {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("No bot sessions found for task %s", taskID))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"taskId":   taskID,
+		"sessions": sessions,
+	})
+}
+
+// streamEventsHeartbeat keeps idle event streams from being closed by proxies
+const streamEventsHeartbeat = 15 * time.Second
+
+// PalabraStreamEvents pushes the modes of the channel's avatar streams as
+// server-sent events: the current modes first, then every change. Clients
+// switch to fallbackUid when a stream falls back to audio. Captions of the
+// streams follow as "caption" events.
+func (s *ServiceRouter) PalabraStreamEvents(w http.ResponseWriter, r *http.Request) {
+	channel := mux.Vars(r)["channel"]
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	updates, current := avatarStreams.subscribe(channel)
+	defer avatarStreams.unsubscribe(updates)
+	captions := captionRelay.subscribe(channel)
+	defer captionRelay.unsubscribe(captions)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for _, update := range current {
+		writeStreamEvent(w, update)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamEventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				// Fell behind; the client reconnects and gets the current modes
+				return
+			}
+			writeStreamEvent(w, update)
+		case caption := <-captions:
+			writeCaptionEvent(w, caption)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// writeStreamEvent writes a stream mode as a "stream" server-sent event
+func writeStreamEvent(w http.ResponseWriter, update StreamUpdate) {
+	data, _ := json.Marshal(update)
+	fmt.Fprintf(w, "event: stream\ndata: %s\n\n", data)
+}
+
+// writeCaptionEvent writes a caption as a "caption" server-sent event
+func writeCaptionEvent(w http.ResponseWriter, caption CaptionEvent) {
+	data, _ := json.Marshal(caption)
+	fmt.Fprintf(w, "event: caption\ndata: %s\n\n", data)
+}
+
+// ChannelTranscripts exports the transcript of a channel's final captions,
+// merging its speakers into one timeline. Query parameters: lang, format
+// (json, srt, vtt or txt; default json), speaker (comma-separated UIDs,
+// default all) and labels (name speakers, default true).
+func (s *ServiceRouter) ChannelTranscripts(w http.ResponseWriter, r *http.Request) {
+	channel := mux.Vars(r)["channel"]
+	query := r.URL.Query()
+
+	language := query.Get("lang")
+	format := query.Get("format")
+	if format == "" {
+		format = TranscriptFormatJSON
+	}
+	if !ValidTranscriptFormat(format) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid format %q (expected %s, %s, %s or %s)", format, TranscriptFormatJSON, TranscriptFormatSRT, TranscriptFormatVTT, TranscriptFormatText))
+		return
+	}
+	// Subtitles mixing languages are of no use
+	if language == "" && format != TranscriptFormatJSON {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("lang is required for format %s", format))
+		return
+	}
+	var speakers []string
+	if speaker := query.Get("speaker"); speaker != "" {
+		speakers = strings.Split(speaker, ",")
+	}
+	labels := true
+	if value := query.Get("labels"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid labels %q", value))
+			return
+		}
+		labels = parsed
+	}
+
+	transcript, ok, err := getTranscripts().export(channel, language, speakers)
+	if err != nil {
+		s.Logger.Error().Err(err).Str("channel", channel).Msg("Failed to read transcript")
+		respondWithError(w, http.StatusInternalServerError, "Failed to read transcript")
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("No transcript for channel %s", channel))
+		return
+	}
+
+	switch format {
+	case TranscriptFormatJSON:
+		if !labels {
+			for i := range transcript.Segments {
+				transcript.Segments[i].Speaker = ""
+			}
+		}
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":    true,
+			"transcript": transcript,
+		})
+	case TranscriptFormatSRT:
+		w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+		writeSRT(w, transcript, labels)
+	case TranscriptFormatVTT:
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		writeVTT(w, transcript, labels)
+	case TranscriptFormatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeText(w, transcript, labels)
+	}
+}
+
+// PalabraCrashes lists the crash bundles written for bot_worker processes
+// that died unexpectedly, newest first
+func (s *ServiceRouter) PalabraCrashes(w http.ResponseWriter, r *http.Request) {
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	bundles, err := GetBotProcessManager(s.Logger).ListCrashBundles()
+	if err != nil {
+		s.Logger.Error().Err(err).Msg("Failed to list crash bundles")
+		respondWithError(w, http.StatusInternalServerError, "Failed to list crash bundles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"crashes": bundles,
+	})
+}
+
+// PalabraCrashDownload streams a crash bundle as a .tar.gz archive
+func (s *ServiceRouter) PalabraCrashDownload(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	// Build the archive in memory so errors can still be reported as JSON
+	var archive bytes.Buffer
+	if err := GetBotProcessManager(s.Logger).WriteCrashBundleArchive(name, &archive); err != nil {
+		if err == ErrCrashBundleNotFound {
+			respondWithError(w, http.StatusNotFound, fmt.Sprintf("Crash bundle %s not found", name))
+			return
+		}
+		s.Logger.Error().Err(err).Str("bundle", name).Msg("Failed to archive crash bundle")
+		respondWithError(w, http.StatusInternalServerError, "Failed to archive crash bundle")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+	w.WriteHeader(http.StatusOK)
+	archive.WriteTo(w)
+}
+
+// PalabraAudioCaptures lists the WAV captures of bot sessions that asked
+// for audio capture, newest first
+func (s *ServiceRouter) PalabraAudioCaptures(w http.ResponseWriter, r *http.Request) {
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	captures, err := GetBotProcessManager(s.Logger).ListAudioCaptures()
+	if err != nil {
+		s.Logger.Error().Err(err).Msg("Failed to list audio captures")
+		respondWithError(w, http.StatusInternalServerError, "Failed to list audio captures")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"captures": captures,
+	})
+}
+
+// PalabraAudioCaptureDownload serves an audio capture as a WAV file
+func (s *ServiceRouter) PalabraAudioCaptureDownload(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	file, err := GetBotProcessManager(s.Logger).OpenAudioCapture(name)
+	if err != nil {
+		if err == ErrAudioCaptureNotFound {
+			respondWithError(w, http.StatusNotFound, fmt.Sprintf("Audio capture %s not found", name))
+			return
+		}
+		s.Logger.Error().Err(err).Str("capture", name).Msg("Failed to open audio capture")
+		respondWithError(w, http.StatusInternalServerError, "Failed to open audio capture")
+		return
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil {
+		s.Logger.Error().Err(err).Str("capture", name).Msg("Failed to open audio capture")
+		respondWithError(w, http.StatusInternalServerError, "Failed to open audio capture")
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeContent(w, r, name, fi.ModTime(), file)
+}
+
+// sessionLogs is the non-follow response body of one session's logs
+type sessionLogs struct {
+	TaskID  string            `json:"taskId"`
+	Active  bool              `json:"active"`
+	Entries []SessionLogEntry `json:"entries"`
+}
+
+// PalabraTaskLogs returns the buffered logs of the bot sessions of a task.
+// With ?follow=true it streams the log of a single session as newline
+// delimited JSON until the session ends or the client disconnects.
+// ?tail=N limits the output to the last N entries per session.
+func (s *ServiceRouter) PalabraTaskLogs(w http.ResponseWriter, r *http.Request) {
+	taskID := mux.Vars(r)["id"]
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	tail, _ := strconv.Atoi(r.URL.Query().Get("tail"))
+
+	if !viper.GetBool("ENABLE_ANAM") {
+		respondWithError(w, http.StatusNotFound, "Bot sessions are not enabled")
+		return
+	}
+
+	procs := GetBotProcessManager(s.Logger).FindSessions(taskID)
+	if len(procs) == 0 {
+		respondWithError(w, http.StatusNotFound, fmt.Sprintf("No bot sessions found for task %s", taskID))
+		return
+	}
+
+	lastEntries := func(entries []SessionLogEntry) []SessionLogEntry {
+		if tail > 0 && len(entries) > tail {
+			return entries[len(entries)-tail:]
+		}
+		return entries
+	}
+
+	if !follow {
+		sessions := make([]sessionLogs, 0, len(procs))
+		for _, proc := range procs {
+			entries, _, _ := proc.Logs(0)
+			sessions = append(sessions, sessionLogs{
+				TaskID:  proc.TaskID,
+				Active:  proc.Active(),
+				Entries: lastEntries(entries),
+			})
+		}
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"success":  true,
+			"taskId":   taskID,
+			"sessions": sessions,
+		})
+		return
+	}
+
+	if len(procs) > 1 {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Task %s has %d sessions, follow a single session ID (e.g. %s)", taskID, len(procs), procs[0].TaskID))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	proc := procs[0]
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	entries, updated, ended := proc.Logs(0)
+	entries = lastEntries(entries)
+	var next uint64
+	for {
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return
+			}
+			next = entry.Seq + 1
+		}
+		flusher.Flush()
+
+		if ended {
+			return
+		}
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+		entries, updated, ended = proc.Logs(next)
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	agoraservice "github.com/AgoraIO-Extensions/Agora-Golang-Server-SDK/v2/go_sdk/rtc"
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+	"github.com/samyak-jain/agora_backend/services/video"
+)
+
+// Placeholder video settings. The placeholder UID is the Anam UID plus
+// placeholderUIDOffset (4400-4499), inside the range clients hide.
+const (
+	placeholderUIDOffset      = 400
+	DefaultPlaceholderTimeout = 30 * time.Second
+	placeholderWidth          = 640
+	placeholderHeight         = 360
+	placeholderFrameRate      = 10
+	placeholderBitrate        = 400 // kbps
+	placeholderConnectTimeout = 5 * time.Second
+)
+
+// AvatarVideoCallback is called when the placeholder video of a session
+// starts, fails or hands over to the avatar
+type AvatarVideoCallback func(taskID string, event ipc.AvatarVideoEvent)
+
+// placeholderVideo publishes a still or looping clip under a companion UID
+// until the first video frame of the avatar arrives, so listeners do not
+// stare at a blank tile while Anam warms up
+type placeholderVideo struct {
+	conn        *agoraservice.RtcConnection
+	clip        *video.Clip
+	uid         string
+	avatarUID   string
+	timeout     time.Duration
+	avatarFrame chan struct{} // Closed on the avatar's first frame
+	frameOnce   sync.Once
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	doneChan    chan struct{} // Closed once the placeholder is unpublished
+	logFunc     LogFunc
+	onEvent     func(event ipc.AvatarVideoEvent) // Told when the placeholder starts and hands over, not when stopped
+}
+
+// startPlaceholderVideo connects as the placeholder UID and publishes the
+// clip until the avatar shows video, the timeout runs out or Stop is called
+func startPlaceholderVideo(appID, channel string, avatarUID uint32, config *ipc.PlaceholderVideo, logFunc LogFunc, onEvent func(event ipc.AvatarVideoEvent)) (*placeholderVideo, error) {
+	clip, err := video.LoadClip(config.Source, placeholderWidth, placeholderHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load placeholder: %w", err)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPlaceholderTimeout
+	}
+
+	p := &placeholderVideo{
+		clip:        clip,
+		uid:         fmt.Sprintf("%d", config.UID),
+		avatarUID:   fmt.Sprintf("%d", avatarUID),
+		timeout:     timeout,
+		avatarFrame: make(chan struct{}),
+		stopChan:    make(chan struct{}),
+		doneChan:    make(chan struct{}),
+		logFunc:     logFunc,
+		onEvent:     onEvent,
+	}
+
+	acquireAgoraService(appID, p.log)
+
+	conCfg := &agoraservice.RtcConnectionConfig{
+		AutoSubscribeAudio: false,
+		AutoSubscribeVideo: false, // Only the avatar's video, to see when it starts
+		ClientRole:         agoraservice.ClientRoleBroadcaster,
+		ChannelProfile:     agoraservice.ChannelProfileLiveBroadcasting,
+	}
+	publishConfig := agoraservice.NewRtcConPublishConfig()
+	publishConfig.IsPublishAudio = false
+	publishConfig.IsPublishVideo = true
+	publishConfig.VideoPublishType = agoraservice.VideoPublishTypeYuv
+
+	p.conn = agoraservice.NewRtcConnection(conCfg, publishConfig)
+	if p.conn == nil {
+		releaseAgoraService(p.log)
+		return nil, fmt.Errorf("failed to create RTC connection")
+	}
+
+	p.conn.SetVideoEncoderConfiguration(&agoraservice.VideoEncoderConfiguration{
+		CodecType:         agoraservice.VideoCodecTypeH264,
+		Width:             placeholderWidth,
+		Height:            placeholderHeight,
+		Framerate:         placeholderFrameRate,
+		Bitrate:           placeholderBitrate,
+		MinBitrate:        -1,
+		OrientationMode:   agoraservice.OrientationModeFixedLandscape,
+		DegradePreference: agoraservice.DegradeMaintainFramerate,
+	})
+
+	connSignal := make(chan struct{}, 1)
+	p.conn.RegisterObserver(&agoraservice.RtcConnectionObserver{
+		OnConnected: func(con *agoraservice.RtcConnection, info *agoraservice.RtcConnectionInfo, reason int) {
+			select {
+			case connSignal <- struct{}{}:
+			default:
+			}
+		},
+		OnUserJoined: func(con *agoraservice.RtcConnection, uid string) {
+			if uid != p.avatarUID {
+				return
+			}
+			// The low stream is enough to see that the avatar shows video
+			ret := con.GetLocalUser().SubscribeVideo(uid, &agoraservice.VideoSubscriptionOptions{
+				StreamType: agoraservice.VideoStreamLow,
+			})
+			if ret != 0 {
+				p.log(botipc.LogLevelWARN, "Placeholder failed to subscribe to avatar video, ret=%d", ret)
+			}
+		},
+	})
+
+	p.conn.Connect(config.Token, channel, p.uid)
+	select {
+	case <-connSignal:
+	case <-time.After(placeholderConnectTimeout):
+		p.release()
+		return nil, fmt.Errorf("timeout connecting as UID %s", p.uid)
+	}
+
+	p.conn.RegisterVideoFrameObserver(&agoraservice.VideoFrameObserver{
+		OnFrame: func(channelId string, userId string, frame *agoraservice.VideoFrame) bool {
+			if userId == p.avatarUID {
+				p.frameOnce.Do(func() { close(p.avatarFrame) })
+			}
+			return true
+		},
+	})
+
+	if ret := p.conn.PublishVideo(); ret != 0 {
+		p.release()
+		return nil, fmt.Errorf("failed to publish video, ret=%d", ret)
+	}
+
+	p.log(botipc.LogLevelINFO, "Placeholder video published as UID %s (%d frames, timeout %v)", p.uid, clip.Frames(), timeout)
+	if p.onEvent != nil {
+		p.onEvent(ipc.AvatarVideoEvent{Event: ipc.AvatarVideoPlaceholderStarted})
+	}
+	go p.run()
+	return p, nil
+}
+
+// run pushes the clip's frames until the placeholder hands over or stops
+func (p *placeholderVideo) run() {
+	startedAt := time.Now()
+	ticker := time.NewTicker(time.Second / placeholderFrameRate)
+	defer ticker.Stop()
+	timeout := time.NewTimer(p.timeout)
+	defer timeout.Stop()
+
+	var event ipc.AvatarVideoEvent
+	p.pushFrame(0)
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			p.pushFrame(time.Since(startedAt))
+		case <-p.avatarFrame:
+			p.log(botipc.LogLevelINFO, "Avatar video arrived after %v, stopping placeholder", time.Since(startedAt).Round(time.Millisecond))
+			event.Event = ipc.AvatarVideoReady
+			break loop
+		case <-timeout.C:
+			p.log(botipc.LogLevelWARN, "No avatar video after %v, stopping placeholder", p.timeout)
+			event.Event = ipc.AvatarVideoTimeout
+			break loop
+		case <-p.stopChan:
+			break loop
+		}
+	}
+
+	// Unpublish first, so clients switching on the event find the avatar alone
+	p.conn.UnpublishVideo()
+	p.release()
+	close(p.doneChan)
+
+	if event.Event != "" && p.onEvent != nil {
+		event.Waited = time.Since(startedAt)
+		p.onEvent(event)
+	}
+}
+
+// pushFrame sends the clip's picture at elapsed
+func (p *placeholderVideo) pushFrame(elapsed time.Duration) {
+	ret := p.conn.PushVideoFrame(&agoraservice.ExternalVideoFrame{
+		Type:      agoraservice.VideoBufferRawData,
+		Format:    agoraservice.VideoPixelI420,
+		Buffer:    p.clip.FrameAt(elapsed),
+		Stride:    p.clip.Width,
+		Height:    p.clip.Height,
+		Timestamp: time.Now().UnixMilli(),
+	})
+	if ret != 0 {
+		p.log(botipc.LogLevelDEBUG, "Placeholder frame rejected, ret=%d", ret)
+	}
+}
+
+// release disconnects the placeholder UID
+func (p *placeholderVideo) release() {
+	p.conn.Disconnect()
+	p.conn.Release()
+	releaseAgoraService(p.log)
+}
+
+// Stop unpublishes the placeholder without reporting an event. It returns
+// once the connection is released.
+func (p *placeholderVideo) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopChan)
+	})
+	<-p.doneChan
+}
+
+// log writes a log line through logFunc, falling back to stdout
+func (p *placeholderVideo) log(level botipc.LogLevel, format string, args ...interface{}) {
+	if p.logFunc != nil {
+		p.logFunc(level, format, args...)
+		return
+	}
+	fmt.Printf("[Placeholder] "+format+"\n", args...)
+}
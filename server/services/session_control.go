@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// How long to wait for the bot_worker to ACK or NACK a control command
+const controlAckTimeout = 5 * time.Second
+
+// Errors returned by the session control methods
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrControlTimeout  = errors.New("timed out waiting for control acknowledgement")
+	ErrUnsupported     = errors.New("not supported by the bot_worker hosting the session")
+)
+
+// UpdateSessionConfig changes the voice detection settings of a live session.
+// Zero values leave a setting unchanged.
+func (m *BotProcessManager) UpdateSessionConfig(taskID string, vadThreshold, hangoverMs, prerollMs uint32) error {
+	msg := ipc.BuildUpdateConfigMessage(taskID, vadThreshold, hangoverMs, prerollMs)
+	return m.controlSession(taskID, botipc.MessageTypeUPDATE_CONFIG, msg)
+}
+
+// SetSessionForwarding pauses or resumes forwarding Palabra audio to the avatar
+func (m *BotProcessManager) SetSessionForwarding(taskID string, enabled bool) error {
+	command := botipc.MessageTypePAUSE_FORWARDING
+	if enabled {
+		command = botipc.MessageTypeRESUME_FORWARDING
+	}
+	return m.controlSession(taskID, command, ipc.BuildSessionCommandMessage(command, taskID))
+}
+
+// SwitchSessionTarget makes a live session forward the audio of another
+// Palabra UID
+func (m *BotProcessManager) SwitchSessionTarget(taskID string, palabraUID uint32) error {
+	msg := ipc.BuildSwitchTargetMessage(taskID, palabraUID)
+	if err := m.controlSession(taskID, botipc.MessageTypeSWITCH_TARGET, msg); err != nil {
+		return err
+	}
+
+	if proc, ok := m.GetSession(taskID); ok {
+		proc.mu.Lock()
+		proc.config.PalabraUID = palabraUID
+		proc.mu.Unlock()
+	}
+	return nil
+}
+
+// controlSession sends a control command to the worker hosting a session and
+// waits for its ACK or NACK
+func (m *BotProcessManager) controlSession(taskID string, command botipc.MessageType, msg []byte) error {
+	proc, ok := m.GetSession(taskID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	name := botipc.EnumNamesMessageType[command]
+	if !proc.worker.hello.Supports(command) {
+		return fmt.Errorf("%s: %w", name, ErrUnsupported)
+	}
+
+	proc.logger.Info().Str("command", name).Msg("Sending control command")
+	_, err := proc.worker.requests.Do(proc.worker.writer, msg, controlAckTimeout)
+
+	var nack *ipc.CommandError
+	switch {
+	case err == nil:
+		proc.logger.Info().Str("command", name).Msg("Control command acknowledged")
+		return nil
+	case errors.As(err, &nack):
+		proc.logger.Warn().Str("command", name).Str("errorCode", nack.Code).Msgf("Control command rejected: %s", nack.Message)
+		if nack.Code == ipc.NackUnknownSession {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("%s rejected: %s", name, nack.Message)
+	case errors.Is(err, ipc.ErrRequestTimeout):
+		proc.logger.Warn().Str("command", name).Msg("Control command not acknowledged in time")
+		return ErrControlTimeout
+	default:
+		proc.logger.Error().Err(err).Str("command", name).Msg("Failed to send control command")
+		return fmt.Errorf("failed to send %s: %w", name, err)
+	}
+}
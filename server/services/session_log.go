@@ -0,0 +1,133 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Max log entries kept per session; the oldest are dropped first
+const sessionLogLimit = 500
+
+// Sources of session log entries
+const (
+	logSourceManager = "manager"    // BotProcessManager messages about the session
+	logSourceWorker  = "bot_worker" // LOG_MESSAGE sent by the child for the session
+	logSourceStderr  = "stderr"     // stderr of a child hosting only this session
+)
+
+// SessionLogEntry is one line of a session's log
+type SessionLogEntry struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+}
+
+// sessionLog is a bounded log buffer of one session that readers can follow
+type sessionLog struct {
+	mu      sync.Mutex
+	entries []SessionLogEntry
+	nextSeq uint64
+	closed  bool
+	updated chan struct{} // Closed and replaced on every append, and on close
+}
+
+func newSessionLog() *sessionLog {
+	return &sessionLog{updated: make(chan struct{})}
+}
+
+// append adds an entry and wakes up followers
+func (l *sessionLog) append(level zerolog.Level, source, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+
+	l.entries = append(l.entries, SessionLogEntry{
+		Seq:     l.nextSeq,
+		Time:    time.Now(),
+		Level:   level.String(),
+		Source:  source,
+		Message: message,
+	})
+	l.nextSeq++
+	if len(l.entries) > sessionLogLimit {
+		l.entries = append(l.entries[:0], l.entries[len(l.entries)-sessionLogLimit:]...)
+	}
+
+	close(l.updated)
+	l.updated = make(chan struct{})
+}
+
+// close marks the end of the session's log; followers stop once drained
+func (l *sessionLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.updated)
+}
+
+// since returns the buffered entries with a sequence number >= seq, a channel
+// closed on the next change and whether the log is closed
+func (l *sessionLog) since(seq uint64) ([]SessionLogEntry, <-chan struct{}, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := len(l.entries)
+	for i, entry := range l.entries {
+		if entry.Seq >= seq {
+			start = i
+			break
+		}
+	}
+	return append([]SessionLogEntry(nil), l.entries[start:]...), l.updated, l.closed
+}
+
+// hook copies every message logged through a zerolog logger into the buffer
+func (l *sessionLog) hook(source string) zerolog.Hook {
+	return zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, message string) {
+		l.append(level, source, message)
+	})
+}
+
+// childLogLevel maps a child log level to its zerolog equivalent
+func childLogLevel(level botipc.LogLevel) zerolog.Level {
+	switch level {
+	case botipc.LogLevelDEBUG:
+		return zerolog.DebugLevel
+	case botipc.LogLevelWARN:
+		return zerolog.WarnLevel
+	case botipc.LogLevelERROR:
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.InfoLevel
+	}
+}
+
+// logChildOutput logs a line of child output with the session's fields and
+// records it in the session's log. proc is nil when the line cannot be
+// attributed to a single session.
+func (m *BotProcessManager) logChildOutput(worker *workerProcess, proc *BotProcess, level zerolog.Level, source, message string) {
+	logger := worker.logger
+	if proc != nil {
+		logger = proc.outputLogger
+		proc.logs.append(level, source, message)
+	}
+	logger.WithLevel(level).Str("source", source).Msg(message)
+}
+
+// Logs returns the session's buffered log entries with a sequence number >=
+// since, a channel closed when the log changes and whether the log has ended
+func (p *BotProcess) Logs(since uint64) ([]SessionLogEntry, <-chan struct{}, bool) {
+	return p.logs.since(since)
+}
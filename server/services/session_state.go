@@ -0,0 +1,235 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc"
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// Max transitions kept per session; the oldest are dropped first
+const sessionHistoryLimit = 64
+
+// Number of ended sessions whose history stays available through the API
+const finishedSessionsRetained = 100
+
+// sessionTransitions lists the statuses each status may move to. FAILED can be
+// entered from anywhere; a failed session may still report its teardown.
+var sessionTransitions = map[botipc.SessionStatus][]botipc.SessionStatus{
+	botipc.SessionStatusINITIALIZING:     {botipc.SessionStatusCONNECTING_ANAM, botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED},
+	botipc.SessionStatusCONNECTING_ANAM:  {botipc.SessionStatusCONNECTING_AGORA, botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED},
+	botipc.SessionStatusCONNECTING_AGORA: {botipc.SessionStatusCONNECTED, botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED},
+	botipc.SessionStatusCONNECTED:        {botipc.SessionStatusSTREAMING, botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED},
+	botipc.SessionStatusSTREAMING:        {botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED},
+	botipc.SessionStatusDISCONNECTING:    {botipc.SessionStatusDISCONNECTED},
+	botipc.SessionStatusDISCONNECTED:     {},
+	botipc.SessionStatusFAILED:           {botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED},
+}
+
+// validTransition reports whether a session may move from one status to another
+func validTransition(from, to botipc.SessionStatus) bool {
+	if to == botipc.SessionStatusFAILED {
+		return from != botipc.SessionStatusDISCONNECTED
+	}
+	for _, next := range sessionTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionTransition is one entry of a session's status history
+type SessionTransition struct {
+	From      string    `json:"from,omitempty"` // Empty for the initial status
+	To        string    `json:"to"`
+	At        time.Time `json:"at"`
+	ElapsedMs int64     `json:"elapsedMs"` // Time since the session was started
+	Message   string    `json:"message,omitempty"`
+	ErrorCode string    `json:"errorCode,omitempty"`
+	Rejected  bool      `json:"rejected,omitempty"` // Illegal transition, status left unchanged
+}
+
+// SessionHistory is a snapshot of a session and its status history
+type SessionHistory struct {
+	TaskID      string              `json:"taskId"`
+	Channel     string              `json:"channel"`
+	Language    string              `json:"language"`
+	Pid         int                 `json:"pid,omitempty"`
+	Node        string              `json:"node,omitempty"`
+	Status      string              `json:"status"`
+	Active      bool                `json:"active"`
+	StartTime   time.Time           `json:"startTime"`
+	Transitions []SessionTransition `json:"transitions"`
+}
+
+// SessionStatusInfo is a snapshot of a session's status and latest telemetry
+type SessionStatusInfo struct {
+	TaskID    string            `json:"taskId"`
+	Channel   string            `json:"channel"`
+	Language  string            `json:"language"`
+	Pid       int               `json:"pid,omitempty"`
+	Node      string            `json:"node,omitempty"`
+	Status    string            `json:"status"`
+	Active    bool              `json:"active"`
+	StartTime time.Time         `json:"startTime"`
+	AnamUID   uint32            `json:"anamUid,omitempty"`
+	Telemetry *SessionTelemetry `json:"telemetry,omitempty"` // Nil before the first METRICS report or for workers without session_telemetry
+}
+
+// transition moves the session to a new status if the state machine allows it.
+// Every report is recorded in the history, including rejected ones; reports of
+// the current status are recorded without changing anything.
+func (p *BotProcess) transition(to botipc.SessionStatus, message, errorCode string) (from botipc.SessionStatus, ok bool) {
+	p.mu.Lock()
+	from = p.Status
+	ok = from == to || validTransition(from, to)
+	if ok {
+		p.Status = to
+	}
+
+	now := time.Now()
+	p.history = append(p.history, SessionTransition{
+		From:      botipc.EnumNamesSessionStatus[from],
+		To:        botipc.EnumNamesSessionStatus[to],
+		At:        now,
+		ElapsedMs: now.Sub(p.StartTime).Milliseconds(),
+		Message:   message,
+		ErrorCode: errorCode,
+		Rejected:  !ok,
+	})
+	if len(p.history) > sessionHistoryLimit {
+		p.history = append(p.history[:0], p.history[len(p.history)-sessionHistoryLimit:]...)
+	}
+	p.mu.Unlock()
+
+	if ok && from != to && p.onTransition != nil {
+		p.onTransition(p, to, errorCode)
+	}
+	return from, ok
+}
+
+// Active reports whether the session is still in the active sessions map
+func (p *BotProcess) Active() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return !p.ended
+}
+
+// History returns a snapshot of the session and its status history
+func (p *BotProcess) History() SessionHistory {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return SessionHistory{
+		TaskID:      p.TaskID,
+		Channel:     p.Channel,
+		Language:    p.Language,
+		Pid:         p.Pid(),
+		Node:        p.Node(),
+		Status:      botipc.EnumNamesSessionStatus[p.Status],
+		Active:      !p.ended,
+		StartTime:   p.StartTime,
+		Transitions: append([]SessionTransition(nil), p.history...),
+	}
+}
+
+// StatusInfo returns a snapshot of the session's status and latest telemetry
+func (p *BotProcess) StatusInfo() SessionStatusInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	info := SessionStatusInfo{
+		TaskID:    p.TaskID,
+		Channel:   p.Channel,
+		Language:  p.Language,
+		Pid:       p.Pid(),
+		Node:      p.Node(),
+		Status:    botipc.EnumNamesSessionStatus[p.Status],
+		Active:    !p.ended,
+		StartTime: p.StartTime,
+		AnamUID:   p.AnamUID,
+	}
+	if !p.metricsAt.IsZero() && p.hasTelemetry() {
+		telemetry := newSessionTelemetry(p.metrics, p.metricsAt, p.hasSendQueue())
+		info.Telemetry = &telemetry
+	}
+	return info
+}
+
+// hasTelemetry reports whether the session's worker sends the live readings
+// of METRICS; older workers only send the counters
+func (p *BotProcess) hasTelemetry() bool {
+	return p.worker.hello.HasCapability(ipc.CapabilitySessionTelemetry)
+}
+
+// hasSendQueue reports whether the session's worker reports its Anam send queue
+func (p *BotProcess) hasSendQueue() bool {
+	return p.worker.hello.HasCapability(ipc.CapabilityAnamSendQueue)
+}
+
+// retainFinished keeps an ended session around so its history can still be
+// queried, evicting the oldest once the limit is reached.
+// Must be called with m.mu held.
+func (m *BotProcessManager) retainFinished(proc *BotProcess) {
+	proc.mu.Lock()
+	proc.ended = true
+	proc.mu.Unlock()
+
+	m.finished = append(m.finished, proc)
+	if len(m.finished) > finishedSessionsRetained {
+		m.finished = append(m.finished[:0], m.finished[len(m.finished)-finishedSessionsRetained:]...)
+	}
+}
+
+// FindSessions returns every live or recently ended session belonging to a
+// task, oldest first. taskID may be a session ID or a Palabra task ID, whose
+// sessions are named "taskID-index".
+func (m *BotProcessManager) FindSessions(taskID string) []*BotProcess {
+	matches := func(id string) bool {
+		return id == taskID || strings.HasPrefix(id, taskID+"-")
+	}
+
+	m.mu.RLock()
+	var procs []*BotProcess
+	for id, proc := range m.processes {
+		if matches(id) {
+			procs = append(procs, proc)
+		}
+	}
+	for _, proc := range m.finished {
+		if matches(proc.TaskID) {
+			procs = append(procs, proc)
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(procs, func(i, j int) bool {
+		return procs[i].StartTime.Before(procs[j].StartTime)
+	})
+	return procs
+}
+
+// GetSessionHistory returns the history of every live or recently ended session
+// belonging to a task (see FindSessions)
+func (m *BotProcessManager) GetSessionHistory(taskID string) []SessionHistory {
+	procs := m.FindSessions(taskID)
+	histories := make([]SessionHistory, 0, len(procs))
+	for _, proc := range procs {
+		histories = append(histories, proc.History())
+	}
+	return histories
+}
+
+// GetSessionStatus returns the status and latest telemetry of every live or
+// recently ended session belonging to a task (see FindSessions)
+func (m *BotProcessManager) GetSessionStatus(taskID string) []SessionStatusInfo {
+	procs := m.FindSessions(taskID)
+	statuses := make([]SessionStatusInfo, 0, len(procs))
+	for _, proc := range procs {
+		statuses = append(statuses, proc.StatusInfo())
+	}
+	return statuses
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/samyak-jain/agora_backend/services/ipc/botipc"
+)
+
+// testSession returns a session in status recording the transitions it
+// reports through onTransition
+func testSession(status botipc.SessionStatus) (*BotProcess, *[]botipc.SessionStatus) {
+	var reported []botipc.SessionStatus
+	proc := &BotProcess{
+		TaskID:    "task-1",
+		Status:    status,
+		StartTime: time.Now(),
+		onTransition: func(proc *BotProcess, to botipc.SessionStatus, errorCode string) {
+			reported = append(reported, to)
+		},
+	}
+	return proc, &reported
+}
+
+func TestValidTransition(t *testing.T) {
+	tests := []struct {
+		from, to botipc.SessionStatus
+		want     bool
+	}{
+		{botipc.SessionStatusINITIALIZING, botipc.SessionStatusCONNECTING_ANAM, true},
+		{botipc.SessionStatusCONNECTING_ANAM, botipc.SessionStatusCONNECTING_AGORA, true},
+		{botipc.SessionStatusCONNECTING_AGORA, botipc.SessionStatusCONNECTED, true},
+		{botipc.SessionStatusCONNECTED, botipc.SessionStatusSTREAMING, true},
+		{botipc.SessionStatusSTREAMING, botipc.SessionStatusDISCONNECTING, true},
+		{botipc.SessionStatusDISCONNECTING, botipc.SessionStatusDISCONNECTED, true},
+		{botipc.SessionStatusINITIALIZING, botipc.SessionStatusDISCONNECTED, true},
+
+		// FAILED can be entered from anywhere but the end, and still
+		// reports its teardown
+		{botipc.SessionStatusINITIALIZING, botipc.SessionStatusFAILED, true},
+		{botipc.SessionStatusSTREAMING, botipc.SessionStatusFAILED, true},
+		{botipc.SessionStatusDISCONNECTING, botipc.SessionStatusFAILED, true},
+		{botipc.SessionStatusDISCONNECTED, botipc.SessionStatusFAILED, false},
+		{botipc.SessionStatusFAILED, botipc.SessionStatusDISCONNECTED, true},
+		{botipc.SessionStatusFAILED, botipc.SessionStatusSTREAMING, false},
+
+		// No skipping ahead or going back
+		{botipc.SessionStatusINITIALIZING, botipc.SessionStatusSTREAMING, false},
+		{botipc.SessionStatusSTREAMING, botipc.SessionStatusCONNECTED, false},
+		{botipc.SessionStatusDISCONNECTED, botipc.SessionStatusINITIALIZING, false},
+	}
+	for _, tt := range tests {
+		if got := validTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("validTransition(%s, %s) = %v, want %v",
+				botipc.EnumNamesSessionStatus[tt.from], botipc.EnumNamesSessionStatus[tt.to], got, tt.want)
+		}
+	}
+}
+
+func TestTransition(t *testing.T) {
+	proc, reported := testSession(botipc.SessionStatusCONNECTED)
+
+	from, ok := proc.transition(botipc.SessionStatusSTREAMING, "streaming", "")
+	if !ok || from != botipc.SessionStatusCONNECTED {
+		t.Fatalf("transition returned %s, %v, want CONNECTED, true", botipc.EnumNamesSessionStatus[from], ok)
+	}
+	if proc.Status != botipc.SessionStatusSTREAMING {
+		t.Errorf("status is %s, want STREAMING", botipc.EnumNamesSessionStatus[proc.Status])
+	}
+	if len(*reported) != 1 || (*reported)[0] != botipc.SessionStatusSTREAMING {
+		t.Errorf("reported %v, want STREAMING", *reported)
+	}
+
+	entry := proc.history[len(proc.history)-1]
+	if entry.From != "CONNECTED" || entry.To != "STREAMING" || entry.Message != "streaming" || entry.Rejected {
+		t.Errorf("history entry %+v, want CONNECTED -> STREAMING", entry)
+	}
+}
+
+func TestTransitionRejected(t *testing.T) {
+	proc, reported := testSession(botipc.SessionStatusSTREAMING)
+
+	if _, ok := proc.transition(botipc.SessionStatusCONNECTING_ANAM, "late report", ""); ok {
+		t.Fatal("STREAMING -> CONNECTING_ANAM accepted")
+	}
+	if proc.Status != botipc.SessionStatusSTREAMING {
+		t.Errorf("rejected transition moved the session to %s", botipc.EnumNamesSessionStatus[proc.Status])
+	}
+	if len(*reported) != 0 {
+		t.Errorf("rejected transition reported %v", *reported)
+	}
+	if len(proc.history) != 1 || !proc.history[0].Rejected || proc.history[0].To != "CONNECTING_ANAM" {
+		t.Errorf("history %+v, want the rejected report recorded", proc.history)
+	}
+}
+
+func TestTransitionSameStatus(t *testing.T) {
+	proc, reported := testSession(botipc.SessionStatusSTREAMING)
+
+	if _, ok := proc.transition(botipc.SessionStatusSTREAMING, "still streaming", ""); !ok {
+		t.Fatal("report of the current status rejected")
+	}
+	if len(*reported) != 0 {
+		t.Errorf("report of the current status was passed on: %v", *reported)
+	}
+	if len(proc.history) != 1 || proc.history[0].Rejected {
+		t.Errorf("history %+v, want the report recorded", proc.history)
+	}
+}
+
+func TestTransitionFailedErrorCode(t *testing.T) {
+	var errorCode string
+	proc := &BotProcess{
+		Status: botipc.SessionStatusSTREAMING,
+		onTransition: func(proc *BotProcess, to botipc.SessionStatus, code string) {
+			errorCode = code
+		},
+	}
+
+	proc.transition(botipc.SessionStatusFAILED, "worker exited", "WORKER_LOST")
+	if errorCode != "WORKER_LOST" {
+		t.Errorf("reported error code %q, want WORKER_LOST", errorCode)
+	}
+	if entry := proc.history[0]; entry.ErrorCode != "WORKER_LOST" {
+		t.Errorf("history entry %+v, want the error code", entry)
+	}
+}
+
+func TestTransitionHistoryLimit(t *testing.T) {
+	proc, _ := testSession(botipc.SessionStatusSTREAMING)
+
+	reports := sessionHistoryLimit + 10
+	for i := 0; i < reports; i++ {
+		proc.transition(botipc.SessionStatusSTREAMING, fmt.Sprintf("report %d", i), "")
+	}
+
+	if len(proc.history) != sessionHistoryLimit {
+		t.Fatalf("history holds %d transitions, want %d", len(proc.history), sessionHistoryLimit)
+	}
+	if first := proc.history[0].Message; first != "report 10" {
+		t.Errorf("oldest kept transition is %q, want report 10", first)
+	}
+	if last := proc.history[len(proc.history)-1].Message; last != fmt.Sprintf("report %d", reports-1) {
+		t.Errorf("newest transition is %q, want report %d", last, reports-1)
+	}
+}
+
+func TestRetainFinished(t *testing.T) {
+	m := &BotProcessManager{}
+	var procs []*BotProcess
+	for i := 0; i < finishedSessionsRetained+5; i++ {
+		proc := &BotProcess{TaskID: fmt.Sprintf("task-%d", i)}
+		procs = append(procs, proc)
+		m.retainFinished(proc)
+	}
+
+	if len(m.finished) != finishedSessionsRetained {
+		t.Fatalf("kept %d ended sessions, want %d", len(m.finished), finishedSessionsRetained)
+	}
+	if m.finished[0] != procs[5] {
+		t.Errorf("oldest kept session is %s, want task-5", m.finished[0].TaskID)
+	}
+	if procs[0].Active() {
+		t.Error("ended session still reports active")
+	}
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// Transcript defaults, overridable through config
+const (
+	DefaultTranscriptDir    = "./transcripts"
+	DefaultTranscriptMaxAge = 30 * 24 * time.Hour
+	transcriptSweepInterval = time.Hour
+	transcriptExtension     = ".jsonl"
+	defaultSegmentDuration  = 2 * time.Second // Of captions without segment timing
+)
+
+// Transcript export formats
+const (
+	TranscriptFormatJSON = "json"
+	TranscriptFormatSRT  = "srt"
+	TranscriptFormatVTT  = "vtt"
+	TranscriptFormatText = "txt"
+)
+
+// TranscriptSegment is a final caption kept in a channel's transcript
+type TranscriptSegment struct {
+	TaskID     string `json:"taskId"`
+	SpeakerUID string `json:"speakerUid"`
+	Speaker    string `json:"speaker,omitempty"` // SourceName of the task, "" if it had none
+	Language   string `json:"language"`
+	Text       string `json:"text"`
+	StartMs    int64  `json:"startMs"` // Relative to the task start when stored, to the transcript start when exported
+	EndMs      int64  `json:"endMs"`
+
+	TaskStartedAt int64 `json:"taskStartedAt"` // Unix ms the task started
+}
+
+// label names the speaker of the segment
+func (s TranscriptSegment) label() string {
+	if s.Speaker != "" {
+		return s.Speaker
+	}
+	return s.SpeakerUID
+}
+
+// Transcript is the timeline of a channel's segments, merged across
+// speakers
+type Transcript struct {
+	Channel   string              `json:"channel"`
+	Language  string              `json:"language,omitempty"` // "" for all languages
+	StartedAt int64               `json:"startedAt"`          // Unix ms of the first task start, 0 without segments
+	Segments  []TranscriptSegment `json:"segments"`
+}
+
+// transcriptStore keeps one JSON Lines file of segments per channel
+type transcriptStore struct {
+	mu     sync.Mutex // Serializes appends
+	dir    string
+	maxAge time.Duration
+}
+
+var (
+	transcripts     *transcriptStore
+	transcriptsOnce sync.Once
+)
+
+// getTranscripts returns the transcript store, reading its config and
+// starting its sweeper on first use
+func getTranscripts() *transcriptStore {
+	transcriptsOnce.Do(func() {
+		dir := viper.GetString("PALABRA_TRANSCRIPT_DIR")
+		if dir == "" {
+			dir = DefaultTranscriptDir
+		}
+		maxAge := time.Duration(viper.GetInt("PALABRA_TRANSCRIPT_MAX_AGE_HOURS")) * time.Hour
+		if maxAge <= 0 {
+			maxAge = DefaultTranscriptMaxAge
+		}
+		transcripts = &transcriptStore{dir: dir, maxAge: maxAge}
+		go transcripts.sweep()
+	})
+	return transcripts
+}
+
+// path returns the file of a channel. Channel names may hold characters
+// unsafe in file names, so they are hex encoded.
+func (s *transcriptStore) path(channel string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(channel))+transcriptExtension)
+}
+
+// append adds a segment to the transcript of a channel
+func (s *transcriptStore) append(channel string, segment TranscriptSegment) error {
+	line, err := json.Marshal(segment)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	file, err := os.OpenFile(s.path(channel), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// read returns the segments of a channel in the order they were stored,
+// false if the channel has no transcript
+func (s *transcriptStore) read(channel string) ([]TranscriptSegment, bool, error) {
+	file, err := os.Open(s.path(channel))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	var segments []TranscriptSegment
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var segment TranscriptSegment
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &segment); err == nil {
+			segments = append(segments, segment)
+		}
+	}
+	return segments, true, scanner.Err()
+}
+
+// export merges the segments of a channel in language ("" for all) spoken
+// by speakers (nil for all) into one timeline starting with the earliest
+// task. It returns false if the channel has no transcript.
+func (s *transcriptStore) export(channel, language string, speakers []string) (Transcript, bool, error) {
+	segments, ok, err := s.read(channel)
+	if err != nil || !ok {
+		return Transcript{}, ok, err
+	}
+
+	transcript := Transcript{Channel: channel, Language: language, Segments: []TranscriptSegment{}}
+	for _, segment := range segments {
+		if language != "" && !strings.EqualFold(segment.Language, language) {
+			continue
+		}
+		if speakers != nil && !containsString(speakers, segment.SpeakerUID) {
+			continue
+		}
+		if transcript.StartedAt == 0 || segment.TaskStartedAt < transcript.StartedAt {
+			transcript.StartedAt = segment.TaskStartedAt
+		}
+		transcript.Segments = append(transcript.Segments, segment)
+	}
+
+	for i := range transcript.Segments {
+		segment := &transcript.Segments[i]
+		offset := segment.TaskStartedAt - transcript.StartedAt
+		segment.StartMs += offset
+		segment.EndMs += offset
+	}
+	sort.SliceStable(transcript.Segments, func(i, j int) bool {
+		return transcript.Segments[i].StartMs < transcript.Segments[j].StartMs
+	})
+	return transcript, true, nil
+}
+
+// sweep removes expired transcripts for the life of the process
+func (s *transcriptStore) sweep() {
+	ticker := time.NewTicker(transcriptSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.prune()
+		<-ticker.C
+	}
+}
+
+// prune removes transcripts not written to for longer than the max age
+func (s *transcriptStore) prune() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Error().Err(err).Msg("Failed to list transcripts")
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), transcriptExtension) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil || time.Since(fi.ModTime()) <= s.maxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, entry.Name())); err != nil {
+			log.Error().Err(err).Str("transcript", entry.Name()).Msg("Failed to remove transcript")
+		}
+	}
+}
+
+// ValidTranscriptFormat reports whether format is a known export format
+func ValidTranscriptFormat(format string) bool {
+	switch format {
+	case TranscriptFormatJSON, TranscriptFormatSRT, TranscriptFormatVTT, TranscriptFormatText:
+		return true
+	}
+	return false
+}
+
+// writeSRT writes a transcript as SubRip subtitles
+func writeSRT(w io.Writer, transcript Transcript, labels bool) {
+	for i, segment := range transcript.Segments {
+		text := cueText(segment.Text)
+		if labels {
+			text = cueText(segment.label()) + ": " + text
+		}
+		fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatCueTime(segment.StartMs, ','), formatCueTime(segment.EndMs, ','), text)
+	}
+}
+
+// writeVTT writes a transcript as WebVTT subtitles, naming speakers with
+// voice spans
+func writeVTT(w io.Writer, transcript Transcript, labels bool) {
+	fmt.Fprint(w, "WEBVTT\n\n")
+	for _, segment := range transcript.Segments {
+		text := escapeVTT(cueText(segment.Text))
+		if labels {
+			text = "<v " + escapeVTT(cueText(segment.label())) + ">" + text
+		}
+		fmt.Fprintf(w, "%s --> %s\n%s\n\n",
+			formatCueTime(segment.StartMs, '.'), formatCueTime(segment.EndMs, '.'), text)
+	}
+}
+
+// writeText writes a transcript as plain text, one timestamped line per
+// segment
+func writeText(w io.Writer, transcript Transcript, labels bool) {
+	for _, segment := range transcript.Segments {
+		stamp := formatCueTime(segment.StartMs, '.')[:8]
+		if labels {
+			fmt.Fprintf(w, "[%s] %s: %s\n", stamp, cueText(segment.label()), cueText(segment.Text))
+		} else {
+			fmt.Fprintf(w, "[%s] %s\n", stamp, cueText(segment.Text))
+		}
+	}
+}
+
+// formatCueTime formats ms as HH:MM:SS followed by sep and milliseconds
+func formatCueTime(ms int64, sep byte) string {
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// cueText puts text on a single line. A blank line ends a cue, so a
+// caption spanning lines would cut its cue short.
+func cueText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// escapeVTT escapes the characters WebVTT cue text reserves. With ">"
+// escaped, a "-->" in the text cannot be read as a cue timing.
+func escapeVTT(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// containsString reports whether values holds value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// testStore returns a transcript store in a temporary directory holding
+// segments for channel
+func testStore(t *testing.T, channel string, segments ...TranscriptSegment) *transcriptStore {
+	t.Helper()
+	store := &transcriptStore{dir: t.TempDir()}
+	for _, segment := range segments {
+		if err := store.append(channel, segment); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	return store
+}
+
+func TestTranscriptExportMergesTasks(t *testing.T) {
+	// The second task started 10s after the first
+	store := testStore(t, "room",
+		TranscriptSegment{TaskID: "b", SpeakerUID: "2", Language: "es", Text: "dos", StartMs: 1000, EndMs: 2000, TaskStartedAt: 20000},
+		TranscriptSegment{TaskID: "a", SpeakerUID: "1", Language: "es", Text: "uno", StartMs: 5000, EndMs: 6000, TaskStartedAt: 10000},
+		TranscriptSegment{TaskID: "a", SpeakerUID: "1", Language: "es", Text: "tres", StartMs: 12000, EndMs: 13000, TaskStartedAt: 10000},
+	)
+
+	transcript, ok, err := store.export("room", "", nil)
+	if err != nil || !ok {
+		t.Fatalf("export: ok %v, err %v", ok, err)
+	}
+	if transcript.StartedAt != 10000 {
+		t.Errorf("transcript starts at %d, want the first task's 10000", transcript.StartedAt)
+	}
+
+	want := []struct {
+		text           string
+		startMs, endMs int64
+	}{
+		{"uno", 5000, 6000},
+		{"dos", 11000, 12000},
+		{"tres", 12000, 13000},
+	}
+	if len(transcript.Segments) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(transcript.Segments), len(want))
+	}
+	for i, w := range want {
+		got := transcript.Segments[i]
+		if got.Text != w.text || got.StartMs != w.startMs || got.EndMs != w.endMs {
+			t.Errorf("segment %d: got %q %d-%d, want %q %d-%d", i, got.Text, got.StartMs, got.EndMs, w.text, w.startMs, w.endMs)
+		}
+	}
+}
+
+func TestTranscriptExportKeepsStoredOrderOfTies(t *testing.T) {
+	store := testStore(t, "room",
+		TranscriptSegment{SpeakerUID: "1", Text: "first", StartMs: 1000, TaskStartedAt: 10000},
+		TranscriptSegment{SpeakerUID: "2", Text: "second", StartMs: 1000, TaskStartedAt: 10000},
+	)
+
+	transcript, _, _ := store.export("room", "", nil)
+	if len(transcript.Segments) != 2 || transcript.Segments[0].Text != "first" || transcript.Segments[1].Text != "second" {
+		t.Errorf("got %+v, want the segments in the order they were stored", transcript.Segments)
+	}
+}
+
+func TestTranscriptExportFilters(t *testing.T) {
+	store := testStore(t, "room",
+		TranscriptSegment{SpeakerUID: "1", Language: "es", Text: "hola", StartMs: 3000, TaskStartedAt: 20000},
+		TranscriptSegment{SpeakerUID: "1", Language: "fr", Text: "salut", StartMs: 1000, TaskStartedAt: 10000},
+		TranscriptSegment{SpeakerUID: "2", Language: "es", Text: "adiós", StartMs: 4000, TaskStartedAt: 20000},
+	)
+
+	tests := []struct {
+		language  string
+		speakers  []string
+		want      []string
+		startedAt int64
+	}{
+		{"", nil, []string{"salut", "hola", "adiós"}, 10000},
+		{"ES", nil, []string{"hola", "adiós"}, 20000},
+		{"", []string{"2"}, []string{"adiós"}, 20000},
+		{"es", []string{"1"}, []string{"hola"}, 20000},
+		{"de", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		transcript, ok, err := store.export("room", tt.language, tt.speakers)
+		if err != nil || !ok {
+			t.Fatalf("export(%q, %v): ok %v, err %v", tt.language, tt.speakers, ok, err)
+		}
+		var got []string
+		for _, segment := range transcript.Segments {
+			got = append(got, segment.Text)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("export(%q, %v): got %v, want %v", tt.language, tt.speakers, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("export(%q, %v): got %v, want %v", tt.language, tt.speakers, got, tt.want)
+				break
+			}
+		}
+		if transcript.StartedAt != tt.startedAt {
+			t.Errorf("export(%q, %v): starts at %d, want %d", tt.language, tt.speakers, transcript.StartedAt, tt.startedAt)
+		}
+		if transcript.Segments == nil {
+			t.Errorf("export(%q, %v): nil segments, want an empty list", tt.language, tt.speakers)
+		}
+	}
+}
+
+func TestTranscriptExportMissingChannel(t *testing.T) {
+	store := testStore(t, "room")
+	if _, ok, err := store.export("other", "", nil); ok || err != nil {
+		t.Errorf("export of a channel without transcript: ok %v, err %v", ok, err)
+	}
+}
+
+func TestFormatCueTime(t *testing.T) {
+	tests := []struct {
+		ms   int64
+		sep  byte
+		want string
+	}{
+		{0, ',', "00:00:00,000"},
+		{1500, ',', "00:00:01,500"},
+		{61001, '.', "00:01:01.001"},
+		{3723004, '.', "01:02:03.004"},
+		{-20, ',', "00:00:00,000"},
+	}
+	for _, tt := range tests {
+		if got := formatCueTime(tt.ms, tt.sep); got != tt.want {
+			t.Errorf("formatCueTime(%d, %q) = %q, want %q", tt.ms, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeVTT(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"plain", "plain"},
+		{"a < b & c > d", "a &lt; b &amp; c &gt; d"},
+		{"<b>bold</b>", "&lt;b&gt;bold&lt;/b&gt;"},
+		{"then --> now", "then --&gt; now"},
+	}
+	for _, tt := range tests {
+		if got := escapeVTT(tt.text); got != tt.want {
+			t.Errorf("escapeVTT(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCueText(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"one line", "one line"},
+		{"two\nlines", "two lines"},
+		{"blank\n\nline", "blank line"},
+		{" padded \r\n", "padded"},
+	}
+	for _, tt := range tests {
+		if got := cueText(tt.text); got != tt.want {
+			t.Errorf("cueText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// exportTranscript is the transcript the writer tests export
+var exportTranscript = Transcript{
+	Channel: "room",
+	Segments: []TranscriptSegment{
+		{SpeakerUID: "1001", Speaker: "Ana", Text: "Hola a todos", StartMs: 1500, EndMs: 3250},
+		{SpeakerUID: "1002", Text: "Buenos\n\ndías", StartMs: 61000, EndMs: 62000},
+	},
+}
+
+func TestWriteTranscripts(t *testing.T) {
+	tests := []struct {
+		name   string
+		write  func(w io.Writer, transcript Transcript, labels bool)
+		labels bool
+		want   string
+	}{
+		{"srt", writeSRT, false, "" +
+			"1\n00:00:01,500 --> 00:00:03,250\nHola a todos\n\n" +
+			"2\n00:01:01,000 --> 00:01:02,000\nBuenos días\n\n"},
+		{"srt labels", writeSRT, true, "" +
+			"1\n00:00:01,500 --> 00:00:03,250\nAna: Hola a todos\n\n" +
+			"2\n00:01:01,000 --> 00:01:02,000\n1002: Buenos días\n\n"},
+		{"vtt", writeVTT, false, "WEBVTT\n\n" +
+			"00:00:01.500 --> 00:00:03.250\nHola a todos\n\n" +
+			"00:01:01.000 --> 00:01:02.000\nBuenos días\n\n"},
+		{"vtt labels", writeVTT, true, "WEBVTT\n\n" +
+			"00:00:01.500 --> 00:00:03.250\n<v Ana>Hola a todos\n\n" +
+			"00:01:01.000 --> 00:01:02.000\n<v 1002>Buenos días\n\n"},
+		{"txt", writeText, false, "" +
+			"[00:00:01] Hola a todos\n" +
+			"[00:01:01] Buenos días\n"},
+		{"txt labels", writeText, true, "" +
+			"[00:00:01] Ana: Hola a todos\n" +
+			"[00:01:01] 1002: Buenos días\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		tt.write(&buf, exportTranscript, tt.labels)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteVTTEscapesSpeaker(t *testing.T) {
+	transcript := Transcript{Segments: []TranscriptSegment{
+		{SpeakerUID: "1", Speaker: "<Ana>", Text: "a --> b", StartMs: 0, EndMs: 1000},
+	}}
+	var buf bytes.Buffer
+	writeVTT(&buf, transcript, true)
+
+	want := "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n<v &lt;Ana&gt;>a --&gt; b\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,193 @@
+// Package video holds the images the bot publishes as video.
+package video
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	_ "image/jpeg" // Decoded by image.Decode
+	_ "image/png"  // Decoded by image.Decode
+	"os"
+	"time"
+)
+
+// Clip limits
+const (
+	MaxClipFrames   = 100                   // Frames of an animated GIF, at about 350 KB each at 640x360
+	minFrameDelay   = 20 * time.Millisecond // Browsers show shorter GIF delays as defaultGIFDelay
+	defaultGIFDelay = 100 * time.Millisecond
+)
+
+// Frame is one picture of a clip in I420
+type Frame struct {
+	I420     []byte
+	Duration time.Duration // How long it shows, 0 for a still
+}
+
+// Clip is a still image or a looping animation, scaled and letterboxed to
+// a fixed size and converted to I420
+type Clip struct {
+	Width  int
+	Height int
+	frames []Frame
+	length time.Duration // Loop length, 0 for a still
+}
+
+// LoadClip reads a PNG, JPEG or GIF and letterboxes it on black to
+// width x height. Animated GIFs loop; everything else is a still.
+func LoadClip(path string, width, height int) (*Clip, error) {
+	if width <= 0 || height <= 0 || width%2 != 0 || height%2 != 0 {
+		return nil, fmt.Errorf("clip size must be even and positive, got %dx%d", width, height)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return nil, fmt.Errorf("%s: empty image", path)
+	}
+
+	clip := &Clip{Width: width, Height: height}
+	if format == "gif" {
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := clip.addAnimation(anim); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return clip, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	clip.frames = []Frame{{I420: toI420(img, width, height)}}
+	return clip, nil
+}
+
+// addAnimation composites the frames of a GIF the way browsers show them
+func (c *Clip) addAnimation(anim *gif.GIF) error {
+	if len(anim.Image) > MaxClipFrames {
+		return fmt.Errorf("%d frames, at most %d supported", len(anim.Image), MaxClipFrames)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, anim.Config.Width, anim.Config.Height))
+	for i, frame := range anim.Image {
+		var previous *image.RGBA
+		if i < len(anim.Disposal) && anim.Disposal[i] == gif.DisposalPrevious {
+			previous = image.NewRGBA(canvas.Bounds())
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		delay := defaultGIFDelay
+		if i < len(anim.Delay) {
+			if d := time.Duration(anim.Delay[i]) * 10 * time.Millisecond; d >= minFrameDelay {
+				delay = d
+			}
+		}
+		c.frames = append(c.frames, Frame{I420: toI420(canvas, c.Width, c.Height), Duration: delay})
+		c.length += delay
+
+		if i < len(anim.Disposal) {
+			switch anim.Disposal[i] {
+			case gif.DisposalBackground:
+				draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+			case gif.DisposalPrevious:
+				copy(canvas.Pix, previous.Pix)
+			}
+		}
+	}
+
+	if len(c.frames) == 1 {
+		// A single-frame GIF is a still
+		c.frames[0].Duration = 0
+		c.length = 0
+	}
+	return nil
+}
+
+// Frames returns the number of frames of the clip
+func (c *Clip) Frames() int {
+	return len(c.frames)
+}
+
+// FrameAt returns the I420 picture showing elapsed into the loop
+func (c *Clip) FrameAt(elapsed time.Duration) []byte {
+	if c.length == 0 {
+		return c.frames[0].I420
+	}
+	at := elapsed % c.length
+	for _, frame := range c.frames {
+		if at < frame.Duration {
+			return frame.I420
+		}
+		at -= frame.Duration
+	}
+	return c.frames[len(c.frames)-1].I420
+}
+
+// toI420 scales img to fit width x height, centres it on black and converts
+// it to I420 (BT.601, limited range). Transparent pixels show black.
+func toI420(img image.Image, width, height int) []byte {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	// Nearest-neighbour scale, keeping the aspect ratio
+	scaledW, scaledH := width, bounds.Dy()*width/bounds.Dx()
+	if scaledH > height {
+		scaledW, scaledH = bounds.Dx()*height/bounds.Dy(), height
+	}
+	left, top := (width-scaledW)/2, (height-scaledH)/2
+
+	rgb := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < scaledH; y++ {
+		sy := y * bounds.Dy() / scaledH
+		for x := 0; x < scaledW; x++ {
+			sx := x * bounds.Dx() / scaledW
+			copy(rgb.Pix[rgb.PixOffset(left+x, top+y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+
+	out := make([]byte, width*height*3/2)
+	yPlane := out[:width*height]
+	uPlane := out[width*height : width*height*5/4]
+	vPlane := out[width*height*5/4:]
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := rgb.Pix[rgb.PixOffset(x, y):]
+			r, g, b := int(p[0]), int(p[1]), int(p[2])
+			yPlane[y*width+x] = byte((66*r+129*g+25*b+128)>>8 + 16)
+		}
+	}
+
+	// Chroma of each 2x2 block from its mean colour
+	for y := 0; y < height; y += 2 {
+		for x := 0; x < width; x += 2 {
+			var r, g, b int
+			for _, offset := range [4]int{rgb.PixOffset(x, y), rgb.PixOffset(x+1, y), rgb.PixOffset(x, y+1), rgb.PixOffset(x+1, y+1)} {
+				r += int(rgb.Pix[offset])
+				g += int(rgb.Pix[offset+1])
+				b += int(rgb.Pix[offset+2])
+			}
+			r, g, b = r/4, g/4, b/4
+			i := (y/2)*(width/2) + x/2
+			uPlane[i] = byte((-38*r-74*g+112*b+128)>>8 + 128)
+			vPlane[i] = byte((112*r-94*g-18*b+128)>>8 + 128)
+		}
+	}
+	return out
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	agoraservice "github.com/AgoraIO-Extensions/Agora-Golang-Server-SDK/v2/go_sdk/rtc"
+	"github.com/samyak-jain/agora_backend/services/audio"
+)
+
+// Voice detectors a session can choose through StartSessionConfig.VADMode
+const (
+	VADModeEnergy   = "energy"   // Mean-square energy gate with hangover and pre-roll (default)
+	VADModeV2       = "vad_v2"   // The SDK's AudioVadV2, run by the bot on the target's frames
+	VADModeSDK      = "sdk"      // AudioVadV2 run by the SDK's audio frame observer for every user
+	VADModeAdaptive = "adaptive" // Level gate following the noise floor, speech level and gaps between words
+)
+
+// DefaultVADMode is used when a session does not choose a detector
+const DefaultVADMode = VADModeEnergy
+
+// ValidVADMode reports whether mode names a voice detector ("" selects the default)
+func ValidVADMode(mode string) bool {
+	switch mode {
+	case "", VADModeEnergy, VADModeV2, VADModeSDK, VADModeAdaptive:
+		return true
+	}
+	return false
+}
+
+// VoiceDetector decides which of the target's frames are forwarded to Anam.
+// Detect and Reset are only called from the SDK audio thread; Update may be
+// called concurrently.
+type VoiceDetector interface {
+	audio.Detector
+
+	// Update changes the detector's settings. Zero values keep the current
+	// setting; settings the detector does not have are an error.
+	Update(rmsThreshold int64, hangover, preroll time.Duration) error
+
+	// ObserverVAD is the VAD configuration the audio frame observer is
+	// registered with, nil to register it without VAD
+	ObserverVAD() *agoraservice.AudioVadConfigV2
+
+	// Settings describes the current settings for logs
+	Settings() string
+}
+
+// NewVoiceDetector creates the detector for a VAD mode
+func NewVoiceDetector(mode string) (VoiceDetector, error) {
+	switch mode {
+	case "", VADModeEnergy:
+		return newEnergyDetector(), nil
+	case VADModeV2:
+		return newVADV2Detector(false), nil
+	case VADModeSDK:
+		return newVADV2Detector(true), nil
+	case VADModeAdaptive:
+		return newAdaptiveDetector(), nil
+	default:
+		return nil, fmt.Errorf("unknown VAD mode %q", mode)
+	}
+}
+
+// energyDetector is the pipeline's energy gate; it needs no VAD from the observer
+type energyDetector struct {
+	*audio.EnergyGate
+}
+
+func newEnergyDetector() *energyDetector {
+	return &energyDetector{audio.NewEnergyGate()}
+}
+
+func (d *energyDetector) ObserverVAD() *agoraservice.AudioVadConfigV2 {
+	return nil
+}
+
+// GateDiagnoser is implemented by detectors that can explain their decisions
+type GateDiagnoser interface {
+	// Diagnostics returns the detector's estimates and latest segment ends
+	Diagnostics() audio.GateDiagnostics
+
+	// LastEnd returns why the latest segment ended
+	LastEnd() (audio.GateEnd, bool)
+}
+
+// adaptiveDetector is the pipeline's adaptive gate; it tunes itself, so
+// update_config has nothing to set
+type adaptiveDetector struct {
+	*audio.AdaptiveGate
+}
+
+func newAdaptiveDetector() *adaptiveDetector {
+	return &adaptiveDetector{audio.NewAdaptiveGate()}
+}
+
+func (d *adaptiveDetector) Update(rmsThreshold int64, hangover, preroll time.Duration) error {
+	if rmsThreshold > 0 || hangover > 0 || preroll > 0 {
+		return fmt.Errorf("the %s detector adapts its threshold, hangover and pre-roll to the audio", VADModeAdaptive)
+	}
+	return nil
+}
+
+func (d *adaptiveDetector) ObserverVAD() *agoraservice.AudioVadConfigV2 {
+	return nil
+}
+
+// vadV2Detector follows the state of the SDK's AudioVadV2, which combines the
+// APM's voice probability with an RMS threshold adapted to the last speech
+// segment. The VAD either runs in the detector on the target's frames, or in
+// the audio frame observer (observer), which runs one VAD per remote user.
+type vadV2Detector struct {
+	observer bool
+	config   atomic.Pointer[agoraservice.AudioVadConfigV2] // Latest settings
+
+	// Audio thread state
+	vad       *agoraservice.AudioVadV2
+	vadConfig *agoraservice.AudioVadConfigV2 // Settings vad was created with
+	speaking  bool
+}
+
+// defaultVADConfigV2 returns the SDK's default AudioVadV2 settings
+func defaultVADConfigV2() *agoraservice.AudioVadConfigV2 {
+	return &agoraservice.AudioVadConfigV2{
+		PreStartRecognizeCount:     16,
+		StartRecognizeCount:        30,
+		StopRecognizeCount:         65,
+		ActivePercent:              0.7,
+		InactivePercent:            0.5,
+		StartVoiceProb:             70,
+		StartRms:                   -70,
+		StopVoiceProb:              70,
+		StopRms:                    -70,
+		EnableAdaptiveRmsThreshold: true,
+		AdaptiveRmsThresholdFactor: 0.67,
+	}
+}
+
+func newVADV2Detector(observer bool) *vadV2Detector {
+	d := &vadV2Detector{observer: observer}
+	d.config.Store(defaultVADConfigV2())
+	return d
+}
+
+func (d *vadV2Detector) Detect(p audio.Packet) (audio.VoiceEvent, [][]byte) {
+	vadState, vadPCM := p.VAD.State, p.VAD.PCM
+	if !d.observer {
+		// Recreate the VAD after Update or Reset. NewAudioVadV2 rewrites the
+		// RMS thresholds of the config it is given, so it gets a copy.
+		if config := d.config.Load(); config != d.vadConfig {
+			if d.vad != nil {
+				d.vad.Release()
+			}
+			vadConfig := *config
+			d.vad = agoraservice.NewAudioVadV2(&vadConfig)
+			d.vadConfig = config
+			d.speaking = false
+		}
+		vadFrame, state := d.vad.Process(sdkAudioFrame(p))
+		vadState, vadPCM = audio.VADState(state), nil
+		if vadFrame != nil {
+			vadPCM = vadFrame.Buffer
+		}
+	}
+
+	switch vadState {
+	case audio.VADStartSpeaking:
+		// The start frame carries the frames that led to the onset
+		d.speaking = true
+		return audio.VoiceStart, vadAudio(vadPCM)
+	case audio.VADSpeaking:
+		// After a Reset, wait for the next onset
+		if !d.speaking {
+			return audio.VoiceSilence, nil
+		}
+		return audio.VoiceContinue, vadAudio(vadPCM)
+	case audio.VADStopSpeaking:
+		if !d.speaking {
+			return audio.VoiceSilence, nil
+		}
+		d.speaking = false
+		return audio.VoiceEnd, vadAudio(vadPCM)
+	default:
+		return audio.VoiceSilence, nil
+	}
+}
+
+// sdkAudioFrame rebuilds the SDK frame a packet came from, with the APM
+// analysis AudioVadV2 reads
+func sdkAudioFrame(p audio.Packet) *agoraservice.AudioFrame {
+	return &agoraservice.AudioFrame{
+		Type:              agoraservice.AudioFrameTypePCM16,
+		SamplesPerChannel: len(p.PCM) / 2,
+		BytesPerSample:    2,
+		Channels:          1,
+		SamplesPerSec:     p.SampleRate,
+		Buffer:            p.PCM,
+		FarFieldFlag:      p.Analysis.FarFieldFlag,
+		Rms:               p.Analysis.Rms,
+		VoiceProb:         p.Analysis.VoiceProb,
+		MusicProb:         p.Analysis.MusicProb,
+		Pitch:             p.Analysis.Pitch,
+	}
+}
+
+// vadAudio returns the PCM of a VAD result
+func vadAudio(pcm []byte) [][]byte {
+	if len(pcm) == 0 {
+		return nil
+	}
+	return [][]byte{pcm}
+}
+
+func (d *vadV2Detector) Reset() {
+	d.speaking = false
+	d.vadConfig = nil
+}
+
+// Update maps the hangover to the frames of silence that end speech and the
+// pre-roll to the frames kept before the onset. The RMS threshold adapts to
+// the speech itself and cannot be set.
+func (d *vadV2Detector) Update(rmsThreshold int64, hangover, preroll time.Duration) error {
+	if d.observer {
+		return fmt.Errorf("the %s detector's settings are fixed when the session starts", VADModeSDK)
+	}
+	if rmsThreshold > 0 {
+		return fmt.Errorf("the %s detector adapts its RMS threshold, only hangover and pre-roll can be set", VADModeV2)
+	}
+
+	config := *d.config.Load()
+	if hangover > 0 {
+		config.StopRecognizeCount = int(max(hangover/audio.FrameDuration, 1))
+	}
+	if preroll > 0 {
+		config.PreStartRecognizeCount = int(max(preroll/audio.FrameDuration, 1))
+	}
+	d.config.Store(&config)
+	return nil
+}
+
+func (d *vadV2Detector) ObserverVAD() *agoraservice.AudioVadConfigV2 {
+	if !d.observer {
+		return nil
+	}
+	// The SDK's VADs rewrite the config they share, so it gets a copy
+	config := *d.config.Load()
+	return &config
+}
+
+func (d *vadV2Detector) Settings() string {
+	config := d.config.Load()
+	mode := VADModeV2
+	if d.observer {
+		mode = VADModeSDK
+	}
+	return fmt.Sprintf("%s hangover=%dms, pre-roll=%dms, onset=%dms", mode,
+		config.StopRecognizeCount*10, config.PreStartRecognizeCount*10, config.StartRecognizeCount*10)
+}